@@ -0,0 +1,30 @@
+// backend/backendtest/backendtest.go -- shared rigging.
+
+// Package backendtest holds the tests of the optional backend interfaces
+// that are shared by several backends, so that every backend is held to
+// the same behavior.  Each function is one test, to be called from the
+// backend's own suite with its Require and a client in a clean state;
+// anything specific to the backend stays in its own tests.
+package backendtest
+
+import (
+	"fmt"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// saveSet saves count small objects at paths made from pfmt, returning the
+// paths.
+func saveSet(require *require.Assertions, c backend.BackendClient, count int, pfmt string) []string {
+
+	paths := make([]string, count)
+	for i := 0; i < count; i++ {
+		paths[i] = fmt.Sprintf(pfmt, i)
+		data := []byte(fmt.Sprintf(`{"n":%d}`, i))
+		require.NoError(c.SaveRaw(paths[i], data), "save error")
+	}
+	return paths
+
+}
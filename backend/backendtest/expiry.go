@@ -0,0 +1,100 @@
+// backend/backendtest/expiry.go -- tests for sliding expiry.
+
+package backendtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// SlidingClient is what the sliding expiry tests need, with history enabled
+// and the sliding expiry set to an hour.
+type SlidingClient interface {
+	backend.ContextBackendClient
+	backend.Versioner
+	backend.BatchClient
+	backend.Historian
+	backend.Transactor
+}
+
+// slidingLoads returns every way of loading a single object that should
+// renew it.
+func slidingLoads(c SlidingClient) map[string]func(string) ([]byte, error) {
+
+	ctx := context.Background()
+	return map[string]func(string) ([]byte, error){
+		"raw": c.LoadRaw,
+		"many": func(path string) ([]byte, error) {
+			loaded, err := c.LoadRawManyCtx(ctx, []string{path})
+			if err == nil && loaded[path] == nil {
+				err = backend.ErrNotFound
+			}
+			return loaded[path], err
+		},
+		"version": func(path string) ([]byte, error) {
+			data, _, err := c.LoadRawVersionCtx(ctx, path)
+			return data, err
+		},
+		"tx": func(path string) (data []byte, err error) {
+			err = c.TxCtx(ctx, func(tx backend.TxClient) error {
+				data, err = tx.LoadRawCtx(ctx, path)
+				return err
+			})
+			return data, err
+		},
+	}
+
+}
+
+// SlidingExpiryOK checks that every load renews expiring objects that would
+// expire sooner, without saving them again, and leaves the rest alone.
+func SlidingExpiryOK(require *require.Assertions, c SlidingClient) {
+
+	loads := slidingLoads(c)
+	names := make([]string, 0, len(loads))
+	for name := range loads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		load := loads[name]
+		soon := time.Now().Add(time.Minute)
+		later := time.Now().Add(2 * time.Hour)
+		prefix := "/slide/" + name
+		require.NoError(c.SaveRawExpiry(prefix+"/soon", []byte(`1`), soon))
+		require.NoError(c.SaveRawExpiry(prefix+"/later", []byte(`2`), later))
+		require.NoError(c.SaveRaw(prefix+"/never", []byte(`3`)))
+
+		for i, path := range []string{"/soon", "/later", "/never"} {
+			data, err := load(prefix + path)
+			require.NoError(err, prefix+path)
+			require.Equal(fmt.Sprint(i+1), string(data), prefix+path)
+		}
+		_, err := load(prefix + "/none")
+		require.ErrorIs(err, backend.ErrNotFound, name)
+
+		detail, err := c.LoadDetail(prefix + "/soon")
+		require.NoError(err)
+		require.WithinDuration(time.Now().Add(time.Hour), detail.Expiry(),
+			time.Minute, "renewed by %s", name)
+		require.EqualValues(1, detail.Version(), "not saved again by %s", name)
+		detail, err = c.LoadDetail(prefix + "/later")
+		require.NoError(err)
+		require.WithinDuration(later, detail.Expiry(), time.Millisecond,
+			"not shortened by %s", name)
+		detail, err = c.LoadDetail(prefix + "/never")
+		require.NoError(err)
+		require.False(detail.Expires(), "still never after %s", name)
+
+		versions, err := c.ListVersionsCtx(context.Background(), prefix+"/soon")
+		require.NoError(err)
+		require.Len(versions, 1, "no history for renewals by %s", name)
+	}
+
+}
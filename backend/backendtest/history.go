@@ -0,0 +1,220 @@
+// backend/backendtest/history.go -- tests for Historian backends.
+
+package backendtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// HistoryClient is what the history tests need, with history enabled.
+type HistoryClient interface {
+	backend.ContextBackendClient
+	backend.MetaClient
+	backend.Versioner
+	backend.Historian
+	backend.Purger
+}
+
+// ListVersionsOK checks that every save is kept, with its metadata.
+func ListVersionsOK(require *require.Assertions, c HistoryClient) {
+
+	ctx := context.Background()
+	meta := map[string]string{"owner": "bob"}
+	require.NoError(c.SaveRawMetaCtx(ctx, "/h", []byte(`1`), nil, meta))
+	require.NoError(c.SaveRaw("/h", []byte(`22`)))
+	require.NoError(c.SaveRawIfVersionCtx(ctx, "/h", []byte(`333`), nil, 2))
+
+	detailers, err := c.ListVersionsCtx(ctx, "/h")
+	require.NoError(err)
+	require.Len(detailers, 3)
+	for i, d := range detailers {
+		require.Equal("/h", d.Path())
+		require.Equal(int64(i+1), d.Version())
+		require.Equal(i+1, d.Size())
+		require.Equal(meta, d.Meta())
+	}
+
+	detailers, err = c.ListVersionsCtx(ctx, "/none")
+	require.NoError(err)
+	require.NotNil(detailers)
+	require.Empty(detailers)
+
+}
+
+// HistoryKeepsDeletedOK checks that deleted objects stay in the history,
+// and that the latest revision wins when versions start over.
+func HistoryKeepsDeletedOK(require *require.Assertions, c HistoryClient) {
+
+	ctx := context.Background()
+	require.NoError(c.SaveRaw("/h", []byte(`"a"`)))
+	require.NoError(c.SaveRaw("/h", []byte(`"b"`)))
+	time.Sleep(2 * time.Millisecond) // deletes may use the database clock
+	before := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(c.Delete("/h"))
+	time.Sleep(2 * time.Millisecond)
+	after := time.Now()
+
+	_, err := c.LoadRaw("/h")
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	detailers, err := c.ListVersionsCtx(ctx, "/h")
+	require.NoError(err)
+	require.Len(detailers, 2)
+
+	data, version, err := c.LoadRawAsOfCtx(ctx, "/h", before)
+	require.NoError(err)
+	require.Equal(`"b"`, string(data))
+	require.Equal(int64(2), version)
+	_, _, err = c.LoadRawAsOfCtx(ctx, "/h", after)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	// Versions start over, and the latest revision wins.
+	require.NoError(c.SaveRaw("/h", []byte(`"c"`)))
+	data, err = c.LoadRawAtVersionCtx(ctx, "/h", 1)
+	require.NoError(err)
+	require.Equal(`"c"`, string(data))
+	require.NoError(c.SaveRaw("/h", []byte(`"d"`)))
+	data, err = c.LoadRawAtVersionCtx(ctx, "/h", 1)
+	require.NoError(err)
+	require.Equal(`"c"`, string(data))
+	data, err = c.LoadRawAtVersionCtx(ctx, "/h", 2)
+	require.NoError(err)
+	require.Equal(`"d"`, string(data))
+
+}
+
+// HistorySkipsExpiredOK checks that expired objects are not kept when
+// replaced or purged.
+func HistorySkipsExpiredOK(require *require.Assertions, c HistoryClient) {
+
+	ctx := context.Background()
+	require.NoError(c.SaveRawExpiry("/h", []byte(`1`),
+		time.Now().Add(-time.Second)))
+	require.NoError(c.SaveRaw("/h", []byte(`2`)))
+	require.NoError(c.SaveRawExpiry("/x", []byte(`1`),
+		time.Now().Add(-time.Second)))
+	purged, err := c.Purge()
+	require.NoError(err)
+	require.Equal(1, purged)
+
+	detailers, err := c.ListVersionsCtx(ctx, "/h")
+	require.NoError(err)
+	require.Len(detailers, 1)
+	require.Equal(int64(1), detailers[0].Version())
+	detailers, err = c.ListVersionsCtx(ctx, "/x")
+	require.NoError(err)
+	require.Empty(detailers)
+
+}
+
+// LoadRawAtVersionFailsNotFound checks a version that was never saved.
+func LoadRawAtVersionFailsNotFound(require *require.Assertions, c HistoryClient) {
+
+	require.NoError(c.SaveRaw("/h", []byte(`1`)))
+	_, err := c.LoadRawAtVersionCtx(context.Background(), "/h", 2)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+}
+
+// PruneHistoryOK checks pruning by count and by age.
+func PruneHistoryOK(require *require.Assertions, c HistoryClient) {
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		require.NoError(c.SaveRaw("/a", []byte(`1`)))
+		require.NoError(c.SaveRaw("/b", []byte(`1`)))
+	}
+
+	pruned, err := c.PruneHistoryCtx(ctx, 0, 0)
+	require.NoError(err)
+	require.Equal(0, pruned)
+
+	pruned, err = c.PruneHistoryCtx(ctx, 2, 0)
+	require.NoError(err)
+	require.Equal(4, pruned)
+	detailers, err := c.ListVersionsCtx(ctx, "/a")
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(int64(3), detailers[0].Version())
+
+	time.Sleep(2 * time.Millisecond)
+	pruned, err = c.PruneHistoryCtx(ctx, 0, time.Millisecond)
+	require.NoError(err)
+	require.Equal(4, pruned)
+	detailers, err = c.ListVersionsCtx(ctx, "/b")
+	require.NoError(err)
+	require.Len(detailers, 1)
+
+}
+
+// PruneHistoryFailsNegative checks the limits are validated.
+func PruneHistoryFailsNegative(require *require.Assertions, c HistoryClient) {
+
+	_, err := c.PruneHistoryCtx(context.Background(), -1, 0)
+	require.EqualError(err, "history keep must not be negative, not -1")
+	_, err = c.PruneHistoryCtx(context.Background(), 0, -time.Second)
+	require.EqualError(err, "history max age must not be negative, not -1s")
+
+}
+
+// PurgePrunesHistoryOK checks that Purge and PurgeBatchCtx prune the history
+// once bound has set the client's bounds, batches counting the revisions
+// after the expired objects.
+func PurgePrunesHistoryOK(require *require.Assertions, c interface {
+	HistoryClient
+	backend.BatchPurger
+}, bound func(keep int, max_age time.Duration)) {
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		require.NoError(c.SaveRaw("/a", []byte(`1`)))
+		require.NoError(c.SaveRaw("/b", []byte(`1`)))
+	}
+	purged, err := c.Purge()
+	require.NoError(err)
+	require.Equal(0, purged, "unbounded")
+
+	bound(2, 0)
+	purged, err = c.Purge()
+	require.NoError(err)
+	require.Equal(4, purged, "pruned to 2 revisions each")
+	detailers, err := c.ListVersionsCtx(ctx, "/a")
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(int64(3), detailers[0].Version())
+
+	require.NoError(c.SaveRaw("/a", []byte(`1`)))
+	require.NoError(c.SaveRaw("/a", []byte(`1`)))
+	require.NoError(c.SaveRawExpiry("/x", []byte(`1`),
+		time.Now().Add(-time.Second)))
+	purged, err = c.PurgeBatchCtx(ctx, 2)
+	require.NoError(err)
+	require.Equal(2, purged, "expired then one revision")
+	purged, err = c.PurgeBatchCtx(ctx, 10)
+	require.NoError(err)
+	require.Equal(1, purged, "the other revision")
+	detailers, err = c.ListVersionsCtx(ctx, "/a")
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(int64(5), detailers[0].Version())
+
+	bound(0, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	purged, err = c.Purge()
+	require.NoError(err)
+	require.Equal(4, purged, "all revisions too old")
+	detailers, err = c.ListVersionsCtx(ctx, "/b")
+	require.NoError(err)
+	require.Len(detailers, 1)
+
+	bound(-1, 0)
+	_, err = c.Purge()
+	require.EqualError(err, "history keep must not be negative, not -1")
+
+}
@@ -0,0 +1,178 @@
+// backend/backendtest/move.go -- tests for Mover backends.
+
+package backendtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// MoveClient is what the copy and move tests need.
+type MoveClient interface {
+	backend.ContextBackendClient
+	backend.MetaClient
+	backend.Mover
+}
+
+// CopyOK checks that a copy keeps the data, expiry and metadata, and that
+// the destination gets its own version.
+func CopyOK(require *require.Assertions, c MoveClient) {
+
+	ctx := context.Background()
+	meta := map[string]string{"owner": "bob"}
+	expiry := time.Now().Add(time.Hour).Round(time.Millisecond)
+	require.NoError(c.SaveRawMetaCtx(ctx, "/src", []byte(`1`), &expiry, meta))
+	require.NoError(c.SaveRaw("/src", []byte(`2`)))
+
+	require.NoError(c.CopyCtx(ctx, "/src", "/dst", backend.OverwriteNever))
+	src, err := c.LoadDetail("/src")
+	require.NoError(err)
+	dst, err := c.LoadDetail("/dst")
+	require.NoError(err)
+	require.Equal(int64(1), dst.Version(), "new object")
+	require.Equal(meta, dst.Meta())
+	require.Equal(src.Expires(), dst.Expires())
+	data, err := c.LoadRaw("/dst")
+	require.NoError(err)
+	require.Equal(`2`, string(data))
+
+	require.NoError(c.SaveRaw("/src", []byte(`3`)))
+	require.NoError(c.CopyCtx(ctx, "/src", "/dst", backend.OverwriteAlways))
+	dst, err = c.LoadDetail("/dst")
+	require.NoError(err)
+	require.Equal(int64(2), dst.Version(), "replaced object")
+	data, err = c.LoadRaw("/dst")
+	require.NoError(err)
+	require.Equal(`3`, string(data))
+
+}
+
+// CopyExistingOK checks the overwrite policies for a live destination, and
+// that an expired one does not count.
+func CopyExistingOK(require *require.Assertions, c MoveClient) {
+
+	ctx := context.Background()
+	require.NoError(c.SaveRaw("/src", []byte(`1`)))
+	require.NoError(c.SaveRaw("/dst", []byte(`2`)))
+
+	err := c.CopyCtx(ctx, "/src", "/dst", backend.OverwriteNever)
+	require.ErrorIs(err, backend.ErrConflict)
+	require.NoError(c.CopyCtx(ctx, "/src", "/dst", backend.OverwriteSkip))
+	data, err := c.LoadRaw("/dst")
+	require.NoError(err)
+	require.Equal(`2`, string(data))
+
+	// Expired objects do not count.
+	require.NoError(c.SaveRawExpiry("/old", []byte(`3`), time.Now().Add(-time.Hour)))
+	require.NoError(c.CopyCtx(ctx, "/src", "/old", backend.OverwriteNever))
+	dst, err := c.LoadDetail("/old")
+	require.NoError(err)
+	require.Equal(int64(1), dst.Version())
+
+}
+
+// CopyFails checks a missing source and copying onto itself.
+func CopyFails(require *require.Assertions, c MoveClient) {
+
+	ctx := context.Background()
+	err := c.CopyCtx(ctx, "/none", "/dst", backend.OverwriteAlways)
+	require.ErrorIs(err, backend.ErrNotFound)
+	err = c.MoveCtx(ctx, "/none", "/dst", backend.OverwriteAlways)
+	require.ErrorIs(err, backend.ErrNotFound)
+	err = c.CopyCtx(ctx, "/none", "/none", backend.OverwriteAlways)
+	require.ErrorIs(err, backend.ErrInvalidPath)
+
+}
+
+// MoveOK checks that a skipped move keeps the source, and a done one
+// removes it.
+func MoveOK(require *require.Assertions, c MoveClient) {
+
+	ctx := context.Background()
+	meta := map[string]string{"owner": "bob"}
+	require.NoError(c.SaveRawMetaCtx(ctx, "/src", []byte(`1`), nil, meta))
+	require.NoError(c.SaveRaw("/dst", []byte(`2`)))
+
+	err := c.MoveCtx(ctx, "/src", "/dst", backend.OverwriteNever)
+	require.ErrorIs(err, backend.ErrConflict)
+	require.NoError(c.MoveCtx(ctx, "/src", "/dst", backend.OverwriteSkip))
+	_, err = c.LoadRaw("/src")
+	require.NoError(err, "skipped source kept")
+
+	require.NoError(c.MoveCtx(ctx, "/src", "/dst", backend.OverwriteAlways))
+	_, err = c.LoadRaw("/src")
+	require.ErrorIs(err, backend.ErrNotFound)
+	dst, err := c.LoadDetail("/dst")
+	require.NoError(err)
+	require.Equal(int64(2), dst.Version())
+	require.Equal(meta, dst.Meta())
+
+}
+
+// MovePrefixOK checks moving a subtree under each overwrite policy.
+func MovePrefixOK(require *require.Assertions, c MoveClient) {
+
+	ctx := context.Background()
+	for _, path := range []string{"/staging/a", "/staging/b", "/staging/c/d"} {
+		require.NoError(c.SaveRaw(path, []byte(`1`)))
+	}
+	require.NoError(c.SaveRawExpiry("/staging/old", []byte(`1`),
+		time.Now().Add(-time.Hour)))
+	require.NoError(c.SaveRaw("/archive/b", []byte(`2`)))
+
+	moved, err := c.MovePrefixCtx(ctx, "/staging/", "/archive/", backend.OverwriteNever)
+	require.ErrorIs(err, backend.ErrConflict)
+	require.Equal(0, moved)
+	paths, err := c.List("/staging/")
+	require.NoError(err)
+	require.Len(paths, 3, "nothing moved")
+
+	moved, err = c.MovePrefixCtx(ctx, "/staging/", "/archive/", backend.OverwriteSkip)
+	require.NoError(err)
+	require.Equal(2, moved)
+	paths, err = c.List("/staging/")
+	require.NoError(err)
+	require.Equal([]string{"/staging/b"}, paths)
+	paths, err = c.List("/archive/")
+	require.NoError(err)
+	require.Equal([]string{"/archive/a", "/archive/b", "/archive/c/d"}, paths)
+
+	moved, err = c.MovePrefixCtx(ctx, "/staging/", "/archive/", backend.OverwriteAlways)
+	require.NoError(err)
+	require.Equal(1, moved)
+	data, err := c.LoadRaw("/archive/b")
+	require.NoError(err)
+	require.Equal(`1`, string(data))
+
+}
+
+// MovePrefixFailsOverlap checks prefixes that contain each other.
+func MovePrefixFailsOverlap(require *require.Assertions, c MoveClient) {
+
+	ctx := context.Background()
+	_, err := c.MovePrefixCtx(ctx, "/a/", "/a/b/", backend.OverwriteAlways)
+	require.ErrorIs(err, backend.ErrInvalidPath)
+	_, err = c.MovePrefixCtx(ctx, "/a/b", "/a", backend.OverwriteAlways)
+	require.ErrorIs(err, backend.ErrInvalidPath)
+
+}
+
+// MoveSkipsTrashOK checks that a moved source does not go to the trash,
+// with soft deletion turned on.
+func MoveSkipsTrashOK(require *require.Assertions, c interface {
+	MoveClient
+	backend.Trasher
+}) {
+
+	ctx := context.Background()
+	require.NoError(c.SaveRaw("/src", []byte(`1`)))
+	require.NoError(c.MoveCtx(ctx, "/src", "/dst", backend.OverwriteNever))
+	deleted, err := c.ListDeletedCtx(ctx, "/")
+	require.NoError(err)
+	require.Empty(deleted)
+
+}
@@ -0,0 +1,180 @@
+// backend/backendtest/trash.go -- tests for Trasher backends.
+
+package backendtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// TrashClient is what the trash tests need.  Tests named for soft deletion
+// expect it to be turned on, the others expect it off.
+type TrashClient interface {
+	backend.ContextBackendClient
+	backend.MetaClient
+	backend.Versioner
+	backend.BatchClient
+	backend.PrefixDeleter
+	backend.Trasher
+	backend.BatchPurger
+}
+
+// SoftDeleteOK checks that a deleted object goes to the trash as it was, and
+// comes back from it the same.
+func SoftDeleteOK(require *require.Assertions, c TrashClient) {
+
+	ctx := context.Background()
+	meta := map[string]string{"owner": "bob"}
+	require.NoError(c.SaveRawMetaCtx(ctx, "/t/a", []byte(`1`), nil, meta))
+	require.NoError(c.SaveRaw("/t/a", []byte(`22`)))
+	require.NoError(c.SaveRaw("/t/b", []byte(`3`)))
+	before := time.Now()
+	require.NoError(c.Delete("/t/a"))
+
+	_, err := c.LoadRaw("/t/a")
+	require.ErrorIs(err, backend.ErrNotFound)
+	count, err := c.Count("/t/")
+	require.NoError(err)
+	require.Equal(1, count)
+	require.ErrorIs(c.Delete("/t/a"), backend.ErrNotFound, "already gone")
+
+	deleted, err := c.ListDeletedCtx(ctx, "/t/")
+	require.NoError(err)
+	require.Len(deleted, 1)
+	require.Equal("/t/a", deleted[0].Path())
+	require.Equal(int64(2), deleted[0].Version())
+	require.Equal(meta, deleted[0].Meta())
+	require.WithinDuration(before, deleted[0].Deleted(), time.Minute)
+
+	require.NoError(c.UndeleteCtx(ctx, "/t/a"))
+	data, version, err := c.LoadRawVersionCtx(ctx, "/t/a")
+	require.NoError(err)
+	require.Equal(`22`, string(data))
+	require.Equal(int64(2), version)
+	detail, err := c.LoadDetail("/t/a")
+	require.NoError(err)
+	require.Equal(meta, detail.Meta())
+
+	deleted, err = c.ListDeletedCtx(ctx, "/t/")
+	require.NoError(err)
+	require.NotNil(deleted)
+	require.Empty(deleted)
+
+}
+
+// SoftDeleteManyOK checks DeleteMany moves what it deletes to the trash.
+func SoftDeleteManyOK(require *require.Assertions, c TrashClient) {
+
+	ctx := context.Background()
+	paths := saveSet(require, c, 3, "/t/%d")
+	deleted, err := c.DeleteManyCtx(ctx, append(paths[:2], "/t/none"))
+	require.NoError(err)
+	require.Equal(2, deleted)
+
+	trashed, err := c.ListDeletedCtx(ctx, "/t/")
+	require.NoError(err)
+	require.Len(trashed, 2)
+	count, err := c.CountAll()
+	require.NoError(err)
+	require.Equal(1, count)
+
+}
+
+// SoftDeletePrefixOK checks DeletePrefix moves what it deletes to the trash.
+func SoftDeletePrefixOK(require *require.Assertions, c TrashClient) {
+
+	ctx := context.Background()
+	saveSet(require, c, 3, "/t/%d")
+	saveSet(require, c, 1, "/u/%d")
+	deleted, err := c.DeletePrefixCtx(ctx, "/t/")
+	require.NoError(err)
+	require.Equal(3, deleted)
+
+	trashed, err := c.ListDeletedCtx(ctx, "/")
+	require.NoError(err)
+	require.Len(trashed, 3)
+	count, err := c.CountAll()
+	require.NoError(err)
+	require.Equal(1, count)
+
+}
+
+// HardDeleteSkipsTrashOK checks nothing goes to the trash when soft
+// deletion is off.
+func HardDeleteSkipsTrashOK(require *require.Assertions, c TrashClient) {
+
+	require.NoError(c.SaveRaw("/t/a", []byte(`1`)))
+	require.NoError(c.Delete("/t/a"))
+	trashed, err := c.ListDeletedCtx(context.Background(), "/")
+	require.NoError(err)
+	require.Empty(trashed)
+
+}
+
+// UndeleteFailsNotFound checks undeleting what is not in the trash.
+func UndeleteFailsNotFound(require *require.Assertions, c TrashClient) {
+
+	err := c.UndeleteCtx(context.Background(), "/t/none")
+	require.ErrorIs(err, backend.ErrNotFound)
+
+}
+
+// UndeleteFailsConflict checks undeleting onto a live object, but not onto
+// an expired one.
+func UndeleteFailsConflict(require *require.Assertions, c TrashClient) {
+
+	ctx := context.Background()
+	require.NoError(c.SaveRaw("/t/a", []byte(`1`)))
+	require.NoError(c.Delete("/t/a"))
+	require.NoError(c.SaveRaw("/t/a", []byte(`2`)))
+
+	require.ErrorIs(c.UndeleteCtx(ctx, "/t/a"), backend.ErrConflict)
+	trashed, err := c.ListDeletedCtx(ctx, "/t/")
+	require.NoError(err)
+	require.Len(trashed, 1, "still in the trash")
+
+	// Replacing an expired object is fine.
+	require.NoError(c.SaveRawExpiry("/t/a", []byte(`3`),
+		time.Now().Add(-time.Second)))
+	require.NoError(c.UndeleteCtx(ctx, "/t/a"))
+	data, err := c.LoadRaw("/t/a")
+	require.NoError(err)
+	require.Equal(`1`, string(data))
+
+}
+
+// PurgeTrashOK checks that purging leaves the trash alone until its
+// retention is over, which no_retention must end for everything in it, and
+// that expired objects are purged first.
+func PurgeTrashOK(require *require.Assertions, c TrashClient, no_retention func()) {
+
+	ctx := context.Background()
+	paths := saveSet(require, c, 3, "/t/%d")
+	_, err := c.DeleteManyCtx(ctx, paths)
+	require.NoError(err)
+	past := time.Now().Add(-time.Second)
+	for _, path := range []string{"/x/0", "/x/1"} {
+		require.NoError(c.SaveRawExpiry(path, []byte(`1`), past))
+	}
+
+	purged, err := c.Purge()
+	require.NoError(err)
+	require.Equal(2, purged, "expired only")
+
+	no_retention()
+	require.NoError(c.SaveRawExpiry("/x/0", []byte(`1`), past))
+	purged, err = c.PurgeBatchCtx(ctx, 2)
+	require.NoError(err)
+	require.Equal(2, purged, "expired first")
+	purged, err = c.PurgeBatchCtx(ctx, 2)
+	require.NoError(err)
+	require.Equal(2, purged)
+	purged, err = c.Purge()
+	require.NoError(err)
+	require.Equal(0, purged)
+
+}
@@ -0,0 +1,37 @@
+// backend/cursor.go -- cursors for paged listings.
+//
+// Cursors are opaque to callers.  The helpers here are for backends that
+// page by path, i.e. "everything after this path"; a backend with its own
+// continuation tokens may use those instead.
+
+package backend
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// EncodeCursor returns a cursor for the page after the path last.
+func EncodeCursor(last string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(last))
+}
+
+// DecodeCursor returns the path encoded in cursor by EncodeCursor, or
+// ErrInvalidCursor.  The empty cursor decodes to the empty path, i.e. the
+// first page.
+func DecodeCursor(cursor string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	return string(b), nil
+}
+
+// CheckPage validates the arguments to a paged listing, returning the path
+// encoded in cursor.
+func CheckPage(cursor string, limit int) (string, error) {
+	if limit < 1 {
+		return "", fmt.Errorf("page limit must be positive, not %d", limit)
+	}
+	return DecodeCursor(cursor)
+}
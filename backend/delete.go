@@ -0,0 +1,15 @@
+// backend/delete.go -- helpers for PrefixDeleter backends.
+
+package backend
+
+import "fmt"
+
+// CheckDeletePrefix returns ErrInvalidPath for the empty prefix, which would
+// delete everything.
+func CheckDeletePrefix(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("%w: refusing to delete with empty prefix",
+			ErrInvalidPath)
+	}
+	return nil
+}
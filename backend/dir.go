@@ -0,0 +1,47 @@
+// backend/dir.go -- directory-style listings.
+
+package backend
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// CheckDelimiter validates the delimiter for a directory-style listing.
+func CheckDelimiter(delimiter string) error {
+	if delimiter == "" {
+		return errors.New("delimiter must not be empty")
+	}
+	return nil
+}
+
+// SplitDir sorts paths, all beginning with prefix, into those with no
+// delimiter after the prefix and the common prefixes of the rest, up to and
+// including the first delimiter after the prefix.  Both are returned sorted
+// and the common prefixes are unique.
+//
+// This is for backends that can not roll up the listing themselves.
+func SplitDir(prefix string, delimiter string, paths []string) ([]string, []string) {
+
+	objs := []string{}
+	prefixes := []string{}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		rest := strings.TrimPrefix(path, prefix)
+		i := strings.Index(rest, delimiter)
+		if i < 0 {
+			objs = append(objs, path)
+			continue
+		}
+		common := prefix + rest[:i+len(delimiter)]
+		if !seen[common] {
+			seen[common] = true
+			prefixes = append(prefixes, common)
+		}
+	}
+	sort.Strings(objs)
+	sort.Strings(prefixes)
+	return objs, prefixes
+
+}
@@ -0,0 +1,40 @@
+// backend/errors.go -- errors shared by all backends.
+//
+// Backends return these, or wrap them, so that callers can use errors.Is
+// without importing driver packages.
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned when there is no live object at a path.
+var ErrNotFound = errors.New("object not found")
+
+// ErrExpired is returned instead of ErrNotFound by backends that can tell
+// that the object exists but has expired.  It wraps ErrNotFound, so callers
+// who do not care about the difference need only check for that.
+var ErrExpired = fmt.Errorf("object expired: %w", ErrNotFound)
+
+// ErrConflict is returned when a write can not be made because the stored
+// object is not in the expected state.
+var ErrConflict = errors.New("conflict")
+
+// ErrInvalidPath is returned for paths the backend can not store.
+var ErrInvalidPath = errors.New("invalid object path")
+
+// ErrInvalidJson is returned when saving data that is not valid JSON.
+var ErrInvalidJson = errors.New("invalid JSON")
+
+// ErrInvalidCursor is returned for paging cursors the backend did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrUnsupported is returned when the backend does not support the
+// requested operation.
+var ErrUnsupported = errors.New("operation not supported by backend")
+
+// ErrPatchFailed is returned when a patch is invalid or can not be applied,
+// including when a JSON Patch "test" operation fails.
+var ErrPatchFailed = errors.New("patch failed")
@@ -0,0 +1,20 @@
+// backend/history.go -- helpers for Historian backends.
+
+package backend
+
+import (
+	"fmt"
+	"time"
+)
+
+// CheckPrune returns an error if the limits for PruneHistoryCtx are
+// negative.
+func CheckPrune(keep int, max_age time.Duration) error {
+	if keep < 0 {
+		return fmt.Errorf("history keep must not be negative, not %d", keep)
+	}
+	if max_age < 0 {
+		return fmt.Errorf("history max age must not be negative, not %s", max_age)
+	}
+	return nil
+}
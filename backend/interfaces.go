@@ -7,6 +7,7 @@
 package backend
 
 import (
+	"context"
 	"time"
 )
 
@@ -18,6 +19,8 @@ type Detailer interface {
 	Modified() time.Time
 	Expires() bool
 	Expiry() time.Time
+	Version() int64
+	Meta() map[string]string // nil if the object has no metadata
 }
 
 // BackendClient is the client that talks to the back-end storage.
@@ -34,3 +37,221 @@ type BackendClient interface {
 	CountAll() (int, error)
 	Shutdown() error
 }
+
+// ContextBackendClient is a BackendClient that also accepts a Context for
+// every operation, so that callers can cancel or set deadlines.  The plain
+// BackendClient methods are expected to use context.Background().
+type ContextBackendClient interface {
+	BackendClient
+	SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error
+	SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error
+	LoadRawCtx(ctx context.Context, path string) ([]byte, error)
+	LoadDetailCtx(ctx context.Context, path string) (Detailer, error)
+	DeleteCtx(ctx context.Context, path string) error
+	ListCtx(ctx context.Context, prefix string) ([]string, error)
+	ListDetailCtx(ctx context.Context, prefix string) ([]Detailer, error)
+	CountCtx(ctx context.Context, prefix string) (int, error)
+	CountAllCtx(ctx context.Context) (int, error)
+	ShutdownCtx(ctx context.Context) error
+}
+
+// Purger is a BackendClient that can delete its expired objects on demand,
+// returning the number deleted.
+type Purger interface {
+	Purge() (int, error)
+}
+
+// ContextPurger is a Purger that also accepts a Context.
+type ContextPurger interface {
+	Purger
+	PurgeCtx(ctx context.Context) (int, error)
+}
+
+// BatchPurger is a Purger that can limit the number of objects deleted at
+// once, so that a single purge does not hold locks for long.
+type BatchPurger interface {
+	Purger
+	PurgeBatchCtx(ctx context.Context, limit int) (int, error)
+}
+
+// Versioner is a BackendClient supporting optimistic concurrency.  Every
+// save of an object increments its Version, starting at 1 when it is
+// created, including when it replaces an expired object.  Version zero
+// means there is no live object.
+type Versioner interface {
+	// LoadRawVersionCtx returns the raw value of the object at path along
+	// with its version, read together.
+	LoadRawVersionCtx(ctx context.Context, path string) ([]byte, int64, error)
+
+	// SaveRawIfVersionCtx saves raw_obj only if the live object at path has
+	// the given version, or if version is zero and there is no live object.
+	// Otherwise ErrConflict is returned.  A nil expiry means none.
+	SaveRawIfVersionCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, version int64) error
+}
+
+// BatchClient is a BackendClient that can save, load and delete many
+// objects at once, typically in a single round trip.
+type BatchClient interface {
+	// SaveRawManyCtx saves every raw_obj at its path, with no expiry.
+	SaveRawManyCtx(ctx context.Context, raw_objs map[string][]byte) error
+
+	// LoadRawManyCtx returns the raw values of the live objects at paths.
+	// Paths with no live object are left out of the map.
+	LoadRawManyCtx(ctx context.Context, paths []string) (map[string][]byte, error)
+
+	// DeleteManyCtx deletes the objects at paths, expired or not, and
+	// returns the number deleted.  Missing objects are not an error.
+	DeleteManyCtx(ctx context.Context, paths []string) (int, error)
+}
+
+// Pager is a BackendClient that can list objects a page at a time, without
+// holding the whole listing in memory.  A page holds at most limit items,
+// in path order, and the returned cursor fetches the next page; it is empty
+// when there are no more.  The empty cursor fetches the first page.
+type Pager interface {
+	ListPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]string, string, error)
+	ListDetailPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]Detailer, string, error)
+}
+
+// Iterator is a BackendClient that can stream the objects beginning with
+// prefix, in path order, without reading them all into memory first.  If
+// with_data is false, fn is called with nil data.  If fn returns an error
+// the iteration stops and that error is returned.
+type Iterator interface {
+	IterateCtx(ctx context.Context, prefix string, with_data bool, fn func(Detailer, []byte) error) error
+}
+
+// DirLister is a BackendClient that can list the objects beginning with
+// prefix as a directory, returning the paths with no delimiter after the
+// prefix and the common prefixes of the rest, as described for SplitDir.
+type DirLister interface {
+	ListDirCtx(ctx context.Context, prefix string, delimiter string) ([]string, []string, error)
+}
+
+// Updater is a BackendClient that can update an object atomically in place,
+// replacing its data with the result of fn and keeping its expiry.  If fn
+// returns an error nothing is saved and that error is returned.
+type Updater interface {
+	UpdateRawCtx(ctx context.Context, path string, fn func([]byte) ([]byte, error)) error
+}
+
+// MetaClient is a BackendClient that keeps user-defined metadata with its
+// objects, returned by Detailer.Meta.  SaveRawMetaCtx replaces the metadata
+// of the object at path with meta, which may be nil to remove it.  All other
+// saves keep the metadata of the live object they replace.
+type MetaClient interface {
+	SaveRawMetaCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, meta map[string]string) error
+}
+
+// MetaLister is a BackendClient that can filter objects by metadata itself,
+// returning only the live objects beginning with prefix whose metadata
+// matches meta, as for MatchMeta.
+type MetaLister interface {
+	ListDetailMetaCtx(ctx context.Context, prefix string, meta map[string]string) ([]Detailer, error)
+	CountMetaCtx(ctx context.Context, prefix string, meta map[string]string) (int, error)
+}
+
+// Historian is a BackendClient that keeps the prior revisions of its objects
+// when they are replaced or deleted.  Revisions are identified by version,
+// but as versions start over at 1 when an object is recreated, the same
+// version may occur more than once; the most recent one is used.
+type Historian interface {
+	// ListVersionsCtx returns the kept revisions of the object at path,
+	// oldest first, ending with the live object if there is one.  An empty
+	// array is not considered an error.
+	ListVersionsCtx(ctx context.Context, path string) ([]Detailer, error)
+
+	// LoadRawAtVersionCtx returns the raw value of the object at path as of
+	// the given version, or ErrNotFound if that revision is not kept.
+	LoadRawAtVersionCtx(ctx context.Context, path string, version int64) ([]byte, error)
+
+	// LoadRawAsOfCtx returns the raw value and version of the object that
+	// was live at path at time t, or ErrNotFound if there was none or its
+	// revision is not kept.
+	LoadRawAsOfCtx(ctx context.Context, path string, t time.Time) ([]byte, int64, error)
+
+	// PruneHistoryCtx deletes all but the keep most recent revisions of
+	// every object, and all revisions replaced more than max_age ago.  Zero
+	// disables either limit.  The number of revisions deleted is returned.
+	PruneHistoryCtx(ctx context.Context, keep int, max_age time.Duration) (int, error)
+}
+
+// DeletedDetailer describes an object in the trash of a Trasher.
+type DeletedDetailer interface {
+	Detailer
+	Deleted() time.Time
+}
+
+// Trasher is a BackendClient that can move deleted objects to a trash
+// instead of removing them, so that they can be undeleted.  Objects in the
+// trash are invisible to every other operation.
+type Trasher interface {
+	// UndeleteCtx moves the object at path back out of the trash as it was
+	// when deleted.  If it is not in the trash ErrNotFound is returned, and
+	// if there is a live object at path ErrConflict is.
+	UndeleteCtx(ctx context.Context, path string) error
+
+	// ListDeletedCtx returns DeletedDetailers for the objects in the trash
+	// beginning with prefix, in path order.  An empty array is not
+	// considered an error.
+	ListDeletedCtx(ctx context.Context, prefix string) ([]DeletedDetailer, error)
+}
+
+// Expirer is a BackendClient that can change the expiry of an object without
+// saving it again.  The data, version, modification time and metadata are
+// unchanged.  A nil expiry means none.  If there is no live object at path
+// ErrNotFound is returned.
+type Expirer interface {
+	SetExpiryCtx(ctx context.Context, path string, expiry *time.Time) error
+}
+
+// Mover is a BackendClient that can copy and move objects itself, keeping
+// their expiry and metadata.  The copy gets a new modification time, and a
+// version following that of any live object it replaces.
+//
+// If there is no live object at src ErrNotFound is returned.  If there is
+// a live object at the destination the overwrite policy applies; see
+// Overwrite.
+type Mover interface {
+	CopyCtx(ctx context.Context, src string, dst string, overwrite Overwrite) error
+	MoveCtx(ctx context.Context, src string, dst string, overwrite Overwrite) error
+
+	// MovePrefixCtx moves every live object beginning with src_prefix to
+	// the same path beginning with dst_prefix instead, returning the number
+	// moved.  With OverwriteNever no object is moved if any would replace
+	// a live object.
+	MovePrefixCtx(ctx context.Context, src_prefix string, dst_prefix string, overwrite Overwrite) (int, error)
+}
+
+// PrefixDeleter is a BackendClient that can delete every object beginning
+// with a prefix itself, including expired objects, returning the number
+// deleted.  The empty prefix is an ErrInvalidPath; see CheckDeletePrefix.
+type PrefixDeleter interface {
+	DeletePrefixCtx(ctx context.Context, prefix string) (int, error)
+}
+
+// TxClient is the part of a BackendClient available within a transaction
+// of a Transactor.  It must not be used after the transaction ends.
+type TxClient interface {
+	SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error
+	SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error
+	LoadRawCtx(ctx context.Context, path string) ([]byte, error)
+	DeleteCtx(ctx context.Context, path string) error
+	ListCtx(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Transactor is a BackendClient that can run several operations atomically.
+// TxCtx calls fn with a TxClient for a new transaction, which is committed
+// if fn returns nil and rolled back if it returns an error or panics.  The
+// error from fn is returned as is.
+type Transactor interface {
+	TxCtx(ctx context.Context, fn func(TxClient) error) error
+}
+
+// BinaryClient is a BackendClient that can be set to store data that is not
+// JSON, for wrappers that encode it themselves.  StoresBinary returns true
+// if it is so set, in which case the data is not validated and anything
+// that treats it as JSON is unsupported.
+type BinaryClient interface {
+	StoresBinary() bool
+}
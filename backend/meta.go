@@ -0,0 +1,26 @@
+// backend/meta.go -- user-defined metadata.
+
+package backend
+
+// MatchMeta returns true if have has every key in want, with the same
+// value.  Any metadata matches an empty want.
+func MatchMeta(have map[string]string, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := have[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// CopyMeta returns a copy of meta, or nil if it is empty.
+func CopyMeta(meta map[string]string) map[string]string {
+	if len(meta) == 0 {
+		return nil
+	}
+	cp := make(map[string]string, len(meta))
+	for k, v := range meta {
+		cp[k] = v
+	}
+	return cp
+}
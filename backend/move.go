@@ -0,0 +1,53 @@
+// backend/move.go -- helpers for Mover backends.
+
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Overwrite is the policy for copying or moving an object to a path at which
+// there is already a live object.
+type Overwrite int
+
+const (
+	// OverwriteNever fails with ErrConflict, changing nothing.
+	OverwriteNever Overwrite = iota
+	// OverwriteAlways replaces the object at the destination.
+	OverwriteAlways
+	// OverwriteSkip leaves both objects as they are, without error.
+	OverwriteSkip
+)
+
+// String returns the name of the policy.
+func (o Overwrite) String() string {
+	switch o {
+	case OverwriteNever:
+		return "OverwriteNever"
+	case OverwriteAlways:
+		return "OverwriteAlways"
+	case OverwriteSkip:
+		return "OverwriteSkip"
+	}
+	return fmt.Sprintf("Overwrite(%d)", int(o))
+}
+
+// CheckMove returns ErrInvalidPath if src and dst are the same path.
+func CheckMove(src string, dst string) error {
+	if src == dst {
+		return fmt.Errorf("%w: can not copy %s onto itself", ErrInvalidPath, src)
+	}
+	return nil
+}
+
+// CheckMovePrefix returns ErrInvalidPath if either prefix begins with the
+// other, as the objects moved would then overlap with their destinations.
+func CheckMovePrefix(src_prefix string, dst_prefix string) error {
+	if strings.HasPrefix(dst_prefix, src_prefix) ||
+		strings.HasPrefix(src_prefix, dst_prefix) {
+		return fmt.Errorf("%w: prefixes %s and %s overlap", ErrInvalidPath,
+			src_prefix, dst_prefix)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// backend/patch.go -- applying JSON patches.
+
+package backend
+
+import (
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// MergePatch returns raw_obj with the RFC 7396 JSON Merge Patch applied.
+func MergePatch(raw_obj []byte, patch []byte) ([]byte, error) {
+	patched, err := jsonpatch.MergePatch(raw_obj, patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPatchFailed, err)
+	}
+	return patched, nil
+}
+
+// ApplyPatch returns raw_obj with the RFC 6902 JSON Patch applied.
+func ApplyPatch(raw_obj []byte, patch []byte) ([]byte, error) {
+	p, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPatchFailed, err)
+	}
+	patched, err := p.Apply(raw_obj)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPatchFailed, err)
+	}
+	return patched, nil
+}
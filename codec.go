@@ -0,0 +1,85 @@
+// codec.go -- marshaling objects to and from JSON.
+
+package jsobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Codec marshals objects for saving and unmarshals them on loading.  The
+// bundled backends store JSON, so Marshal must produce it; a Codec is the
+// place for a faster JSON library or special handling of certain types.
+type Codec interface {
+	Marshal(obj any) ([]byte, error)
+	Unmarshal(data []byte, obj any) error
+}
+
+// JsonCodec is the default Codec, using encoding/json.
+//
+// If UseNumber is true, numbers loaded into an interface value are
+// json.Number rather than float64, so that large integers keep their
+// precision.  If DisallowUnknownFields is true, loading an object into a
+// struct fails if the object has a field the struct does not.
+type JsonCodec struct {
+	UseNumber             bool
+	DisallowUnknownFields bool
+}
+
+// Marshal implements Codec with json.Marshal.
+func (jc JsonCodec) Marshal(obj any) ([]byte, error) {
+	return json.Marshal(obj)
+}
+
+// Unmarshal implements Codec with json.Unmarshal, or with a json.Decoder if
+// either option is set.
+func (jc JsonCodec) Unmarshal(data []byte, obj any) error {
+
+	if !jc.UseNumber && !jc.DisallowUnknownFields {
+		return json.Unmarshal(data, obj)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if jc.UseNumber {
+		dec.UseNumber()
+	}
+	if jc.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(obj); err != nil {
+		return err
+	}
+	// Unlike json.Unmarshal, the Decoder does not mind trailing data.
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("invalid data after top-level value")
+	}
+	return nil
+
+}
+
+// codec returns the Codec, or the default JsonCodec if it is not set.
+func (c *Client) codec() Codec {
+	if c.Codec == nil {
+		return JsonCodec{}
+	}
+	return c.Codec
+}
+
+// marshal marshals obj with the Codec.
+func (c *Client) marshal(obj any) ([]byte, error) {
+	b, err := c.codec().Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal JSON: %w", err)
+	}
+	return b, nil
+}
+
+// unmarshal unmarshals data into obj with the Codec.
+func (c *Client) unmarshal(data []byte, obj any) error {
+	if err := c.codec().Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("Failed to marshal JSON: %w", err)
+	}
+	return nil
+}
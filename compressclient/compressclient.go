@@ -0,0 +1,301 @@
+// compressclient.go - compressing wrapper for other backend clients
+//
+// Compressed data is recognized on load by its magic bytes, which can never
+// begin valid JSON, so objects saved before compression was turned on (or
+// below the threshold) are loaded as they are.  The encoding is also kept
+// in the metadata for the benefit of other readers.
+
+// Package compressclient implements backend.BackendClient by compressing
+// the data of another backend client.
+package compressclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// MetaKey is the metadata key recording the encoding of compressed objects.
+// It is absent for objects saved uncompressed.
+var MetaKey = "content-encoding"
+
+// Encodings recorded under MetaKey.
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// DefaultThreshold is the smallest data compressed, for new clients.
+var DefaultThreshold = 1024
+
+// DefaultZstdThreshold is the smallest data compressed with zstd rather than
+// gzip, for new clients.
+var DefaultZstdThreshold = 64 * 1024
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Backend is what CompressClient requires of the client it wraps, which must
+// also be set to store binary data.
+type Backend interface {
+	backend.ContextBackendClient
+	backend.MetaClient
+	backend.BinaryClient
+}
+
+// CompressClient is a BackendClient that compresses data of at least
+// Threshold bytes before saving it to its Backend, and decompresses it on
+// loading.  Data of at least ZstdThreshold bytes is compressed with zstd and
+// smaller data with gzip; zero or less means gzip only.  Data that does not
+// get smaller is saved as it is.
+//
+// The data is validated as JSON before it is compressed.  All other
+// operations are passed through to the Backend, so sizes are those of the
+// stored data.  Other optional interfaces of the Backend are not available,
+// as they would bypass the compression.
+//
+// Saves that keep the live object's metadata but change its encoding load
+// the metadata first and save it again, so unlike the Backend's own saves
+// they are not atomic.
+type CompressClient struct {
+	Backend
+	Threshold     int
+	ZstdThreshold int
+
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// New returns a new CompressClient wrapping bc with DefaultThreshold and
+// DefaultZstdThreshold.  If bc is not a Backend storing binary data,
+// backend.ErrUnsupported is returned.
+func New(bc backend.BackendClient) (*CompressClient, error) {
+
+	b, ok := bc.(Backend)
+	if !ok || !b.StoresBinary() {
+		return nil, fmt.Errorf("%w: %s does not store binary data",
+			backend.ErrUnsupported, bc)
+	}
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &CompressClient{
+		Backend:       b,
+		Threshold:     DefaultThreshold,
+		ZstdThreshold: DefaultZstdThreshold,
+		encoder:       encoder,
+		decoder:       decoder,
+	}
+	return client, nil
+}
+
+// String returns an identifying string.
+func (c *CompressClient) String() string {
+	return fmt.Sprintf("compressclient (%s)", c.Backend)
+}
+
+// StoresBinary implements backend.BinaryClient: the data saved must be JSON,
+// whatever the Backend stores.
+func (c *CompressClient) StoresBinary() bool {
+	return false
+}
+
+// SaveRaw compresses and saves raw_obj with no expiry.
+func (c *CompressClient) SaveRaw(path string, raw_obj []byte) error {
+	return c.SaveRawCtx(context.Background(), path, raw_obj)
+}
+
+// SaveRawCtx is SaveRaw with a context.
+func (c *CompressClient) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	return c.save(ctx, path, raw_obj, nil)
+}
+
+// SaveRawExpiry compresses and saves raw_obj for availability until expiry.
+func (c *CompressClient) SaveRawExpiry(path string, raw_obj []byte, expiry time.Time) error {
+	return c.SaveRawExpiryCtx(context.Background(), path, raw_obj, expiry)
+}
+
+// SaveRawExpiryCtx is SaveRawExpiry with a context.
+func (c *CompressClient) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	return c.save(ctx, path, raw_obj, &expiry)
+}
+
+// SaveRawMetaCtx implements backend.MetaClient, setting MetaKey in meta as
+// needed.
+func (c *CompressClient) SaveRawMetaCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, meta map[string]string) error {
+
+	data, encoding, err := c.compress(raw_obj)
+	if err != nil {
+		return err
+	}
+	return c.Backend.SaveRawMetaCtx(ctx, path, data, expiry,
+		withEncoding(meta, encoding))
+}
+
+// save saves raw_obj keeping the live object's metadata, which is only
+// loaded if the encoding is to be set.
+func (c *CompressClient) save(ctx context.Context, path string, raw_obj []byte, expiry *time.Time) error {
+
+	data, encoding, err := c.compress(raw_obj)
+	if err != nil {
+		return err
+	}
+	var meta map[string]string
+	detail, err := c.Backend.LoadDetailCtx(ctx, path)
+	if err == nil {
+		meta = detail.Meta()
+	} else if !errors.Is(err, backend.ErrNotFound) {
+		return err
+	}
+	if meta[MetaKey] != encoding {
+		return c.Backend.SaveRawMetaCtx(ctx, path, data, expiry,
+			withEncoding(meta, encoding))
+	}
+	if expiry == nil {
+		return c.Backend.SaveRawCtx(ctx, path, data)
+	}
+	return c.Backend.SaveRawExpiryCtx(ctx, path, data, *expiry)
+
+}
+
+// withEncoding returns a copy of meta with MetaKey set to encoding, or
+// removed if encoding is empty.
+func withEncoding(meta map[string]string, encoding string) map[string]string {
+
+	meta = backend.CopyMeta(meta)
+	if encoding == "" {
+		delete(meta, MetaKey)
+		return meta
+	}
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	meta[MetaKey] = encoding
+	return meta
+
+}
+
+// compress validates and compresses raw_obj, returning the data to save and
+// its encoding, which is empty if it was not compressed.
+func (c *CompressClient) compress(raw_obj []byte) ([]byte, string, error) {
+
+	if !json.Valid(raw_obj) {
+		return nil, "", backend.ErrInvalidJson
+	}
+	if len(raw_obj) < c.Threshold {
+		return raw_obj, "", nil
+	}
+	var data []byte
+	var encoding string
+	if c.ZstdThreshold > 0 && len(raw_obj) >= c.ZstdThreshold {
+		data = c.encoder.EncodeAll(raw_obj, nil)
+		encoding = Zstd
+	} else {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(raw_obj); err != nil {
+			return nil, "", err
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+		data = buf.Bytes()
+		encoding = Gzip
+	}
+	if len(data) >= len(raw_obj) {
+		return raw_obj, "", nil
+	}
+	return data, encoding, nil
+
+}
+
+// decompress returns data decompressed if it begins with the magic bytes of
+// either encoding, otherwise as it is.
+func (c *CompressClient) decompress(data []byte) ([]byte, error) {
+
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case bytes.HasPrefix(data, zstdMagic):
+		return c.decoder.DecodeAll(data, nil)
+	}
+	return data, nil
+
+}
+
+// LoadRaw loads and decompresses the data at path.
+func (c *CompressClient) LoadRaw(path string) ([]byte, error) {
+	return c.LoadRawCtx(context.Background(), path)
+}
+
+// LoadRawCtx is LoadRaw with a context.
+func (c *CompressClient) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+
+	data, err := c.Backend.LoadRawCtx(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	raw_obj, err := c.decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress %s: %w", path, err)
+	}
+	return raw_obj, nil
+
+}
+
+// Purge purges the Backend, if it is a backend.Purger.
+func (c *CompressClient) Purge() (int, error) {
+	return c.PurgeCtx(context.Background())
+}
+
+// PurgeCtx is Purge with a context.  If the Backend is a Purger without
+// context support, ctx is ignored.
+func (c *CompressClient) PurgeCtx(ctx context.Context) (int, error) {
+
+	switch p := c.Backend.(type) {
+	case backend.ContextPurger:
+		return p.PurgeCtx(ctx)
+	case backend.Purger:
+		return p.Purge()
+	}
+	return 0, fmt.Errorf("%w: %s can not purge", backend.ErrUnsupported,
+		c.Backend)
+
+}
+
+// Shutdown shuts down the Backend and releases the zstd coders, after which
+// the client can not be used.
+func (c *CompressClient) Shutdown() error {
+	return c.ShutdownCtx(context.Background())
+}
+
+// ShutdownCtx is Shutdown with a context.
+func (c *CompressClient) ShutdownCtx(ctx context.Context) error {
+
+	err := c.Backend.ShutdownCtx(ctx)
+	c.encoder.Close()
+	c.decoder.Close()
+	return err
+
+}
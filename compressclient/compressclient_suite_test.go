@@ -0,0 +1,39 @@
+// compressclient_suite_test.go -- test suite rigging
+
+package compressclient_test
+
+import (
+	"testing"
+
+	"github.com/biztos/jsobs/compressclient"
+	"github.com/biztos/jsobs/memclient"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CompressClientTestSuite struct {
+	suite.Suite
+	Backend *memclient.MemClient
+	Client  *compressclient.CompressClient
+}
+
+// The wrapped memclient lets us look at the data as stored.
+func (suite *CompressClientTestSuite) SetupTest() {
+
+	require := suite.Require()
+
+	suite.Backend = memclient.New()
+	suite.Backend.Binary = true
+	client, err := compressclient.New(suite.Backend)
+	require.NoError(err, "new client")
+	suite.Client = client
+}
+
+func (suite *CompressClientTestSuite) TearDownTest() {
+	suite.Client.Shutdown()
+}
+
+// The actual runner func:
+func TestCompressClientTestSuite(t *testing.T) {
+	suite.Run(t, new(CompressClientTestSuite))
+}
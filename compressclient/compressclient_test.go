@@ -0,0 +1,262 @@
+// compressclient_test.go -- tests for the compressing wrapper.
+
+package compressclient_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/compressclient"
+	"github.com/biztos/jsobs/memclient"
+)
+
+// bigJson returns repetitive JSON of at least size bytes.
+func bigJson(size int) []byte {
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"items":[`)
+	for i := 0; buf.Len() < size; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `{"id":%d,"status":"open","note":"same old"}`, i)
+	}
+	buf.WriteString(`]}`)
+	return buf.Bytes()
+}
+
+func (suite *CompressClientTestSuite) TestNewNotBinaryFails() {
+
+	require := suite.Require()
+
+	_, err := compressclient.New(memclient.New())
+	require.ErrorIs(err, backend.ErrUnsupported)
+	require.ErrorContains(err, "does not store binary data")
+}
+
+func (suite *CompressClientTestSuite) TestImplementsContextBackendClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.ContextBackendClient)(nil), suite.Client)
+}
+
+func (suite *CompressClientTestSuite) TestImplementsMetaClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.MetaClient)(nil), suite.Client)
+}
+
+func (suite *CompressClientTestSuite) TestHidesVersioner() {
+
+	require := suite.Require()
+	require.Implements((*backend.Versioner)(nil), suite.Backend)
+	_, ok := any(suite.Client).(backend.Versioner)
+	require.False(ok, "versioner would bypass compression")
+}
+
+func (suite *CompressClientTestSuite) TestStringOK() {
+
+	require := suite.Require()
+	require.Equal("compressclient (memclient (objects=0))", suite.Client.String())
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawSmallOK() {
+
+	require := suite.Require()
+
+	raw := []byte(`{"a":1}`)
+	require.NoError(suite.Client.SaveRaw("/a", raw))
+
+	stored, err := suite.Backend.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal(raw, stored)
+	detail, err := suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Nil(detail.Meta())
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawGzipOK() {
+
+	require := suite.Require()
+
+	raw := bigJson(4096)
+	require.NoError(suite.Client.SaveRaw("/a", raw))
+
+	stored, err := suite.Backend.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal([]byte{0x1f, 0x8b}, stored[:2])
+	require.Less(len(stored), len(raw))
+	detail, err := suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Equal(map[string]string{"content-encoding": "gzip"}, detail.Meta())
+	require.Equal(len(stored), detail.Size())
+
+	loaded, err := suite.Client.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal(raw, loaded)
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawZstdOK() {
+
+	require := suite.Require()
+
+	raw := bigJson(100000)
+	require.NoError(suite.Client.SaveRaw("/a", raw))
+
+	stored, err := suite.Backend.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal([]byte{0x28, 0xb5, 0x2f, 0xfd}, stored[:4])
+	detail, err := suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Equal(map[string]string{"content-encoding": "zstd"}, detail.Meta())
+
+	loaded, err := suite.Client.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal(raw, loaded)
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawGzipOnlyOK() {
+
+	require := suite.Require()
+
+	suite.Client.ZstdThreshold = 0
+	require.NoError(suite.Client.SaveRaw("/a", bigJson(100000)))
+
+	detail, err := suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Equal("gzip", detail.Meta()["content-encoding"])
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawIncompressibleOK() {
+
+	require := suite.Require()
+
+	suite.Client.Threshold = 0
+	raw := []byte(`{"a":1}`)
+	require.NoError(suite.Client.SaveRaw("/a", raw))
+
+	stored, err := suite.Backend.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal(raw, stored)
+	detail, err := suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Nil(detail.Meta())
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawInvalidJsonFails() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveRaw("/a", append([]byte("nope"), bigJson(4096)...))
+	require.ErrorIs(err, backend.ErrInvalidJson)
+	_, err = suite.Backend.LoadRaw("/a")
+	require.ErrorIs(err, backend.ErrNotFound)
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawExpiryOK() {
+
+	require := suite.Require()
+
+	expiry := time.Now().Add(time.Hour).Round(0)
+	raw := bigJson(4096)
+	require.NoError(suite.Client.SaveRawExpiry("/a", raw, expiry))
+
+	detail, err := suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.True(detail.Expires())
+	require.True(expiry.Equal(detail.Expiry()))
+	require.Equal("gzip", detail.Meta()["content-encoding"])
+	loaded, err := suite.Client.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal(raw, loaded)
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawKeepsMetaOK() {
+
+	require := suite.Require()
+	ctx := context.Background()
+
+	meta := map[string]string{"owner": "me"}
+	require.NoError(suite.Client.SaveRawMetaCtx(ctx, "/a", []byte(`{}`),
+		nil, meta))
+
+	require.NoError(suite.Client.SaveRaw("/a", bigJson(4096)))
+	detail, err := suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Equal(map[string]string{"owner": "me", "content-encoding": "gzip"},
+		detail.Meta())
+
+	require.NoError(suite.Client.SaveRaw("/a", bigJson(8192)))
+	detail, err = suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Equal(map[string]string{"owner": "me", "content-encoding": "gzip"},
+		detail.Meta())
+
+	require.NoError(suite.Client.SaveRaw("/a", []byte(`{"a":1}`)))
+	detail, err = suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Equal(meta, detail.Meta())
+}
+
+func (suite *CompressClientTestSuite) TestSaveRawMetaSetsEncodingOK() {
+
+	require := suite.Require()
+	ctx := context.Background()
+
+	meta := map[string]string{"owner": "me", "content-encoding": "zstd"}
+	require.NoError(suite.Client.SaveRawMetaCtx(ctx, "/a", []byte(`{}`),
+		nil, meta))
+	detail, err := suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Equal(map[string]string{"owner": "me"}, detail.Meta())
+	require.Equal("zstd", meta["content-encoding"], "caller's meta unchanged")
+
+	require.NoError(suite.Client.SaveRawMetaCtx(ctx, "/a", bigJson(4096),
+		nil, nil))
+	detail, err = suite.Client.LoadDetail("/a")
+	require.NoError(err)
+	require.Equal(map[string]string{"content-encoding": "gzip"}, detail.Meta())
+}
+
+func (suite *CompressClientTestSuite) TestLoadRawUncompressedOK() {
+
+	require := suite.Require()
+
+	raw := bigJson(4096)
+	require.NoError(suite.Backend.SaveRaw("/a", raw))
+
+	loaded, err := suite.Client.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal(raw, loaded)
+}
+
+func (suite *CompressClientTestSuite) TestLoadRawCorruptFails() {
+
+	require := suite.Require()
+
+	require.NoError(suite.Backend.SaveRaw("/a", []byte{0x1f, 0x8b, 0, 0}))
+	_, err := suite.Client.LoadRaw("/a")
+	require.ErrorContains(err, "Failed to decompress /a")
+}
+
+func (suite *CompressClientTestSuite) TestLoadRawNotFound() {
+
+	require := suite.Require()
+
+	_, err := suite.Client.LoadRaw("/nope")
+	require.ErrorIs(err, backend.ErrNotFound)
+}
+
+func (suite *CompressClientTestSuite) TestPurgeOK() {
+
+	require := suite.Require()
+
+	expiry := time.Now().Add(-time.Second)
+	require.NoError(suite.Backend.SaveRawExpiry("/a", []byte(`{}`), expiry))
+	purged, err := suite.Client.Purge()
+	require.NoError(err)
+	require.Equal(1, purged)
+}
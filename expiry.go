@@ -0,0 +1,78 @@
+// expiry.go -- changing the expiry of stored objects.
+
+package jsobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// SetExpiry sets the expiry of the live object at path, without changing its
+// data.  If there is no live object the error is ErrNotFound.
+//
+// If the Backend implements backend.Expirer only the expiry is changed.
+// Otherwise the object is loaded and saved again with SaveRawIfVersion,
+// which gives it a new version, starting over on conflict up to
+// UpdateRetries times; backends not implementing backend.Versioner return
+// ErrUnsupported.
+func (c *Client) SetExpiry(path string, expiry time.Time) error {
+	return c.SetExpiryCtx(context.Background(), path, expiry)
+}
+
+// SetExpiryCtx is SetExpiry with a context.
+func (c *Client) SetExpiryCtx(ctx context.Context, path string, expiry time.Time) error {
+	return c.setExpiry(ctx, path, &expiry)
+}
+
+// ClearExpiry removes the expiry of the live object at path, so that it is
+// kept until deleted, as for SetExpiry.
+func (c *Client) ClearExpiry(path string) error {
+	return c.ClearExpiryCtx(context.Background(), path)
+}
+
+// ClearExpiryCtx is ClearExpiry with a context.
+func (c *Client) ClearExpiryCtx(ctx context.Context, path string) error {
+	return c.setExpiry(ctx, path, nil)
+}
+
+// Touch sets the expiry of the live object at path to ttl from now, as for
+// SetExpiry.
+func (c *Client) Touch(path string, ttl time.Duration) error {
+	return c.TouchCtx(context.Background(), path, ttl)
+}
+
+// TouchCtx is Touch with a context.
+func (c *Client) TouchCtx(ctx context.Context, path string, ttl time.Duration) error {
+	return c.SetExpiryCtx(ctx, path, time.Now().Add(ttl))
+}
+
+func (c *Client) setExpiry(ctx context.Context, path string, expiry *time.Time) error {
+
+	if e, ok := c.Backend.(backend.Expirer); ok {
+		return e.SetExpiryCtx(ctx, path, expiry)
+	}
+	v, err := c.versioner()
+	if err != nil {
+		return err
+	}
+
+	for tries := 0; ; tries++ {
+		raw_obj, version, err := v.LoadRawVersionCtx(ctx, path)
+		if err != nil {
+			return err
+		}
+		err = v.SaveRawIfVersionCtx(ctx, path, raw_obj, expiry, version)
+		if !errors.Is(err, ErrConflict) {
+			return err
+		}
+		if tries >= UpdateRetries {
+			return fmt.Errorf("%w: %s changed during %d tries", ErrConflict,
+				path, tries+1)
+		}
+	}
+
+}
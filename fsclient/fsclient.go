@@ -0,0 +1,725 @@
+// fsclient.go - file system backend client
+//
+// Objects are stored as plain files beneath Root, so that "/demo/t0.json"
+// lives at Root/demo/t0.json and can be inspected with ordinary shell tools.
+// Expiry, modification time and version are kept in a hidden sidecar next to
+// the object, e.g. Root/demo/.t0.json.jsobs -- which is why no element of an
+// object path may begin with a dot.
+//
+// Unlike the database backends, a path can not hold an object and also be
+// the parent of other objects: having saved /a you can not save /a/b.
+//
+// Writes go to a temporary file which is then renamed into place, so readers
+// never see a partial object.  The data is written before its sidecar, so a
+// failed save leaves the old sidecar at worst, never a new version next to
+// the old data; but the pair is not replaced atomically, and a crash in
+// between keeps the old expiry, version and metadata with the new data.
+// Within a process the client is safe for concurrent use; across processes
+// you get whatever the file system gives.
+
+// Package fsclient implements backend.BackendClient on the file system.
+package fsclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// Override if using multiple roots in your process.
+var RootDirEnvVar = "JSOBS_FS_ROOT"
+
+// MetaSuffix is appended to the dotted object file name to make the sidecar
+// file name.
+var MetaSuffix = ".jsobs"
+
+// Errors as defined in the backend package.  ErrInvalidPath is returned for
+// object paths that can not be mapped safely beneath Root, and expired
+// objects are reported as backend.ErrExpired.
+var (
+	ErrNotFound    = backend.ErrNotFound
+	ErrInvalidPath = backend.ErrInvalidPath
+	ErrInvalidJson = backend.ErrInvalidJson
+)
+
+// FsDetailer implements backend.Detailer to describe an object.
+type FsDetailer struct {
+	path     string
+	size     int
+	expiry   *time.Time
+	modified time.Time
+	version  int64
+	meta     map[string]string
+}
+
+// Path implements backend.Detailer.
+func (d *FsDetailer) Path() string {
+	return d.path
+}
+
+// Size implements backend.Detailer.
+func (d *FsDetailer) Size() int {
+	return d.size
+}
+
+// Expires implements backend.Detailer.
+func (d *FsDetailer) Expires() bool {
+	return d.expiry != nil
+}
+
+// Expiry implements backend.Detailer. If Expires returns false then Expiry must
+// be ignored.
+func (d *FsDetailer) Expiry() time.Time {
+	if d.expiry == nil {
+		return time.Time{} // "zero time"
+	}
+	return *d.expiry
+}
+
+// Modified implements backend.Detailer.
+func (d *FsDetailer) Modified() time.Time {
+	return d.modified
+}
+
+// Version implements backend.Detailer.
+func (d *FsDetailer) Version() int64 {
+	return d.version
+}
+
+// Meta implements backend.Detailer.
+func (d *FsDetailer) Meta() map[string]string {
+	return d.meta
+}
+
+// fsMeta is the content of the sidecar file.
+type fsMeta struct {
+	Expiry   *time.Time        `json:"expiry,omitempty"`
+	Modified time.Time         `json:"modified"`
+	Version  int64             `json:"version"`
+	Meta     map[string]string `json:"meta,omitempty"`
+}
+
+func (m *fsMeta) expired(now time.Time) bool {
+	return m.Expiry != nil && !m.Expiry.After(now)
+}
+
+// FsClient is a BackendClient storing objects as files under Root.
+type FsClient struct {
+	Root            string
+	FileMode        fs.FileMode
+	DirMode         fs.FileMode
+	PurgeOnShutdown bool
+
+	mutex sync.RWMutex
+}
+
+// String returns an identifying string.
+func (c *FsClient) String() string {
+	return fmt.Sprintf("fsclient (root=%s)", c.Root)
+}
+
+// New returns a new FsClient for the directory named at RootDirEnvVar, with
+// PurgeOnShutdown true.
+func New() (*FsClient, error) {
+	root := os.Getenv(RootDirEnvVar)
+	if root == "" {
+		return nil, errors.New(RootDirEnvVar + " not defined in env")
+	}
+	return NewForRoot(root)
+}
+
+// NewForRoot returns a new FsClient for root, which is created if it does
+// not exist, with defaults as in New.
+func NewForRoot(root string) (*FsClient, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	client := &FsClient{
+		Root:            abs,
+		FileMode:        0644,
+		DirMode:         0755,
+		PurgeOnShutdown: true,
+	}
+	if err := os.MkdirAll(abs, client.DirMode); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// filePath returns the file system path for object path, or ErrInvalidPath
+// if the path is not of the form /a/b/c with every element safe.
+func (c *FsClient) filePath(path string) (string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("%w: %q must begin with /", ErrInvalidPath, path)
+	}
+	elems := strings.Split(path[1:], "/")
+	for _, e := range elems {
+		if e == "" || strings.HasPrefix(e, ".") ||
+			strings.ContainsAny(e, "\\\x00") {
+			return "", fmt.Errorf("%w: %q", ErrInvalidPath, path)
+		}
+	}
+	return filepath.Join(c.Root, filepath.FromSlash(path[1:])), nil
+}
+
+// metaPath returns the sidecar path for the file at file_path.
+func metaPath(file_path string) string {
+	dir, name := filepath.Split(file_path)
+	return filepath.Join(dir, "."+name+MetaSuffix)
+}
+
+// objectPath returns the object path for the file at file_path.
+func (c *FsClient) objectPath(file_path string) string {
+	rel, _ := filepath.Rel(c.Root, file_path)
+	return "/" + filepath.ToSlash(rel)
+}
+
+// writeFile writes data atomically via a temp file in the same directory.
+func (c *FsClient) writeFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(c.FileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (c *FsClient) save(path string, raw_obj []byte, expiry *time.Time) error {
+	return c.saveIf(path, raw_obj, expiry, -1, nil)
+}
+
+// saveIf saves raw_obj if the live version at path is version, with zero
+// for no live object; a negative version saves unconditionally.  The live
+// object's metadata is kept unless meta is not nil.
+func (c *FsClient) saveIf(path string, raw_obj []byte, expiry *time.Time, version int64, meta *map[string]string) error {
+
+	file_path, err := c.filePath(path)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(raw_obj) {
+		return ErrInvalidJson
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	current := int64(0)
+	var keep map[string]string
+	_, old_meta, err := c.stat(file_path)
+	if err == nil {
+		current = old_meta.Version
+		keep = old_meta.Meta
+	} else if version >= 0 && !errors.Is(err, ErrNotFound) {
+		return err // plain saves may overwrite bad metadata
+	}
+	if version >= 0 && version != current {
+		return fmt.Errorf("%w: %s not at version %d", backend.ErrConflict,
+			path, version)
+	}
+	if meta != nil {
+		keep = backend.CopyMeta(*meta)
+	}
+	sidecar, err := json.Marshal(&fsMeta{
+		Expiry:   expiry,
+		Modified: time.Now(),
+		Version:  current + 1,
+		Meta:     keep,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file_path), c.DirMode); err != nil {
+		return err
+	}
+	if err := c.writeFile(file_path, raw_obj); err != nil {
+		return err
+	}
+	return c.writeFile(metaPath(file_path), sidecar)
+}
+
+// SaveRaw saves the raw_obj to a file with no expiry.
+func (c *FsClient) SaveRaw(path string, raw_obj []byte) error {
+	return c.SaveRawCtx(context.Background(), path, raw_obj)
+}
+
+// SaveRawCtx is SaveRaw with a context.
+func (c *FsClient) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.save(path, raw_obj, nil)
+}
+
+// SaveRawExpiry saves the raw bytes to a file for availability until expiry.
+func (c *FsClient) SaveRawExpiry(path string, raw_obj []byte, expiry time.Time) error {
+	return c.SaveRawExpiryCtx(context.Background(), path, raw_obj, expiry)
+}
+
+// SaveRawExpiryCtx is SaveRawExpiry with a context.
+func (c *FsClient) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.save(path, raw_obj, &expiry)
+}
+
+// notExist returns true if err means there is no file, including when a
+// parent of the file is a file itself, as for /a/b after saving /a.
+func notExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR)
+}
+
+// readMeta reads the sidecar for the file at file_path.  Files without a
+// sidecar, e.g. ones copied in by hand, never expire, use the file's
+// modification time and are at version 1.
+func readMeta(file_path string, info fs.FileInfo) (*fsMeta, error) {
+	b, err := os.ReadFile(metaPath(file_path))
+	if notExist(err) {
+		return &fsMeta{Modified: info.ModTime(), Version: 1}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := &fsMeta{}
+	if err := json.Unmarshal(b, meta); err != nil {
+		return nil, fmt.Errorf("bad metadata for %s: %w", file_path, err)
+	}
+	if meta.Version < 1 {
+		meta.Version = 1 // saved before versions were kept
+	}
+	return meta, nil
+}
+
+// stat returns the info and metadata for a live object at file_path, or
+// ErrNotFound if it does not exist or ErrExpired if expired.  The caller must
+// hold the lock.
+func (c *FsClient) stat(file_path string) (fs.FileInfo, *fsMeta, error) {
+	info, err := os.Stat(file_path)
+	if notExist(err) || (err == nil && info.IsDir()) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	meta, err := readMeta(file_path, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta.expired(time.Now()) {
+		return nil, nil, backend.ErrExpired
+	}
+	return info, meta, nil
+}
+
+// LoadRaw retrieves the object at path and returns its raw value.
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *FsClient) LoadRaw(path string) ([]byte, error) {
+	return c.LoadRawCtx(context.Background(), path)
+}
+
+// LoadRawCtx is LoadRaw with a context.
+func (c *FsClient) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	file_path, err := c.filePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if _, _, err := c.stat(file_path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(file_path)
+}
+
+// LoadRawVersionCtx implements backend.Versioner.
+func (c *FsClient) LoadRawVersionCtx(ctx context.Context, path string) ([]byte, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	file_path, err := c.filePath(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	_, meta, err := c.stat(file_path)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := os.ReadFile(file_path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, meta.Version, nil
+}
+
+// SaveRawIfVersionCtx implements backend.Versioner.
+//
+// The check is only atomic within this process: other processes writing to
+// the same Root are not locked out.
+func (c *FsClient) SaveRawIfVersionCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if version < 0 {
+		return fmt.Errorf("%w: negative version %d", backend.ErrConflict, version)
+	}
+	return c.saveIf(path, raw_obj, expiry, version, nil)
+}
+
+// SaveRawMetaCtx implements backend.MetaClient, keeping meta in the sidecar.
+func (c *FsClient) SaveRawMetaCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, meta map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.saveIf(path, raw_obj, expiry, -1, &meta)
+}
+
+// SetExpiryCtx implements backend.Expirer by rewriting only the sidecar.
+func (c *FsClient) SetExpiryCtx(ctx context.Context, path string, expiry *time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	file_path, err := c.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, meta, err := c.stat(file_path)
+	if err != nil {
+		return err
+	}
+	meta.Expiry = expiry
+	sidecar, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return c.writeFile(metaPath(file_path), sidecar)
+}
+
+// LoadDetail retrieves the details of the object at path and returns its
+// a jsobs.Detailer.
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *FsClient) LoadDetail(path string) (backend.Detailer, error) {
+	return c.LoadDetailCtx(context.Background(), path)
+}
+
+// LoadDetailCtx is LoadDetail with a context.
+func (c *FsClient) LoadDetailCtx(ctx context.Context, path string) (backend.Detailer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	file_path, err := c.filePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	info, meta, err := c.stat(file_path)
+	if err != nil {
+		return nil, err
+	}
+	return &FsDetailer{
+		path:     path,
+		size:     int(info.Size()),
+		expiry:   meta.Expiry,
+		modified: meta.Modified,
+		version:  meta.Version,
+		meta:     meta.Meta,
+	}, nil
+}
+
+// removeEmptyDirs removes dir and its parents, up to but not including Root,
+// for as long as they are empty.
+func (c *FsClient) removeEmptyDirs(dir string) {
+	for dir != c.Root && strings.HasPrefix(dir, c.Root) {
+		if os.Remove(dir) != nil {
+			return // not empty, most likely
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// remove removes the file at file_path and its sidecar.  The caller must
+// hold the write lock.
+func (c *FsClient) remove(file_path string) error {
+	if err := os.Remove(file_path); err != nil {
+		return err
+	}
+	if err := os.Remove(metaPath(file_path)); err != nil &&
+		!notExist(err) {
+		return err
+	}
+	c.removeEmptyDirs(filepath.Dir(file_path))
+	return nil
+}
+
+// Delete deletes the object at path, and removes any directories left empty.
+//
+// As with pgclient, an expired object will still be deleted.
+//
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *FsClient) Delete(path string) error {
+	return c.DeleteCtx(context.Background(), path)
+}
+
+// DeleteCtx is Delete with a context.
+func (c *FsClient) DeleteCtx(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	file_path, err := c.filePath(path)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	info, err := os.Stat(file_path)
+	if notExist(err) || (err == nil && info.IsDir()) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return c.remove(file_path)
+}
+
+// walk calls fn for every object file whose path begins with prefix, with
+// its object path, info and metadata, in no particular order.  Hidden files
+// and directories are skipped, and the walk stops if ctx is done.  The
+// caller must hold the lock.
+func (c *FsClient) walk(ctx context.Context, prefix string, fn func(string, string, fs.FileInfo, *fsMeta) error) error {
+
+	// Start as deep in the tree as the prefix allows.
+	start := c.Root
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		dir, err := c.filePath(prefix[:i])
+		if err != nil {
+			// Nothing can be stored under an invalid path.
+			return nil
+		}
+		start = dir
+	}
+	err := filepath.WalkDir(start, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if notExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && name != start {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		path := c.objectPath(name)
+		if !strings.HasPrefix(path, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		meta, err := readMeta(name, info)
+		if err != nil {
+			return err
+		}
+		return fn(path, name, info, meta)
+	})
+	return err
+}
+
+// List returns an array of all objects beginning with prefix.  An empty array
+// is not considered an error.
+func (c *FsClient) List(prefix string) ([]string, error) {
+	return c.ListCtx(context.Background(), prefix)
+}
+
+// ListCtx is List with a context.
+func (c *FsClient) ListCtx(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := time.Now()
+	paths := []string{}
+	err := c.walk(ctx, prefix, func(path, _ string, _ fs.FileInfo, meta *fsMeta) error {
+		if !meta.expired(now) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ListDetail returns an array of all Detailers describing all objects
+// beginning with prefix.  An empty array is not considered an error.
+func (c *FsClient) ListDetail(prefix string) ([]backend.Detailer, error) {
+	return c.ListDetailCtx(context.Background(), prefix)
+}
+
+// ListDetailCtx is ListDetail with a context.
+func (c *FsClient) ListDetailCtx(ctx context.Context, prefix string) ([]backend.Detailer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := time.Now()
+	detailers := []backend.Detailer{}
+	err := c.walk(ctx, prefix, func(path, _ string, info fs.FileInfo, meta *fsMeta) error {
+		if !meta.expired(now) {
+			detailers = append(detailers, &FsDetailer{
+				path:     path,
+				size:     int(info.Size()),
+				expiry:   meta.Expiry,
+				modified: meta.Modified,
+				version:  meta.Version,
+				meta:     meta.Meta,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(detailers, func(i, j int) bool {
+		return detailers[i].Path() < detailers[j].Path()
+	})
+	return detailers, nil
+}
+
+// Count returns the number of non-expired objects beginning with prefix.
+// If none are found, zero is returned.
+func (c *FsClient) Count(prefix string) (int, error) {
+	return c.CountCtx(context.Background(), prefix)
+}
+
+// CountCtx is Count with a context.
+func (c *FsClient) CountCtx(ctx context.Context, prefix string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := time.Now()
+	count := 0
+	err := c.walk(ctx, prefix, func(_, _ string, _ fs.FileInfo, meta *fsMeta) error {
+		if !meta.expired(now) {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// CountAll returns the total number of non-expired objects.
+func (c *FsClient) CountAll() (int, error) {
+	return c.CountAllCtx(context.Background())
+}
+
+// CountAllCtx is CountAll with a context.
+func (c *FsClient) CountAllCtx(ctx context.Context) (int, error) {
+	return c.CountCtx(ctx, "/")
+}
+
+// Purge deletes expired objects and their sidecars.  Returns the number of
+// objects deleted.
+func (c *FsClient) Purge() (int, error) {
+	return c.PurgeCtx(context.Background())
+}
+
+// PurgeCtx is Purge with a context.
+func (c *FsClient) PurgeCtx(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := time.Now()
+	expired := []string{}
+	err := c.walk(ctx, "/", func(_, name string, _ fs.FileInfo, meta *fsMeta) error {
+		if meta.expired(now) {
+			expired = append(expired, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, name := range expired {
+		if err := c.remove(name); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Shutdown calls Purge if PurgeOnShutdown is true.
+func (c *FsClient) Shutdown() error {
+	return c.ShutdownCtx(context.Background())
+}
+
+// ShutdownCtx is Shutdown with a context.
+func (c *FsClient) ShutdownCtx(ctx context.Context) error {
+	if c.PurgeOnShutdown {
+		_, err := c.PurgeCtx(ctx)
+		return err
+	}
+	return nil
+}
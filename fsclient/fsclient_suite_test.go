@@ -0,0 +1,87 @@
+// fsclient_suite_test.go -- test suite rigging
+
+package fsclient_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/biztos/jsobs/fsclient"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FsClientTestSuite struct {
+	suite.Suite
+	Client *fsclient.FsClient
+}
+
+// Use a new root per test, so there are no fragments.
+func (suite *FsClientTestSuite) SetupTest() {
+
+	require := suite.Require()
+
+	dir, err := os.MkdirTemp("", "jsobs_fs_test_")
+	require.NoError(err, "temp dir")
+
+	client, err := fsclient.NewForRoot(dir)
+	require.NoError(err, "client setup err")
+	suite.Client = client
+
+}
+
+// Remove the test root unless KEEP_DB is set.
+func (suite *FsClientTestSuite) TearDownTest() {
+
+	require := suite.Require()
+
+	val := os.Getenv("KEEP_DB")
+	if val != "" && val != "0" && val != "false" {
+		return
+	}
+
+	require.NoError(os.RemoveAll(suite.Client.Root), "remove root")
+
+}
+
+// see below...
+type SaveSetResult struct {
+	Paths    []string
+	PathData map[string][]byte
+}
+
+// We need sets of data a lot, with expiry in future or not at all.
+func (suite *FsClientTestSuite) SaveSet(count int, pfmt string, exp *time.Time) *SaveSetResult {
+
+	require := suite.Require() // bail out on err
+
+	paths := make([]string, count)
+	path_data := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		path := fmt.Sprintf(pfmt, i)
+		data := []byte(fmt.Sprintf(`{"n":%d}`, i))
+		paths[i] = path
+		path_data[path] = data
+		var err error
+		if exp == nil {
+			err = suite.Client.SaveRaw(path, data)
+		} else {
+			err = suite.Client.SaveRawExpiry(path, data, *exp)
+		}
+
+		require.NoError(err, "save error")
+
+	}
+	return &SaveSetResult{
+		Paths:    paths,
+		PathData: path_data,
+	}
+
+}
+
+// The actual runner func:
+func TestFsClientTestSuite(t *testing.T) {
+	suite.Run(t, new(FsClientTestSuite))
+}
@@ -0,0 +1,787 @@
+// fsclient_test.go
+//
+// suite rigging is in fsclient_suite_test.go, actual tests are here.
+
+package fsclient_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/fsclient"
+)
+
+func (suite *FsClientTestSuite) TestNewFailsNoRoot() {
+
+	require := suite.Require()
+
+	fsclient.RootDirEnvVar = "OTHER_JSOBS_FS_ROOT"
+	os.Setenv("OTHER_JSOBS_FS_ROOT", "")
+	defer func() { fsclient.RootDirEnvVar = "JSOBS_FS_ROOT" }()
+
+	_, err := fsclient.New()
+	require.ErrorContains(err, "OTHER_JSOBS_FS_ROOT not defined in env")
+
+}
+
+func (suite *FsClientTestSuite) TestNewFailsRootIsFile() {
+
+	require := suite.Require()
+
+	file := filepath.Join(suite.Client.Root, "file")
+	require.NoError(os.WriteFile(file, []byte("x"), 0644))
+
+	orig := os.Getenv("JSOBS_FS_ROOT")
+	defer os.Setenv("JSOBS_FS_ROOT", orig)
+	os.Setenv("JSOBS_FS_ROOT", filepath.Join(file, "sub"))
+
+	_, err := fsclient.New()
+	require.ErrorContains(err, "not a directory")
+
+}
+
+func (suite *FsClientTestSuite) TestNewCreatesRootOK() {
+
+	require := suite.Require()
+
+	root := filepath.Join(suite.Client.Root, "new", "root")
+
+	orig := os.Getenv("JSOBS_FS_ROOT")
+	defer os.Setenv("JSOBS_FS_ROOT", orig)
+	os.Setenv("JSOBS_FS_ROOT", root)
+
+	c, err := fsclient.New()
+	require.NoError(err)
+	require.Equal(root, c.Root)
+	require.True(c.PurgeOnShutdown)
+	require.DirExists(root)
+
+}
+
+func (suite *FsClientTestSuite) TestStringOK() {
+
+	require := suite.Require()
+
+	exp := fmt.Sprintf("fsclient (root=%s)", suite.Client.Root)
+	require.Equal(exp, suite.Client.String(), "String")
+}
+
+func (suite *FsClientTestSuite) TestInvalidPathsFail() {
+
+	require := suite.Require()
+
+	bad := []string{
+		"",
+		"no/slash",
+		"/",
+		"/trailing/",
+		"/double//slash",
+		"/../escape",
+		"/a/../../escape",
+		"/a/./b",
+		"/.hidden",
+		"/a/.t0.json.jsobs",
+		"/back\\slash",
+		"/nul\x00byte",
+	}
+	for _, path := range bad {
+		err := suite.Client.SaveRaw(path, []byte(`{}`))
+		require.ErrorIs(err, fsclient.ErrInvalidPath, "save %q", path)
+		_, err = suite.Client.LoadRaw(path)
+		require.ErrorIs(err, fsclient.ErrInvalidPath, "load %q", path)
+		_, err = suite.Client.LoadDetail(path)
+		require.ErrorIs(err, fsclient.ErrInvalidPath, "detail %q", path)
+		err = suite.Client.Delete(path)
+		require.ErrorIs(err, fsclient.ErrInvalidPath, "delete %q", path)
+	}
+
+	// And nothing was written anywhere.
+	entries, err := os.ReadDir(suite.Client.Root)
+	require.NoError(err)
+	require.Empty(entries)
+
+}
+
+func (suite *FsClientTestSuite) TestSaveRawFailsBadJson() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveRaw("/any", []byte("not json"))
+	require.ErrorIs(err, fsclient.ErrInvalidJson)
+
+}
+
+func (suite *FsClientTestSuite) TestSaveRawFailsParentIsFile() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/file/%d", nil)
+	err := suite.Client.SaveRaw("/file/0/child", []byte(`{}`))
+	require.ErrorContains(err, "not a directory")
+
+}
+
+func (suite *FsClientTestSuite) TestSaveRawFailsKeepsSidecar() {
+
+	require := suite.Require()
+
+	require.NoError(suite.Client.SaveRaw("/w", []byte(`{}`)))
+	sidecar := filepath.Join(suite.Client.Root, ".w.jsobs")
+	before, err := os.ReadFile(sidecar)
+	require.NoError(err)
+
+	// A non-empty directory in place of the data file can not be replaced.
+	file_path := filepath.Join(suite.Client.Root, "w")
+	require.NoError(os.Remove(file_path))
+	require.NoError(os.MkdirAll(filepath.Join(file_path, "x"), 0755))
+	require.Error(suite.Client.SaveRaw("/w", []byte(`{"a":1}`)))
+
+	after, err := os.ReadFile(sidecar)
+	require.NoError(err)
+	require.Equal(string(before), string(after), "sidecar untouched")
+
+}
+
+func (suite *FsClientTestSuite) TestSaveRawWritesFilesOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	require.NoError(suite.Client.SaveRaw("/demo/t0.json", data))
+
+	b, err := os.ReadFile(filepath.Join(suite.Client.Root, "demo", "t0.json"))
+	require.NoError(err)
+	require.Equal(data, b, "plain file")
+	require.FileExists(filepath.Join(suite.Client.Root, "demo", ".t0.json.jsobs"),
+		"sidecar")
+
+	// No temp files left over.
+	entries, err := os.ReadDir(filepath.Join(suite.Client.Root, "demo"))
+	require.NoError(err)
+	require.Equal(2, len(entries), "entries")
+
+}
+
+func (suite *FsClientTestSuite) TestLoadRawFailsNotFound() {
+
+	require := suite.Require()
+
+	data, err := suite.Client.LoadRaw("/not/here")
+	require.ErrorIs(err, fsclient.ErrNotFound)
+	require.ErrorIs(err, backend.ErrNotFound)
+	require.NotErrorIs(err, backend.ErrExpired)
+	require.Nil(data)
+
+}
+
+func (suite *FsClientTestSuite) TestLoadRawFailsDirectory() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/dir/%d", nil)
+	data, err := suite.Client.LoadRaw("/dir")
+	require.ErrorIs(err, fsclient.ErrNotFound)
+	require.Nil(data)
+
+}
+
+func (suite *FsClientTestSuite) TestLoadRawFailsBelowObject() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/file/%d", nil)
+	data, err := suite.Client.LoadRaw("/file/0/child")
+	require.ErrorIs(err, fsclient.ErrNotFound)
+	require.Nil(data)
+
+	_, err = suite.Client.LoadDetail("/file/0/child")
+	require.ErrorIs(err, fsclient.ErrNotFound)
+
+}
+
+func (suite *FsClientTestSuite) TestLoadRawFailsExpired() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/expired/%d", &past)
+
+	data, err := suite.Client.LoadRaw("/expired/0")
+	require.ErrorIs(err, fsclient.ErrNotFound)
+	require.ErrorIs(err, backend.ErrExpired)
+	require.Nil(data)
+
+	detail, err := suite.Client.LoadDetail("/expired/0")
+	require.ErrorIs(err, backend.ErrExpired)
+	require.Nil(detail)
+
+}
+
+func (suite *FsClientTestSuite) TestLoadRawFailsBadSidecar() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/bad/%d", nil)
+	meta := filepath.Join(suite.Client.Root, "bad", ".0.jsobs")
+	require.NoError(os.WriteFile(meta, []byte("nope"), 0644))
+
+	_, err := suite.Client.LoadRaw("/bad/0")
+	require.ErrorContains(err, "bad metadata")
+
+}
+
+func (suite *FsClientTestSuite) TestSaveRawLoadRawOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	err := suite.Client.SaveRaw("/any/thing.json", data)
+	require.NoError(err)
+
+	fetched, err := suite.Client.LoadRaw("/any/thing.json")
+	require.NoError(err)
+	require.EqualValues(data, fetched)
+
+}
+
+func (suite *FsClientTestSuite) TestSaveRawExpiryLoadRawOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	err := suite.Client.SaveRawExpiry("/any/thing.json", data,
+		time.Now().Add(time.Hour))
+	require.NoError(err)
+
+	fetched, err := suite.Client.LoadRaw("/any/thing.json")
+	require.NoError(err)
+	require.EqualValues(data, fetched)
+
+}
+
+func (suite *FsClientTestSuite) TestLoadWithoutSidecarOK() {
+
+	require := suite.Require()
+
+	// As if put there by hand.
+	dir := filepath.Join(suite.Client.Root, "manual")
+	require.NoError(os.MkdirAll(dir, 0755))
+	require.NoError(os.WriteFile(filepath.Join(dir, "x.json"), []byte(`[1]`), 0644))
+
+	data, err := suite.Client.LoadRaw("/manual/x.json")
+	require.NoError(err)
+	require.Equal([]byte(`[1]`), data)
+
+	detail, err := suite.Client.LoadDetail("/manual/x.json")
+	require.NoError(err)
+	require.False(detail.Expires())
+	require.Equal(3, detail.Size())
+	require.Less(time.Since(detail.Modified()), time.Second)
+
+}
+
+func (suite *FsClientTestSuite) TestLoadDetailFailsNotFound() {
+
+	require := suite.Require()
+	detail, err := suite.Client.LoadDetail("/nopers.json")
+	require.ErrorIs(err, fsclient.ErrNotFound, "not found")
+	require.Nil(detail, "detail returned")
+}
+
+func (suite *FsClientTestSuite) TestLoadDetailWithExpiryOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+
+	suite.SaveSet(1, "/detail/%d", &future)
+
+	detail, err := suite.Client.LoadDetail("/detail/0")
+	require.NoError(err, "load detail")
+
+	require.Equal("/detail/0", detail.Path(), "path")
+	require.Equal(7, detail.Size(), "size")
+	require.True(detail.Expires(), "expires")
+	require.WithinDuration(future, detail.Expiry(), time.Second, "expiry")
+	require.Less(time.Since(detail.Modified()), time.Second,
+		"modified not too old")
+
+}
+
+func (suite *FsClientTestSuite) TestLoadDetailWithoutExpiryOK() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/detail/%d", nil)
+
+	detail, err := suite.Client.LoadDetail("/detail/0")
+	require.NoError(err, "load detail")
+
+	require.Equal("/detail/0", detail.Path(), "path")
+	require.Equal(7, detail.Size(), "size")
+	require.False(detail.Expires(), "expires")
+	require.True(detail.Expiry().IsZero(), "expiry is zero time")
+
+}
+
+func (suite *FsClientTestSuite) TestCountingOK() {
+
+	require := suite.Require()
+
+	precount, err := suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(0, precount, "nothing there yet")
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(15, "/foo/%d", nil)
+	suite.SaveSet(5, "/foo/expired/%d", &past)
+	suite.SaveSet(5, "/foobar/%d", nil)
+
+	count, err := suite.Client.Count("/foo/")
+	require.NoError(err)
+	require.Equal(15, count, "prefix count")
+
+	count, err = suite.Client.Count("/foo")
+	require.NoError(err)
+	require.Equal(20, count, "partial element prefix count")
+
+	count, err = suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(20, count, "full count")
+
+}
+
+func (suite *FsClientTestSuite) TestListOK() {
+
+	require := suite.Require()
+
+	saved := suite.SaveSet(15, "/any/thing/%02d.json", nil)
+	suite.SaveSet(1, "/any/other/%02d.json", nil)
+
+	paths, err := suite.Client.List("/any/thing")
+	require.NoError(err)
+	require.EqualValues(saved.Paths, paths, "paths")
+
+}
+
+func (suite *FsClientTestSuite) TestListSortsLikeStringsOK() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/a/b%d", nil)
+	suite.SaveSet(1, "/a.x%d", nil)
+
+	paths, err := suite.Client.List("/")
+	require.NoError(err)
+	require.EqualValues([]string{"/a.x0", "/a/b0"}, paths, "paths")
+
+}
+
+func (suite *FsClientTestSuite) TestListEmptyOK() {
+
+	require := suite.Require()
+	paths, err := suite.Client.List("/no/such/stuff")
+	require.NoError(err)
+	require.EqualValues([]string{}, paths, "empty path list")
+
+	paths, err = suite.Client.List("/bad//prefix")
+	require.NoError(err)
+	require.EqualValues([]string{}, paths, "invalid prefix list")
+
+}
+
+func (suite *FsClientTestSuite) TestListDetailEmptyOK() {
+
+	require := suite.Require()
+	detailers, err := suite.Client.ListDetail("/")
+	require.NoError(err)
+	require.EqualValues([]backend.Detailer{}, detailers, "empty list")
+}
+
+func (suite *FsClientTestSuite) TestListDetailsOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(3, "/detail/exp/%d", &future)
+	suite.SaveSet(3, "/detail/noexp/%d", nil)
+	suite.SaveSet(3, "/detail/expired/%d", &past)
+
+	detailers, err := suite.Client.ListDetail("/detail/")
+	require.NoError(err)
+	require.Equal(6, len(detailers), "detailers returned")
+
+	// They will have come back in path order.
+	for i, d := range detailers {
+		if i < 3 {
+			require.Equal(fmt.Sprintf("/detail/exp/%d", i), d.Path(), "path %d", i)
+			require.True(d.Expires(), "expires %d", i)
+			require.WithinDuration(future, d.Expiry(), time.Second, "expiry %d", i)
+
+		} else {
+			require.Equal(fmt.Sprintf("/detail/noexp/%d", i-3), d.Path(), "path %d", i)
+			require.False(d.Expires(), "expires %d", i)
+			require.True(d.Expiry().IsZero(), "expiry is zero time %d", i)
+
+		}
+	}
+
+}
+
+func (suite *FsClientTestSuite) TestDeleteFailsNotFound() {
+
+	require := suite.Require()
+
+	err := suite.Client.Delete("/not/there")
+	require.ErrorIs(err, fsclient.ErrNotFound)
+}
+
+func (suite *FsClientTestSuite) TestDeleteFailsBelowObject() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/file/%d", nil)
+	err := suite.Client.Delete("/file/0/child")
+	require.ErrorIs(err, fsclient.ErrNotFound)
+}
+
+func (suite *FsClientTestSuite) TestDeleteOK() {
+
+	require := suite.Require()
+	suite.SaveSet(1, "/any/thing/%d.json", nil)
+	suite.SaveSet(1, "/any/%d.json", nil)
+
+	require.NoError(suite.Client.Delete("/any/thing/0.json"))
+
+	// Empty directories are removed, others are not.
+	require.NoDirExists(filepath.Join(suite.Client.Root, "any", "thing"))
+	require.DirExists(filepath.Join(suite.Client.Root, "any"))
+
+	require.NoError(suite.Client.Delete("/any/0.json"))
+	require.NoDirExists(filepath.Join(suite.Client.Root, "any"))
+	require.DirExists(suite.Client.Root)
+
+}
+
+func (suite *FsClientTestSuite) TestPurgeOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-1 * time.Hour)
+	keep_a := suite.SaveSet(5, "/keep/a/%02d.json", &future)
+	suite.SaveSet(5, "/purge/%02d.json", &past)
+	keep_b := suite.SaveSet(5, "/keep/b/%02d.json", nil)
+
+	purged, err := suite.Client.Purge()
+	require.NoError(err, "purge")
+	require.Equal(5, purged, "purge count")
+	require.NoDirExists(filepath.Join(suite.Client.Root, "purge"))
+
+	keep_paths := make([]string, 0, len(keep_a.Paths)+len(keep_b.Paths))
+	keep_paths = append(keep_paths, keep_a.Paths...)
+	keep_paths = append(keep_paths, keep_b.Paths...)
+
+	paths, err := suite.Client.List("/")
+	require.NoError(err)
+	require.EqualValues(keep_paths, paths, "path list")
+
+}
+
+func (suite *FsClientTestSuite) TestShutdownOK() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(5, "/purge/%02d.json", &past)
+
+	suite.Client.PurgeOnShutdown = false
+	require.NoError(suite.Client.Shutdown(), "shutdown without purge")
+	require.DirExists(filepath.Join(suite.Client.Root, "purge"))
+
+	suite.Client.PurgeOnShutdown = true
+	require.NoError(suite.Client.Shutdown(), "shutdown with purge")
+	require.NoDirExists(filepath.Join(suite.Client.Root, "purge"))
+
+}
+
+func (suite *FsClientTestSuite) TestImplementsContextBackendClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.ContextBackendClient)(nil), suite.Client)
+}
+
+func (suite *FsClientTestSuite) TestContextCanceledFails() {
+
+	require := suite.Require()
+
+	suite.SaveSet(3, "/ctx/%d", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(suite.Client.SaveRawCtx(ctx, "/ctx/x", []byte("{}")),
+		context.Canceled, "SaveRawCtx")
+	require.ErrorIs(suite.Client.SaveRawExpiryCtx(ctx, "/ctx/x", []byte("{}"),
+		time.Now()), context.Canceled, "SaveRawExpiryCtx")
+	_, err := suite.Client.LoadRawCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadRawCtx")
+	_, err = suite.Client.LoadDetailCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadDetailCtx")
+	require.ErrorIs(suite.Client.DeleteCtx(ctx, "/ctx/0"), context.Canceled,
+		"DeleteCtx")
+	_, err = suite.Client.ListCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListCtx")
+	_, err = suite.Client.ListDetailCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListDetailCtx")
+	_, err = suite.Client.CountCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "CountCtx")
+	_, err = suite.Client.CountAllCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "CountAllCtx")
+	_, err = suite.Client.PurgeCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "PurgeCtx")
+	require.ErrorIs(suite.Client.ShutdownCtx(ctx), context.Canceled,
+		"ShutdownCtx")
+
+	// Nothing happened.
+	paths, err := suite.Client.List("/ctx/")
+	require.NoError(err)
+	require.Equal([]string{"/ctx/0", "/ctx/1", "/ctx/2"}, paths)
+
+}
+
+func (suite *FsClientTestSuite) TestImplementsVersioner() {
+
+	require := suite.Require()
+	require.Implements((*backend.Versioner)(nil), suite.Client)
+}
+
+func (suite *FsClientTestSuite) TestVersionsOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+
+	_, _, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.ErrorIs(err, backend.ErrNotFound, "not yet saved")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "update missing")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 0)
+	require.NoError(err, "create")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 0)
+	require.ErrorIs(err, backend.ErrConflict, "create existing")
+
+	data, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+	require.EqualValues(1, version)
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 1)
+	require.NoError(err, "update")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "stale update")
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":4}`)), "plain save")
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(3, detail.Version(), "detail version")
+
+	details, err := suite.Client.ListDetail("/ver/")
+	require.NoError(err)
+	require.Len(details, 1)
+	require.EqualValues(3, details[0].Version(), "list detail version")
+
+	data, version, err = suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":4}`, string(data))
+	require.EqualValues(3, version)
+
+}
+
+func (suite *FsClientTestSuite) TestVersionsExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":1}`), past))
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":2}`), past))
+
+	err := suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 2)
+	require.ErrorIs(err, backend.ErrConflict, "update expired")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), &future, 0)
+	require.NoError(err, "replace expired")
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(1, detail.Version(), "starts over")
+	require.True(detail.Expires(), "expiry saved")
+	require.WithinDuration(future, detail.Expiry(), time.Second)
+
+}
+
+func (suite *FsClientTestSuite) TestSaveIfVersionConcurrentOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/conc"
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":0}`)))
+
+	// Everybody read version 1, so only one of them may win.
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf(`{"n":%d}`, i+1))
+			errs[i] = suite.Client.SaveRawIfVersionCtx(ctx, path, data, nil, 1)
+		}(i)
+	}
+	wg.Wait()
+
+	wins := 0
+	for _, err := range errs {
+		if err == nil {
+			wins++
+		} else {
+			require.ErrorIs(err, backend.ErrConflict)
+		}
+	}
+	require.Equal(1, wins, "one winner")
+
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.EqualValues(2, version)
+
+}
+
+func (suite *FsClientTestSuite) TestImplementsMetaClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.MetaClient)(nil), suite.Client)
+}
+
+func (suite *FsClientTestSuite) TestSaveRawMetaOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	meta := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	exp := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	meta["Owner"] = "changed" // not kept by reference
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Equal(exp, detail.Meta())
+	require.False(detail.Expires())
+
+	// Other saves keep it.
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, version)
+	require.NoError(err)
+	detailers, err := suite.Client.ListDetail("/meta/")
+	require.NoError(err)
+	require.Len(detailers, 1)
+	require.Equal(exp, detailers[0].Meta(), "kept")
+
+	// Saving with nil removes it.
+	err = suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":4}`), nil, nil)
+	require.NoError(err)
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta())
+
+}
+
+func (suite *FsClientTestSuite) TestSaveRawMetaExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	past := time.Now().Add(-time.Hour)
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), &past,
+		map[string]string{"k": "v"})
+	require.NoError(err)
+	_, err = suite.Client.LoadDetail(path)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta(), "not kept from expired object")
+
+}
+
+func (suite *FsClientTestSuite) TestImplementsExpirer() {
+
+	require := suite.Require()
+	require.Implements((*backend.Expirer)(nil), suite.Client)
+}
+
+func (suite *FsClientTestSuite) TestSetExpiryOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/expiry/x"
+	meta := map[string]string{"owner": "bob"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	before, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+
+	expiry := time.Now().Add(time.Hour).Round(time.Millisecond)
+	require.NoError(suite.Client.SetExpiryCtx(ctx, path, &expiry))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.True(expiry.Equal(detail.Expiry()), "expiry set")
+	require.Equal(before.Version(), detail.Version(), "version unchanged")
+	require.True(before.Modified().Equal(detail.Modified()), "modified unchanged")
+	require.Equal(meta, detail.Meta(), "meta unchanged")
+	data, err := suite.Client.LoadRaw(path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+
+	require.NoError(suite.Client.SetExpiryCtx(ctx, path, nil))
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.False(detail.Expires(), "expiry cleared")
+
+}
+
+func (suite *FsClientTestSuite) TestSetExpiryFailsNotFound() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+	err := suite.Client.SetExpiryCtx(ctx, "/expiry/none", &expiry)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	past := time.Now().Add(-time.Hour)
+	require.NoError(suite.Client.SaveRawExpiry("/expiry/old", []byte(`1`), past))
+	err = suite.Client.SetExpiryCtx(ctx, "/expiry/old", &expiry)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+}
@@ -0,0 +1,147 @@
+// history.go -- prior revisions of objects.
+
+package jsobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// historian returns the Backend as a backend.Historian, or ErrUnsupported.
+func (c *Client) historian() (backend.Historian, error) {
+	h, ok := c.Backend.(backend.Historian)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no history", ErrUnsupported, c.Backend)
+	}
+	return h, nil
+}
+
+// ListVersions returns Detailers for the kept revisions of the object at
+// path, oldest first, ending with the live object if there is one.  An empty
+// array is not considered an error.
+//
+// History must be enabled in the backend, e.g. with pgclient's
+// CreateHistory.  Backends not implementing backend.Historian return
+// ErrUnsupported.
+func (c *Client) ListVersions(path string) ([]backend.Detailer, error) {
+	return c.ListVersionsCtx(context.Background(), path)
+}
+
+// ListVersionsCtx is ListVersions with a context.
+func (c *Client) ListVersionsCtx(ctx context.Context, path string) ([]backend.Detailer, error) {
+	h, err := c.historian()
+	if err != nil {
+		return nil, err
+	}
+	return h.ListVersionsCtx(ctx, path)
+}
+
+// LoadVersion retrieves the object at path as it was at version, which may
+// be the live version, and unmarshals it to obj.  If that revision is not
+// kept the error is ErrNotFound.
+//
+// Versions start over at 1 when an object is deleted or expires and is then
+// saved again, so the same version may have been used more than once; the
+// most recent revision is loaded.
+func (c *Client) LoadVersion(path string, version int64, obj any) error {
+	return c.LoadVersionCtx(context.Background(), path, version, obj)
+}
+
+// LoadVersionCtx is LoadVersion with a context.
+func (c *Client) LoadVersionCtx(ctx context.Context, path string, version int64, obj any) error {
+
+	b, err := c.LoadRawVersionCtx(ctx, path, version)
+	if err != nil {
+		return err
+	}
+	return c.unmarshal(b, obj)
+
+}
+
+// LoadRawVersion is LoadVersion returning the raw value.
+func (c *Client) LoadRawVersion(path string, version int64) ([]byte, error) {
+	return c.LoadRawVersionCtx(context.Background(), path, version)
+}
+
+// LoadRawVersionCtx is LoadRawVersion with a context.
+func (c *Client) LoadRawVersionCtx(ctx context.Context, path string, version int64) ([]byte, error) {
+	h, err := c.historian()
+	if err != nil {
+		return nil, err
+	}
+	return h.LoadRawAtVersionCtx(ctx, path, version)
+}
+
+// LoadAsOf retrieves the object that was live at path at time t, unmarshals
+// it to obj and returns its version.  If there was none, or its revision is
+// not kept, the error is ErrNotFound.
+func (c *Client) LoadAsOf(path string, t time.Time, obj any) (int64, error) {
+	return c.LoadAsOfCtx(context.Background(), path, t, obj)
+}
+
+// LoadAsOfCtx is LoadAsOf with a context.
+func (c *Client) LoadAsOfCtx(ctx context.Context, path string, t time.Time, obj any) (int64, error) {
+
+	b, version, err := c.LoadRawAsOfCtx(ctx, path, t)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.unmarshal(b, obj); err != nil {
+		return 0, err
+	}
+	return version, nil
+
+}
+
+// LoadRawAsOf is LoadAsOf returning the raw value.
+func (c *Client) LoadRawAsOf(path string, t time.Time) ([]byte, int64, error) {
+	return c.LoadRawAsOfCtx(context.Background(), path, t)
+}
+
+// LoadRawAsOfCtx is LoadRawAsOf with a context.
+func (c *Client) LoadRawAsOfCtx(ctx context.Context, path string, t time.Time) ([]byte, int64, error) {
+	h, err := c.historian()
+	if err != nil {
+		return nil, 0, err
+	}
+	return h.LoadRawAsOfCtx(ctx, path, t)
+}
+
+// Restore saves the revision of the object at path at version as the live
+// object, with no expiry.  This is a new save, so the object gets a new
+// version and keeps its current metadata, and the replaced object is itself
+// kept in the history.
+func (c *Client) Restore(path string, version int64) error {
+	return c.RestoreCtx(context.Background(), path, version)
+}
+
+// RestoreCtx is Restore with a context.
+func (c *Client) RestoreCtx(ctx context.Context, path string, version int64) error {
+
+	b, err := c.LoadRawVersionCtx(ctx, path, version)
+	if err != nil {
+		return err
+	}
+	return c.SaveRawCtx(ctx, path, b)
+
+}
+
+// PruneHistory deletes all but the keep most recent revisions of every
+// object, and all revisions replaced more than max_age ago, returning the
+// number deleted.  Zero disables either limit.  History grows without bound
+// unless this is called now and then.
+func (c *Client) PruneHistory(keep int, max_age time.Duration) (int, error) {
+	return c.PruneHistoryCtx(context.Background(), keep, max_age)
+}
+
+// PruneHistoryCtx is PruneHistory with a context.
+func (c *Client) PruneHistoryCtx(ctx context.Context, keep int, max_age time.Duration) (int, error) {
+	h, err := c.historian()
+	if err != nil {
+		return 0, err
+	}
+	return h.PruneHistoryCtx(ctx, keep, max_age)
+}
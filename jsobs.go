@@ -4,28 +4,66 @@
 package jsobs
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/biztos/jsobs/backend"
 	"github.com/biztos/jsobs/pgclient"
+	"github.com/biztos/jsobs/purger"
 )
 
+// Errors returned, possibly wrapped, by all the bundled backends.  Use
+// errors.Is to check for them.  See the backend package for details.
+var (
+	ErrNotFound      = backend.ErrNotFound
+	ErrExpired       = backend.ErrExpired
+	ErrConflict      = backend.ErrConflict
+	ErrInvalidPath   = backend.ErrInvalidPath
+	ErrInvalidJson   = backend.ErrInvalidJson
+	ErrInvalidCursor = backend.ErrInvalidCursor
+	ErrUnsupported   = backend.ErrUnsupported
+	ErrPatchFailed   = backend.ErrPatchFailed
+)
+
+// Overwrite is the policy for Copy, Move and MovePrefix when there is
+// already a live object at the destination.  See the backend package for
+// details.
+type Overwrite = backend.Overwrite
+
+// Overwrite policies, as defined in the backend package.
+const (
+	OverwriteNever  = backend.OverwriteNever
+	OverwriteAlways = backend.OverwriteAlways
+	OverwriteSkip   = backend.OverwriteSkip
+)
+
+// IsNotFound returns true if err is or wraps ErrNotFound, which includes
+// ErrExpired.
 func IsNotFound(err error) bool {
-	if err == pgclient.ErrNotFound {
-		return true
-	}
-	// other known cases here...
-	return false
+	return errors.Is(err, ErrNotFound)
 }
 
 var ExitFunc = os.Exit
 
+// IteratePageSize is the number of objects read at a time by Iterate and
+// IterateDetail for backends that can not stream.
+var IteratePageSize = 1000
+
 // Client handles save, load, list and delete operations for its Backend.
+//
+// Objects are marshaled and unmarshaled by the Codec, or by a JsonCodec with
+// no options set if it is nil.
 type Client struct {
 	Backend backend.BackendClient
+	Codec   Codec
+
+	mutex  sync.Mutex // guards purger
+	purger *purger.Purger
 }
 
 // New returns a client with the provided backend.  Any error returned from
@@ -42,32 +80,62 @@ func NewPgClient() (*Client, error) {
 	return New(pgclient.New())
 }
 
+// ctxBackend returns the Backend as a ContextBackendClient, if it is one.
+func (c *Client) ctxBackend() (backend.ContextBackendClient, bool) {
+	cb, ok := c.Backend.(backend.ContextBackendClient)
+	return cb, ok
+}
+
 // Save marshals obj to json and stores it at path with no expiry.
 // Any existing object at path will be overwritten.
 func (c *Client) Save(path string, obj any) error {
+	return c.SaveCtx(context.Background(), path, obj)
+}
 
-	b, err := json.Marshal(obj)
+// SaveCtx is Save with a context.
+func (c *Client) SaveCtx(ctx context.Context, path string, obj any) error {
+
+	b, err := c.marshal(obj)
 	if err != nil {
-		return fmt.Errorf("Failed to marshal JSON: %w", err)
+		return err
 	}
-	return c.SaveRaw(path, b)
+	return c.SaveRawCtx(ctx, path, b)
 
 }
 
 // SaveExpiry marshals obj to json and stores it at path with expiry set.
 // Any existing object at path will be overwritten.
 func (c *Client) SaveExpiry(path string, obj any, expiry time.Time) error {
-	b, err := json.Marshal(obj)
+	return c.SaveExpiryCtx(context.Background(), path, obj, expiry)
+}
+
+// SaveExpiryCtx is SaveExpiry with a context.
+func (c *Client) SaveExpiryCtx(ctx context.Context, path string, obj any, expiry time.Time) error {
+	b, err := c.marshal(obj)
 	if err != nil {
-		return fmt.Errorf("Failed to marshal JSON: %w", err)
+		return err
 	}
-	return c.SaveRawExpiry(path, b, expiry)
+	return c.SaveRawExpiryCtx(ctx, path, b, expiry)
 }
 
 // SaveRaw behaves like Save but sends raw_obj directly.
 //
 // Use with caution!
 func (c *Client) SaveRaw(path string, raw_obj []byte) error {
+	return c.SaveRawCtx(context.Background(), path, raw_obj)
+}
+
+// SaveRawCtx is SaveRaw with a context.
+//
+// If the Backend does not take a context, ctx is only checked before the
+// call is made.  The same applies to all the other Ctx methods.
+func (c *Client) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.SaveRawCtx(ctx, path, raw_obj)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return c.Backend.SaveRaw(path, raw_obj)
 }
 
@@ -75,45 +143,103 @@ func (c *Client) SaveRaw(path string, raw_obj []byte) error {
 //
 // Use with caution!
 func (c *Client) SaveRawExpiry(path string, raw_obj []byte, expiry time.Time) error {
+	return c.SaveRawExpiryCtx(context.Background(), path, raw_obj, expiry)
+}
+
+// SaveRawExpiryCtx is SaveRawExpiry with a context.
+func (c *Client) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.SaveRawExpiryCtx(ctx, path, raw_obj, expiry)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return c.Backend.SaveRawExpiry(path, raw_obj, expiry)
 }
 
 // Load retrieves the object at path from storage and unmarshals it to obj.
 func (c *Client) Load(path string, obj any) error {
+	return c.LoadCtx(context.Background(), path, obj)
+}
 
-	b, err := c.LoadRaw(path)
+// LoadCtx is Load with a context.
+func (c *Client) LoadCtx(ctx context.Context, path string, obj any) error {
+
+	b, err := c.LoadRawCtx(ctx, path)
 	if err != nil {
 		return err
 	}
-	if err := json.Unmarshal(b, obj); err != nil {
-		return fmt.Errorf("Failed to marshal JSON: %w", err)
-	}
-	return nil
+	return c.unmarshal(b, obj)
 
 }
 
 // LoadRaw retrieves the object at path and returns its raw value.
 func (c *Client) LoadRaw(path string) ([]byte, error) {
+	return c.LoadRawCtx(context.Background(), path)
+}
+
+// LoadRawCtx is LoadRaw with a context.
+func (c *Client) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.LoadRawCtx(ctx, path)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return c.Backend.LoadRaw(path)
 }
 
 // LoadDetail retrieves the object at path and returns its details, but not
 // the actual data.
 func (c *Client) LoadDetail(path string) (backend.Detailer, error) {
+	return c.LoadDetailCtx(context.Background(), path)
+}
+
+// LoadDetailCtx is LoadDetail with a context.
+func (c *Client) LoadDetailCtx(ctx context.Context, path string) (backend.Detailer, error) {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.LoadDetailCtx(ctx, path)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return c.Backend.LoadDetail(path)
 }
 
 // Delete deletes the object at path.
 // If the object does not exist, the error returned will be ErrNotFound.
 func (c *Client) Delete(path string) error {
+	return c.DeleteCtx(context.Background(), path)
+}
+
+// DeleteCtx is Delete with a context.
+func (c *Client) DeleteCtx(ctx context.Context, path string) error {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.DeleteCtx(ctx, path)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return c.Backend.Delete(path)
 }
 
 // List returns an array of all objects beginning with prefix.  An empty array
 // is not considered an error.
 //
-// For S3, this operation may be slow if paged results are returned!
+// For S3, this operation may be slow if paged results are returned, and
+// every object must be inspected to check its expiry!
 func (c *Client) List(prefix string) ([]string, error) {
+	return c.ListCtx(context.Background(), prefix)
+}
+
+// ListCtx is List with a context.
+func (c *Client) ListCtx(ctx context.Context, prefix string) ([]string, error) {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.ListCtx(ctx, prefix)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return c.Backend.List(prefix)
 }
 
@@ -122,30 +248,558 @@ func (c *Client) List(prefix string) ([]string, error) {
 //
 // This operation may be slow for any backend returning paged results!
 func (c *Client) ListDetail(prefix string) ([]backend.Detailer, error) {
+	return c.ListDetailCtx(context.Background(), prefix)
+}
+
+// ListDetailCtx is ListDetail with a context.
+func (c *Client) ListDetailCtx(ctx context.Context, prefix string) ([]backend.Detailer, error) {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.ListDetailCtx(ctx, prefix)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return c.Backend.ListDetail(prefix)
 }
 
+// ListPage returns up to limit paths of objects beginning with prefix, in
+// path order, and a cursor for the next page.  Pass the empty cursor for
+// the first page; the returned cursor is empty when there are no more.
+//
+// Cursors are opaque and only good for the same prefix and Backend.  Objects
+// saved or deleted between pages may or may not be seen.
+//
+// If the Backend implements backend.Pager only one page is read at a time.
+// Otherwise the whole listing is read for every page!
+func (c *Client) ListPage(prefix string, cursor string, limit int) ([]string, string, error) {
+	return c.ListPageCtx(context.Background(), prefix, cursor, limit)
+}
+
+// ListPageCtx is ListPage with a context.
+func (c *Client) ListPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]string, string, error) {
+
+	if p, ok := c.Backend.(backend.Pager); ok {
+		return p.ListPageCtx(ctx, prefix, cursor, limit)
+	}
+	after, err := backend.CheckPage(cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	paths, err := c.ListCtx(ctx, prefix)
+	if err != nil {
+		return nil, "", err
+	}
+	paths, next := page(paths, after, limit, func(p string) string {
+		return p
+	})
+	return paths, next, nil
+
+}
+
+// page returns the page of limit items following the path after in the
+// sorted items, and the cursor for the next page, for backends that can
+// not page for themselves.
+func page[T any](items []T, after string, limit int, path func(T) string) ([]T, string) {
+	start := sort.Search(len(items), func(i int) bool {
+		return path(items[i]) > after
+	})
+	items = items[start:]
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	return items, backend.EncodeCursor(path(items[limit-1]))
+}
+
+// ListDetailPage is ListPage returning Detailers rather than paths.
+func (c *Client) ListDetailPage(prefix string, cursor string, limit int) ([]backend.Detailer, string, error) {
+	return c.ListDetailPageCtx(context.Background(), prefix, cursor, limit)
+}
+
+// ListDetailPageCtx is ListDetailPage with a context.
+func (c *Client) ListDetailPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]backend.Detailer, string, error) {
+
+	if p, ok := c.Backend.(backend.Pager); ok {
+		return p.ListDetailPageCtx(ctx, prefix, cursor, limit)
+	}
+	after, err := backend.CheckPage(cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	detailers, err := c.ListDetailCtx(ctx, prefix)
+	if err != nil {
+		return nil, "", err
+	}
+	detailers, next := page(detailers, after, limit, backend.Detailer.Path)
+	return detailers, next, nil
+
+}
+
+// ListDir lists the objects beginning with prefix as a directory, with paths
+// split on delimiter.  It returns the paths with no delimiter after the
+// prefix, and the "common prefixes" of the rest up to and including the
+// first delimiter after the prefix, each sorted.  Empty arrays are not
+// considered an error.
+//
+// For example, with objects "/a/b", "/a/c/d" and "/a/c/e", ListDir("/a/",
+// "/") returns the paths ["/a/b"] and the common prefixes ["/a/c/"].
+//
+// If the Backend implements backend.DirLister it does the rolling up.
+// Otherwise the whole listing is read and rolled up here.
+func (c *Client) ListDir(prefix string, delimiter string) ([]string, []string, error) {
+	return c.ListDirCtx(context.Background(), prefix, delimiter)
+}
+
+// ListDirCtx is ListDir with a context.
+func (c *Client) ListDirCtx(ctx context.Context, prefix string, delimiter string) ([]string, []string, error) {
+
+	if dl, ok := c.Backend.(backend.DirLister); ok {
+		return dl.ListDirCtx(ctx, prefix, delimiter)
+	}
+	if err := backend.CheckDelimiter(delimiter); err != nil {
+		return nil, nil, err
+	}
+	paths, err := c.ListCtx(ctx, prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	paths, prefixes := backend.SplitDir(prefix, delimiter, paths)
+	return paths, prefixes, nil
+
+}
+
+// Iterate calls fn with the Detailer and raw value of every object beginning
+// with prefix, in path order.  If fn returns an error the iteration stops
+// and that error is returned.  Objects saved or deleted during the iteration
+// may or may not be seen.
+//
+// If the Backend implements backend.Iterator the objects are streamed from
+// it.  Otherwise they are read IteratePageSize at a time, using ListDetailPage
+// and LoadMany.
+func (c *Client) Iterate(prefix string, fn func(backend.Detailer, []byte) error) error {
+	return c.IterateCtx(context.Background(), prefix, fn)
+}
+
+// IterateCtx is Iterate with a context.
+func (c *Client) IterateCtx(ctx context.Context, prefix string, fn func(backend.Detailer, []byte) error) error {
+	return c.iterate(ctx, prefix, true, fn)
+}
+
+// IterateDetail is Iterate without the raw values, which are not read.
+func (c *Client) IterateDetail(prefix string, fn func(backend.Detailer) error) error {
+	return c.IterateDetailCtx(context.Background(), prefix, fn)
+}
+
+// IterateDetailCtx is IterateDetail with a context.
+func (c *Client) IterateDetailCtx(ctx context.Context, prefix string, fn func(backend.Detailer) error) error {
+	return c.iterate(ctx, prefix, false, func(d backend.Detailer, _ []byte) error {
+		return fn(d)
+	})
+}
+
+func (c *Client) iterate(ctx context.Context, prefix string, with_data bool, fn func(backend.Detailer, []byte) error) error {
+
+	if it, ok := c.Backend.(backend.Iterator); ok {
+		return it.IterateCtx(ctx, prefix, with_data, fn)
+	}
+
+	// Without a Pager every page would read the whole listing, so in that
+	// case we read it once and page through it here.
+	var all []backend.Detailer
+	_, paged := c.Backend.(backend.Pager)
+	if !paged {
+		var err error
+		all, err = c.ListDetailCtx(ctx, prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	cursor := ""
+	for {
+		var detailers []backend.Detailer
+		if paged {
+			var err error
+			detailers, cursor, err = c.ListDetailPageCtx(ctx, prefix, cursor,
+				IteratePageSize)
+			if err != nil {
+				return err
+			}
+		} else {
+			if len(all) > IteratePageSize {
+				detailers, all = all[:IteratePageSize], all[IteratePageSize:]
+			} else {
+				detailers, all = all, nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		var raw_objs map[string][]byte
+		if with_data && len(detailers) > 0 {
+			paths := make([]string, len(detailers))
+			for i, d := range detailers {
+				paths[i] = d.Path()
+			}
+			var err error
+			raw_objs, err = c.LoadManyCtx(ctx, paths)
+			if err != nil {
+				return err
+			}
+		}
+		for _, d := range detailers {
+			var raw_obj []byte
+			if with_data {
+				var ok bool
+				if raw_obj, ok = raw_objs[d.Path()]; !ok {
+					continue // gone since listing
+				}
+			}
+			if err := fn(d, raw_obj); err != nil {
+				return err
+			}
+		}
+
+		if (paged && cursor == "") || (!paged && len(all) == 0) {
+			return nil
+		}
+	}
+
+}
+
 // Count returns the number of non-expired objects beginning with prefix.
 // If none are found, zero is returned.
 func (c *Client) Count(prefix string) (int, error) {
+	return c.CountCtx(context.Background(), prefix)
+}
+
+// CountCtx is Count with a context.
+func (c *Client) CountCtx(ctx context.Context, prefix string) (int, error) {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.CountCtx(ctx, prefix)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	return c.Backend.Count(prefix)
 }
 
 // CountAll returns the total number of non-expired objects.
 func (c *Client) CountAll() (int, error) {
+	return c.CountAllCtx(context.Background())
+}
 
+// CountAllCtx is CountAll with a context.
+func (c *Client) CountAllCtx(ctx context.Context) (int, error) {
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.CountAllCtx(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	return c.Backend.CountAll()
+}
 
+// SaveMany marshals each object in objs to json and stores it at its path
+// with no expiry, as with Save.  All objects are marshaled before anything
+// is saved.
+//
+// If the Backend implements backend.BatchClient the objects are saved at
+// once, and for the bundled backends atomically.  Otherwise they are saved
+// one at a time, stopping at the first error.
+func (c *Client) SaveMany(objs map[string]any) error {
+	return c.SaveManyCtx(context.Background(), objs)
+}
+
+// SaveManyCtx is SaveMany with a context.
+func (c *Client) SaveManyCtx(ctx context.Context, objs map[string]any) error {
+
+	raw_objs := make(map[string][]byte, len(objs))
+	for path, obj := range objs {
+		b, err := c.marshal(obj)
+		if err != nil {
+			return err
+		}
+		raw_objs[path] = b
+	}
+	return c.SaveRawManyCtx(ctx, raw_objs)
+
+}
+
+// SaveRawMany behaves like SaveMany but sends raw_objs directly.
+//
+// Use with caution!
+func (c *Client) SaveRawMany(raw_objs map[string][]byte) error {
+	return c.SaveRawManyCtx(context.Background(), raw_objs)
+}
+
+// SaveRawManyCtx is SaveRawMany with a context.
+func (c *Client) SaveRawManyCtx(ctx context.Context, raw_objs map[string][]byte) error {
+
+	if bc, ok := c.Backend.(backend.BatchClient); ok {
+		return bc.SaveRawManyCtx(ctx, raw_objs)
+	}
+
+	// Sorted, so a failure leaves a predictable state.
+	paths := make([]string, 0, len(raw_objs))
+	for path := range raw_objs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := c.SaveRawCtx(ctx, path, raw_objs[path]); err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
+
+// LoadMany retrieves the objects at paths and returns their raw values by
+// path.  Paths with no live object are left out of the map, so check its
+// length if you expect all of them.
+//
+// If the Backend implements backend.BatchClient this is a single call,
+// otherwise LoadRaw is called for each path.
+func (c *Client) LoadMany(paths []string) (map[string][]byte, error) {
+	return c.LoadManyCtx(context.Background(), paths)
+}
+
+// LoadManyCtx is LoadMany with a context.
+func (c *Client) LoadManyCtx(ctx context.Context, paths []string) (map[string][]byte, error) {
+
+	if bc, ok := c.Backend.(backend.BatchClient); ok {
+		return bc.LoadRawManyCtx(ctx, paths)
+	}
+
+	loaded := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		b, err := c.LoadRawCtx(ctx, path)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		loaded[path] = b
+	}
+	return loaded, nil
+
+}
+
+// DeleteMany deletes the objects at paths and returns the number deleted.
+// Unlike Delete, missing objects are not an error.
+//
+// If the Backend implements backend.BatchClient this is a single call,
+// otherwise Delete is called for each path.
+func (c *Client) DeleteMany(paths []string) (int, error) {
+	return c.DeleteManyCtx(context.Background(), paths)
+}
+
+// DeleteManyCtx is DeleteMany with a context.
+func (c *Client) DeleteManyCtx(ctx context.Context, paths []string) (int, error) {
+
+	if bc, ok := c.Backend.(backend.BatchClient); ok {
+		return bc.DeleteManyCtx(ctx, paths)
+	}
+
+	deleted := 0
+	for _, path := range paths {
+		err := c.DeleteCtx(ctx, path)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+
+}
+
+// DeletePrefix deletes every object whose path begins with prefix and
+// returns the number deleted.  The empty prefix, which would delete
+// everything, is an ErrInvalidPath.
+//
+// If the Backend implements backend.PrefixDeleter it deletes the objects
+// itself, including expired ones.  Otherwise the live objects are listed
+// and deleted as for DeleteMany.
+func (c *Client) DeletePrefix(prefix string) (int, error) {
+	return c.DeletePrefixCtx(context.Background(), prefix)
+}
+
+// DeletePrefixCtx is DeletePrefix with a context.
+func (c *Client) DeletePrefixCtx(ctx context.Context, prefix string) (int, error) {
+
+	if pd, ok := c.Backend.(backend.PrefixDeleter); ok {
+		return pd.DeletePrefixCtx(ctx, prefix)
+	}
+	if err := backend.CheckDeletePrefix(prefix); err != nil {
+		return 0, err
+	}
+	paths, err := c.ListCtx(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	return c.DeleteManyCtx(ctx, paths)
+
+}
+
+// versioner returns the Backend as a backend.Versioner, or ErrUnsupported.
+func (c *Client) versioner() (backend.Versioner, error) {
+	v, ok := c.Backend.(backend.Versioner)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no versions", ErrUnsupported, c.Backend)
+	}
+	return v, nil
+}
+
+// LoadWithVersion is Load, also returning the version of the object.
+// Versions start at 1 and increase with every save, so the version can be
+// passed to SaveIfVersion to update the object only if nobody else has.
+func (c *Client) LoadWithVersion(path string, obj any) (int64, error) {
+	return c.LoadWithVersionCtx(context.Background(), path, obj)
+}
+
+// LoadWithVersionCtx is LoadWithVersion with a context.
+func (c *Client) LoadWithVersionCtx(ctx context.Context, path string, obj any) (int64, error) {
+
+	b, version, err := c.LoadRawWithVersionCtx(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.unmarshal(b, obj); err != nil {
+		return 0, err
+	}
+	return version, nil
+
+}
+
+// LoadRawWithVersion is LoadRaw, also returning the version of the object.
+func (c *Client) LoadRawWithVersion(path string) ([]byte, int64, error) {
+	return c.LoadRawWithVersionCtx(context.Background(), path)
+}
+
+// LoadRawWithVersionCtx is LoadRawWithVersion with a context.
+func (c *Client) LoadRawWithVersionCtx(ctx context.Context, path string) ([]byte, int64, error) {
+	v, err := c.versioner()
+	if err != nil {
+		return nil, 0, err
+	}
+	return v.LoadRawVersionCtx(ctx, path)
+}
+
+// SaveIfVersion marshals obj to json and stores it at path with no expiry,
+// but only if the stored object is at version; the new version is then
+// version+1.  Otherwise ErrConflict is returned and nothing is saved.
+//
+// Backends not implementing backend.Versioner return ErrUnsupported.
+func (c *Client) SaveIfVersion(path string, obj any, version int64) error {
+	return c.SaveIfVersionCtx(context.Background(), path, obj, version)
+}
+
+// SaveIfVersionCtx is SaveIfVersion with a context.
+func (c *Client) SaveIfVersionCtx(ctx context.Context, path string, obj any, version int64) error {
+	b, err := c.marshal(obj)
+	if err != nil {
+		return err
+	}
+	return c.SaveRawIfVersionCtx(ctx, path, b, version)
+}
+
+// SaveIfNotExists marshals obj to json and stores it at path with no expiry,
+// at version 1, but only if there is no live object at path.  Otherwise
+// ErrConflict is returned and nothing is saved.
+func (c *Client) SaveIfNotExists(path string, obj any) error {
+	return c.SaveIfVersionCtx(context.Background(), path, obj, 0)
+}
+
+// SaveIfNotExistsCtx is SaveIfNotExists with a context.
+func (c *Client) SaveIfNotExistsCtx(ctx context.Context, path string, obj any) error {
+	return c.SaveIfVersionCtx(ctx, path, obj, 0)
+}
+
+// SaveRawIfVersion behaves like SaveIfVersion but sends raw_obj directly.
+// A version of zero behaves like SaveIfNotExists.
+//
+// Use with caution!
+func (c *Client) SaveRawIfVersion(path string, raw_obj []byte, version int64) error {
+	return c.SaveRawIfVersionCtx(context.Background(), path, raw_obj, version)
+}
+
+// SaveRawIfVersionCtx is SaveRawIfVersion with a context.
+func (c *Client) SaveRawIfVersionCtx(ctx context.Context, path string, raw_obj []byte, version int64) error {
+	v, err := c.versioner()
+	if err != nil {
+		return err
+	}
+	return v.SaveRawIfVersionCtx(ctx, path, raw_obj, nil, version)
+}
+
+// StartPurger starts purging expired objects in the background every
+// interval, for any Backend implementing backend.Purger.  If batch_size is
+// positive and the Backend is a backend.BatchPurger, at most batch_size
+// objects are deleted per statement.  If hook is not nil it is called after
+// each run with the number of objects deleted and any error.
+//
+// If the Backend can not purge, ErrUnsupported is returned.
+//
+// StartPurger, StopPurger and Close are safe to call concurrently, e.g. Close
+// from a signal handler.
+func (c *Client) StartPurger(interval time.Duration, batch_size int, hook func(int, error)) error {
+	bp, ok := c.Backend.(backend.Purger)
+	if !ok {
+		return fmt.Errorf("%w: %s can not purge", ErrUnsupported, c.Backend)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.purger != nil && c.purger.Running() {
+		return purger.ErrRunning
+	}
+	p := purger.New(bp, interval, batch_size)
+	p.OnPurge = hook
+	if err := p.Start(); err != nil {
+		return err
+	}
+	c.purger = p
+	return nil
+}
+
+// StopPurger stops the background purger, if running, waiting for any purge
+// in progress to complete.
+func (c *Client) StopPurger() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.purger != nil {
+		c.purger.Stop()
+	}
+}
+
+// Close stops any background purger and calls Backend.Shutdown, which
+// should perform any shutdown operations such as purging expired items and
+// closing connection pools.  Unlike Shutdown it does not exit the process,
+// so the Client may be closed by libraries, servers and tests.
+//
+// The Client should not be used after Close.
+func (c *Client) Close(ctx context.Context) error {
+	c.StopPurger()
+	if cb, ok := c.ctxBackend(); ok {
+		return cb.ShutdownCtx(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Backend.Shutdown()
 }
 
-// Shutdown calls Backend.Shutdown, which should perform any shutdown
-// operations such as purging expired items from the pool; and then calls
-// ExitFunc with the provided exit code.
+// Shutdown calls Close and then calls ExitFunc with the provided exit code.
 //
-// If Backend.Shutdown returns an error, 99 is used instead of code.
+// If Close returns an error, 99 is used instead of code.
 func (c *Client) Shutdown(code int) {
 	// TODO: logging...
-	err := c.Backend.Shutdown()
+	err := c.Close(context.Background())
 	if err != nil {
 		// TODO: log this bit for sure... and maybe package var for the 99.
 		code = 99
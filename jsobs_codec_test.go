@@ -0,0 +1,125 @@
+// jsobs_codec_test.go -- tests for marshaling with a Codec.
+
+package jsobs_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/biztos/jsobs"
+)
+
+// countingCodec is a JsonCodec that counts its calls, or fails if err is
+// set.
+type countingCodec struct {
+	jsobs.JsonCodec
+	marshals   int
+	unmarshals int
+	err        error
+}
+
+func (cc *countingCodec) Marshal(obj any) ([]byte, error) {
+	cc.marshals++
+	if cc.err != nil {
+		return nil, cc.err
+	}
+	return cc.JsonCodec.Marshal(obj)
+}
+
+func (cc *countingCodec) Unmarshal(data []byte, obj any) error {
+	cc.unmarshals++
+	if cc.err != nil {
+		return cc.err
+	}
+	return cc.JsonCodec.Unmarshal(data, obj)
+}
+
+func (suite *JsobsTestSuite) TestCodecUsedOK() {
+
+	require := suite.Require()
+
+	cc := &countingCodec{}
+	client := suite.memClient()
+	client.Codec = cc
+
+	require.NoError(client.Save("/a", map[string]int{"n": 1}))
+	require.NoError(client.SaveMany(map[string]any{"/b": 2, "/c": 3}))
+	require.NoError(client.MergePatch("/a", map[string]int{"m": 2}))
+	var obj map[string]int
+	require.NoError(client.Load("/a", &obj))
+	require.Equal(map[string]int{"n": 1, "m": 2}, obj)
+	_, err := client.LoadWithVersion("/b", new(int))
+	require.NoError(err)
+
+	require.Equal(4, cc.marshals, "marshals")
+	require.Equal(2, cc.unmarshals, "unmarshals")
+
+}
+
+func (suite *JsobsTestSuite) TestCodecErrors() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/a", 1))
+	client.Codec = &countingCodec{err: errors.New("oops")}
+
+	err := client.Save("/a", 1)
+	require.EqualError(err, "Failed to marshal JSON: oops")
+	err = client.Load("/a", new(int))
+	require.EqualError(err, "Failed to marshal JSON: oops")
+	err = client.SaveMany(map[string]any{"/b": 2})
+	require.EqualError(err, "Failed to marshal JSON: oops")
+
+}
+
+func (suite *JsobsTestSuite) TestJsonCodecUseNumberOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.SaveRaw("/n", []byte(`{"id":1152921504606846977}`)))
+
+	var obj map[string]any
+	require.NoError(client.Load("/n", &obj))
+	require.IsType(float64(0), obj["id"], "default")
+
+	client.Codec = jsobs.JsonCodec{UseNumber: true}
+	require.NoError(client.Load("/n", &obj))
+	require.Equal(json.Number("1152921504606846977"), obj["id"])
+
+}
+
+func (suite *JsobsTestSuite) TestJsonCodecDisallowUnknownFieldsFails() {
+
+	require := suite.Require()
+
+	type Thing struct {
+		Name string
+	}
+	client := suite.memClient()
+	require.NoError(client.SaveRaw("/t", []byte(`{"Name":"x","Extra":1}`)))
+
+	var thing Thing
+	require.NoError(client.Load("/t", &thing), "default")
+	require.Equal("x", thing.Name)
+
+	client.Codec = jsobs.JsonCodec{DisallowUnknownFields: true}
+	err := client.Load("/t", &thing)
+	require.ErrorContains(err, "Failed to marshal JSON: ")
+	require.ErrorContains(err, `unknown field "Extra"`)
+
+}
+
+func (suite *JsobsTestSuite) TestJsonCodecTrailingDataFails() {
+
+	require := suite.Require()
+
+	for _, jc := range []jsobs.JsonCodec{{}, {UseNumber: true}} {
+		var n any
+		err := jc.Unmarshal([]byte(`1 2`), &n)
+		require.Error(err)
+		require.NoError(jc.Unmarshal([]byte(" 1\n"), &n))
+	}
+
+}
@@ -0,0 +1,38 @@
+// jsobs_compress_test.go -- tests for clients with a compressing backend.
+
+package jsobs_test
+
+import (
+	"strings"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/compressclient"
+	"github.com/biztos/jsobs/memclient"
+)
+
+func (suite *JsobsTestSuite) TestCompressClientOK() {
+
+	require := suite.Require()
+
+	mc := memclient.New()
+	mc.Binary = true
+	client, err := jsobs.New(compressclient.New(mc))
+	require.NoError(err)
+
+	obj := map[string]string{"text": strings.Repeat("same old ", 200)}
+	require.NoError(client.SaveWithMeta("/a", obj, map[string]string{"k": "v"}))
+	require.NoError(client.Copy("/a", "/b", jsobs.OverwriteNever))
+
+	for _, path := range []string{"/a", "/b"} {
+		var got map[string]string
+		require.NoError(client.Load(path, &got))
+		require.Equal(obj, got)
+		detail, err := client.LoadDetail(path)
+		require.NoError(err)
+		require.Equal(map[string]string{"k": "v", "content-encoding": "gzip"},
+			detail.Meta())
+	}
+	_, err = client.ListVersions("/a")
+	require.ErrorIs(err, jsobs.ErrUnsupported)
+
+}
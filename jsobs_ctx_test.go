@@ -0,0 +1,103 @@
+// jsobs_ctx_test.go -- tests for the context-taking methods.
+
+package jsobs_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/biztos/jsobs"
+)
+
+type ctxKey string
+
+func (suite *JsobsTestSuite) TestCtxMethodsFailCanceledWithoutCalls() {
+
+	require := suite.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := suite.Client
+	require.ErrorIs(c.SaveCtx(ctx, "/any", 1), context.Canceled)
+	require.ErrorIs(c.SaveExpiryCtx(ctx, "/any", 1, time.Now()), context.Canceled)
+	require.ErrorIs(c.SaveRawCtx(ctx, "/any", []byte("1")), context.Canceled)
+	require.ErrorIs(c.SaveRawExpiryCtx(ctx, "/any", []byte("1"), time.Now()), context.Canceled)
+	require.ErrorIs(c.LoadCtx(ctx, "/any", new(int)), context.Canceled)
+	_, err := c.LoadRawCtx(ctx, "/any")
+	require.ErrorIs(err, context.Canceled)
+	_, err = c.LoadDetailCtx(ctx, "/any")
+	require.ErrorIs(err, context.Canceled)
+	require.ErrorIs(c.DeleteCtx(ctx, "/any"), context.Canceled)
+	_, err = c.ListCtx(ctx, "/any")
+	require.ErrorIs(err, context.Canceled)
+	_, err = c.ListDetailCtx(ctx, "/any")
+	require.ErrorIs(err, context.Canceled)
+	_, err = c.CountCtx(ctx, "/any")
+	require.ErrorIs(err, context.Canceled)
+	_, err = c.CountAllCtx(ctx)
+	require.ErrorIs(err, context.Canceled)
+
+	require.Nil(suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestCtxMethodsUseCtxBackendOK() {
+
+	require := suite.Require()
+
+	b := &TestCtxBackend{TestBackend: suite.Backend}
+	c := &jsobs.Client{Backend: b}
+	ctx := context.WithValue(context.Background(), ctxKey("k"), "v")
+
+	b.nextData = []byte(`{"Foo":"x"}`)
+	b.nextPaths = []string{"/a"}
+	b.nextCount = 3
+
+	require.NoError(c.SaveCtx(ctx, "/any", 1))
+	require.NoError(c.SaveExpiryCtx(ctx, "/any", 1, time.Now()))
+	obj := struct{ Foo string }{}
+	require.NoError(c.LoadCtx(ctx, "/any", &obj))
+	require.Equal("x", obj.Foo)
+	_, err := c.LoadDetailCtx(ctx, "/any")
+	require.NoError(err)
+	require.NoError(c.DeleteCtx(ctx, "/any"))
+	paths, err := c.ListCtx(ctx, "/")
+	require.NoError(err)
+	require.Equal([]string{"/a"}, paths)
+	_, err = c.ListDetailCtx(ctx, "/")
+	require.NoError(err)
+	count, err := c.CountCtx(ctx, "/")
+	require.NoError(err)
+	require.Equal(3, count)
+	count, err = c.CountAllCtx(ctx)
+	require.NoError(err)
+	require.Equal(3, count)
+
+	require.EqualValues([]string{
+		"SaveRawCtx",
+		"SaveRawExpiryCtx",
+		"LoadRawCtx",
+		"LoadDetailCtx",
+		"DeleteCtx",
+		"ListCtx",
+		"ListDetailCtx",
+		"CountCtx",
+		"CountAllCtx",
+	}, b.allCalls, "calls")
+	require.Equal("v", b.lastCtx.Value(ctxKey("k")), "context passed")
+
+}
+
+func (suite *JsobsTestSuite) TestPlainMethodsUseCtxBackendOK() {
+
+	require := suite.Require()
+
+	b := &TestCtxBackend{TestBackend: suite.Backend}
+	c := &jsobs.Client{Backend: b}
+
+	require.NoError(c.SaveRaw("/any", []byte("1")))
+	require.Equal("SaveRawCtx", b.lastCall)
+	require.NotNil(b.lastCtx, "background context")
+
+}
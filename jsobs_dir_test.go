@@ -0,0 +1,57 @@
+// jsobs_dir_test.go -- tests for directory-style listings.
+
+package jsobs_test
+
+import (
+	"errors"
+)
+
+func (suite *JsobsTestSuite) TestListDirFallbackOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	for _, path := range []string{"/a/b", "/a/c/d", "/a/c/e", "/a/f/g/h",
+		"/a/i", "/ab/j", "/k"} {
+		require.NoError(client.Save(path, 1))
+	}
+
+	paths, prefixes, err := client.ListDir("/a/", "/")
+	require.NoError(err)
+	require.Equal([]string{"/a/b", "/a/i"}, paths)
+	require.Equal([]string{"/a/c/", "/a/f/"}, prefixes)
+
+	paths, prefixes, err = client.ListDir("/", "/")
+	require.NoError(err)
+	require.Equal([]string{"/k"}, paths)
+	require.Equal([]string{"/a/", "/ab/"}, prefixes)
+
+	paths, prefixes, err = client.ListDir("/a/", "::")
+	require.NoError(err)
+	require.Equal([]string{"/a/b", "/a/c/d", "/a/c/e", "/a/f/g/h", "/a/i"},
+		paths, "nothing to split")
+	require.Empty(prefixes)
+
+}
+
+func (suite *JsobsTestSuite) TestListDirFailsEmptyDelimiter() {
+
+	require := suite.Require()
+
+	_, _, err := suite.Client.ListDir("/a/", "")
+	require.EqualError(err, "delimiter must not be empty")
+	require.Nil(suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestListDirListError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+	_, _, err := suite.Client.ListDir("/a/", "/")
+	require.EqualError(err, "oops")
+	require.Equal("List", suite.Backend.lastCall)
+	require.Equal("/a/", suite.Backend.lastPrefix)
+
+}
@@ -0,0 +1,85 @@
+// jsobs_expiry_test.go -- tests for changing the expiry of stored objects.
+
+package jsobs_test
+
+import (
+	"time"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/memclient"
+)
+
+// versionerBackend is a MemClient with only the BackendClient and Versioner
+// methods, so that it is not an Expirer.
+type versionerBackend struct {
+	backend.BackendClient
+	backend.Versioner
+}
+
+func newVersionerBackend() *versionerBackend {
+	mc := memclient.New()
+	return &versionerBackend{BackendClient: mc, Versioner: mc}
+}
+
+func (suite *JsobsTestSuite) TestSetExpiryOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/e", 1))
+	expiry := time.Now().Add(time.Hour)
+	require.NoError(client.SetExpiry("/e", expiry))
+
+	detail, err := client.LoadDetail("/e")
+	require.NoError(err)
+	require.True(expiry.Equal(detail.Expiry()))
+	require.EqualValues(1, detail.Version(), "not saved again")
+
+	require.NoError(client.ClearExpiry("/e"))
+	detail, err = client.LoadDetail("/e")
+	require.NoError(err)
+	require.False(detail.Expires())
+
+	require.NoError(client.Touch("/e", -time.Second))
+	require.ErrorIs(client.Load("/e", new(int)), jsobs.ErrNotFound, "expired")
+	require.ErrorIs(client.Touch("/e", time.Hour), jsobs.ErrNotFound,
+		"no resurrection")
+
+}
+
+func (suite *JsobsTestSuite) TestSetExpiryVersionerOK() {
+
+	require := suite.Require()
+
+	client := &jsobs.Client{Backend: newVersionerBackend()}
+	require.NoError(client.Save("/e", 1))
+	require.NoError(client.Touch("/e", time.Hour))
+
+	detail, err := client.LoadDetail("/e")
+	require.NoError(err)
+	require.True(detail.Expires())
+	require.EqualValues(2, detail.Version(), "saved again")
+	var n int
+	require.NoError(client.Load("/e", &n))
+	require.Equal(1, n)
+
+	require.NoError(client.ClearExpiry("/e"))
+	detail, err = client.LoadDetail("/e")
+	require.NoError(err)
+	require.False(detail.Expires())
+
+	require.ErrorIs(client.ClearExpiry("/none"), jsobs.ErrNotFound)
+
+}
+
+func (suite *JsobsTestSuite) TestSetExpiryFailsUnsupported() {
+
+	require := suite.Require()
+
+	err := suite.Client.SetExpiry("/e", time.Now())
+	require.ErrorIs(err, jsobs.ErrUnsupported)
+	require.ErrorIs(suite.Client.ClearExpiry("/e"), jsobs.ErrUnsupported)
+	require.ErrorIs(suite.Client.Touch("/e", time.Hour), jsobs.ErrUnsupported)
+
+}
@@ -0,0 +1,115 @@
+// jsobs_history_test.go -- tests for prior revisions of objects.
+
+package jsobs_test
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/sqliteclient"
+)
+
+func (suite *JsobsTestSuite) historyClient() *jsobs.Client {
+
+	require := suite.Require()
+
+	sc, err := sqliteclient.NewForFile(filepath.Join(suite.T().TempDir(), "db"))
+	require.NoError(err)
+	require.NoError(sc.CreateTable())
+	require.NoError(sc.CreateHistory())
+	client := &jsobs.Client{Backend: sc}
+	suite.T().Cleanup(func() { client.Close(context.Background()) })
+	return client
+
+}
+
+func (suite *JsobsTestSuite) TestHistoryMethodsFailUnsupported() {
+
+	require := suite.Require()
+
+	c := suite.Client
+	_, err := c.ListVersions("/any")
+	require.ErrorIs(err, jsobs.ErrUnsupported, "ListVersions")
+	require.ErrorIs(c.LoadVersion("/any", 1, new(int)),
+		jsobs.ErrUnsupported, "LoadVersion")
+	_, err = c.LoadRawVersion("/any", 1)
+	require.ErrorIs(err, jsobs.ErrUnsupported, "LoadRawVersion")
+	_, err = c.LoadAsOf("/any", time.Now(), new(int))
+	require.ErrorIs(err, jsobs.ErrUnsupported, "LoadAsOf")
+	require.ErrorIs(c.Restore("/any", 1), jsobs.ErrUnsupported, "Restore")
+	_, err = c.PruneHistory(1, 0)
+	require.ErrorIs(err, jsobs.ErrUnsupported, "PruneHistory")
+
+}
+
+func (suite *JsobsTestSuite) TestHistoryOK() {
+
+	require := suite.Require()
+
+	client := suite.historyClient()
+	require.NoError(client.Save("/h", "one"))
+	time.Sleep(time.Millisecond)
+	between := time.Now()
+	time.Sleep(time.Millisecond)
+	require.NoError(client.Save("/h", "two"))
+	require.NoError(client.Save("/h", "three"))
+
+	detailers, err := client.ListVersions("/h")
+	require.NoError(err)
+	require.Len(detailers, 3)
+	for i, d := range detailers {
+		require.Equal(int64(i+1), d.Version())
+	}
+
+	var s string
+	require.NoError(client.LoadVersion("/h", 1, &s))
+	require.Equal("one", s)
+	require.NoError(client.LoadVersion("/h", 3, &s))
+	require.Equal("three", s)
+	require.ErrorIs(client.LoadVersion("/h", 4, &s), jsobs.ErrNotFound)
+
+	version, err := client.LoadAsOf("/h", between, &s)
+	require.NoError(err)
+	require.Equal(int64(1), version)
+	require.Equal("one", s)
+	_, err = client.LoadAsOf("/h", between.Add(-time.Hour), &s)
+	require.ErrorIs(err, jsobs.ErrNotFound)
+
+	require.NoError(client.Restore("/h", 1))
+	version, err = client.LoadWithVersion("/h", &s)
+	require.NoError(err)
+	require.Equal(int64(4), version)
+	require.Equal("one", s)
+
+	pruned, err := client.PruneHistory(1, 0)
+	require.NoError(err)
+	require.Equal(2, pruned)
+	detailers, err = client.ListVersions("/h")
+	require.NoError(err)
+	require.Len(detailers, 2)
+
+}
+
+func (suite *JsobsTestSuite) TestRestoreFailsNotFound() {
+
+	require := suite.Require()
+
+	client := suite.historyClient()
+	require.ErrorIs(client.Restore("/nope", 1), jsobs.ErrNotFound)
+
+}
+
+func (suite *JsobsTestSuite) TestLoadVersionJsonError() {
+
+	require := suite.Require()
+
+	client := suite.historyClient()
+	require.NoError(client.Save("/h", "one"))
+	err := client.LoadVersion("/h", 1, new(int))
+	require.ErrorContains(err, "Failed to marshal JSON")
+	_, err = client.LoadAsOf("/h", time.Now(), new(int))
+	require.ErrorContains(err, "Failed to marshal JSON")
+
+}
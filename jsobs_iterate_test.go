@@ -0,0 +1,169 @@
+// jsobs_iterate_test.go -- tests for iteration.
+
+package jsobs_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/sqliteclient"
+)
+
+// iterated collects what Iterate gives it.
+type iterated struct {
+	paths []string
+	data  []string
+}
+
+func (it *iterated) fn(d backend.Detailer, raw_obj []byte) error {
+	it.paths = append(it.paths, d.Path())
+	it.data = append(it.data, string(raw_obj))
+	return nil
+}
+
+func (suite *JsobsTestSuite) TestIterateFallbackOK() {
+
+	require := suite.Require()
+
+	defer func(size int) { jsobs.IteratePageSize = size }(jsobs.IteratePageSize)
+	jsobs.IteratePageSize = 3
+
+	client := suite.pagedClient(7)
+
+	it := &iterated{}
+	require.NoError(client.Iterate("/p/", it.fn))
+	require.Equal([]string{"/p/00", "/p/01", "/p/02", "/p/03", "/p/04",
+		"/p/05", "/p/06"}, it.paths)
+	require.Equal([]string{"0", "1", "2", "3", "4", "5", "6"}, it.data)
+
+	paths := []string{}
+	require.NoError(client.IterateDetail("/p/", func(d backend.Detailer) error {
+		paths = append(paths, d.Path())
+		return nil
+	}))
+	require.Equal(it.paths, paths)
+
+}
+
+func (suite *JsobsTestSuite) TestIteratePagerOK() {
+
+	require := suite.Require()
+
+	defer func(size int) { jsobs.IteratePageSize = size }(jsobs.IteratePageSize)
+	jsobs.IteratePageSize = 2
+
+	sc, err := sqliteclient.NewForFile(filepath.Join(suite.T().TempDir(), "db"))
+	require.NoError(err)
+	require.NoError(sc.CreateTable())
+	client := &jsobs.Client{Backend: sc}
+	defer client.Close(context.Background())
+	require.NoError(client.Save("/p/a", 1))
+	require.NoError(client.Save("/p/b", 2))
+	require.NoError(client.Save("/p/c", 3))
+	require.NoError(client.Save("/q", 4))
+
+	it := &iterated{}
+	require.NoError(client.Iterate("/p/", it.fn))
+	require.Equal([]string{"/p/a", "/p/b", "/p/c"}, it.paths)
+	require.Equal([]string{"1", "2", "3"}, it.data)
+
+}
+
+func (suite *JsobsTestSuite) TestIterateSkipsDeletedOK() {
+
+	require := suite.Require()
+
+	defer func(size int) { jsobs.IteratePageSize = size }(jsobs.IteratePageSize)
+	jsobs.IteratePageSize = 2
+
+	client := suite.pagedClient(4)
+
+	// Deleting ahead of the iteration, but after the listing.
+	it := &iterated{}
+	require.NoError(client.Iterate("/p/", func(d backend.Detailer, raw_obj []byte) error {
+		if d.Path() == "/p/01" {
+			require.NoError(client.Delete("/p/02"))
+		}
+		return it.fn(d, raw_obj)
+	}))
+	require.Equal([]string{"/p/00", "/p/01", "/p/03"}, it.paths)
+
+}
+
+func (suite *JsobsTestSuite) TestIterateEmptyOK() {
+
+	require := suite.Require()
+
+	called := false
+	err := suite.memClient().Iterate("/p/", func(backend.Detailer, []byte) error {
+		called = true
+		return nil
+	})
+	require.NoError(err)
+	require.False(called)
+
+}
+
+func (suite *JsobsTestSuite) TestIterateStopsOnError() {
+
+	require := suite.Require()
+
+	client := suite.pagedClient(4)
+
+	exp_err := errors.New("enough")
+	count := 0
+	err := client.Iterate("/p/", func(backend.Detailer, []byte) error {
+		count++
+		if count == 2 {
+			return exp_err
+		}
+		return nil
+	})
+	require.ErrorIs(err, exp_err)
+	require.Equal(2, count)
+
+	count = 0
+	err = client.IterateDetail("/p/", func(backend.Detailer) error {
+		count++
+		return exp_err
+	})
+	require.ErrorIs(err, exp_err)
+	require.Equal(1, count)
+
+}
+
+func (suite *JsobsTestSuite) TestIterateListError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+	err := suite.Client.Iterate("/p/", func(backend.Detailer, []byte) error {
+		return nil
+	})
+	require.EqualError(err, "oops")
+	require.Equal("ListDetail", suite.Backend.lastCall)
+	require.Equal("/p/", suite.Backend.lastPrefix)
+
+}
+
+func (suite *JsobsTestSuite) TestIterateCtxFailsCanceled() {
+
+	require := suite.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := suite.pagedClient(4)
+	err := client.IterateCtx(ctx, "/p/", func(backend.Detailer, []byte) error {
+		return nil
+	})
+	require.ErrorIs(err, context.Canceled, "IterateCtx")
+	err = client.IterateDetailCtx(ctx, "/p/", func(backend.Detailer) error {
+		return nil
+	})
+	require.ErrorIs(err, context.Canceled, "IterateDetailCtx")
+
+}
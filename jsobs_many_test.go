@@ -0,0 +1,186 @@
+// jsobs_many_test.go -- tests for the batch methods.
+
+package jsobs_test
+
+import (
+	"errors"
+
+	"github.com/biztos/jsobs"
+)
+
+func (suite *JsobsTestSuite) TestSaveManyJsonError() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveMany(map[string]any{
+		"/good": 1,
+		"/bad":  func() {},
+	})
+	require.ErrorContains(err, "Failed to marshal JSON")
+	require.Nil(suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestSaveManyFallbackOK() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveMany(map[string]any{
+		"/b": 2,
+		"/a": 1,
+		"/c": 3,
+	})
+	require.NoError(err)
+	require.EqualValues([]string{"SaveRaw", "SaveRaw", "SaveRaw"},
+		suite.Backend.allCalls, "calls")
+	require.Equal("/c", suite.Backend.lastPath, "saved in order")
+	require.Equal("3", string(suite.Backend.lastData), "data")
+
+}
+
+func (suite *JsobsTestSuite) TestSaveManyFallbackError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+
+	err := suite.Client.SaveMany(map[string]any{"/b": 2, "/a": 1})
+	require.EqualError(err, "oops")
+	require.EqualValues([]string{"SaveRaw"}, suite.Backend.allCalls, "calls")
+	require.Equal("/a", suite.Backend.lastPath, "stopped at first")
+
+}
+
+func (suite *JsobsTestSuite) TestLoadManyFallbackOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.SaveMany(map[string]any{"/a": 1, "/b": 2}))
+
+	loaded, err := client.LoadMany([]string{"/a", "/b", "/none"})
+	require.NoError(err)
+	require.Equal(map[string][]byte{
+		"/a": []byte("1"),
+		"/b": []byte("2"),
+	}, loaded)
+
+}
+
+func (suite *JsobsTestSuite) TestLoadManyFallbackError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+
+	_, err := suite.Client.LoadMany([]string{"/a", "/b"})
+	require.EqualError(err, "oops")
+	require.EqualValues([]string{"LoadRaw"}, suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestDeleteManyFallbackOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.SaveMany(map[string]any{"/a": 1, "/b": 2, "/c": 3}))
+
+	deleted, err := client.DeleteMany([]string{"/a", "/b", "/none"})
+	require.NoError(err)
+	require.Equal(2, deleted)
+
+	paths, err := client.List("/")
+	require.NoError(err)
+	require.Equal([]string{"/c"}, paths)
+
+}
+
+func (suite *JsobsTestSuite) TestDeleteManyFallbackError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+
+	deleted, err := suite.Client.DeleteMany([]string{"/a", "/b"})
+	require.EqualError(err, "oops")
+	require.Equal(0, deleted)
+	require.EqualValues([]string{"Delete"}, suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestManyMethodsUseBatchBackendOK() {
+
+	require := suite.Require()
+
+	b := &TestBatchBackend{TestBackend: suite.Backend}
+	c := &jsobs.Client{Backend: b}
+
+	require.NoError(c.SaveMany(map[string]any{"/a": 1, "/b": "x"}))
+	require.Equal(map[string][]byte{
+		"/a": []byte("1"),
+		"/b": []byte(`"x"`),
+	}, b.lastObjs)
+
+	b.nextMany = map[string][]byte{"/a": []byte("1")}
+	loaded, err := c.LoadMany([]string{"/a", "/b"})
+	require.NoError(err)
+	require.Equal(b.nextMany, loaded)
+	require.Equal([]string{"/a", "/b"}, b.lastPaths)
+
+	b.nextCount = 2
+	deleted, err := c.DeleteMany([]string{"/a", "/c"})
+	require.NoError(err)
+	require.Equal(2, deleted)
+	require.Equal([]string{"/a", "/c"}, b.lastPaths)
+
+	require.EqualValues([]string{
+		"SaveRawManyCtx",
+		"LoadRawManyCtx",
+		"DeleteManyCtx",
+	}, b.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestDeletePrefixOK() {
+
+	require := suite.Require()
+
+	for _, client := range []*jsobs.Client{suite.memClient(), suite.fsClient()} {
+		require.NoError(client.SaveMany(map[string]any{
+			"/a/1": 1, "/a/2": 2, "/b/1": 3,
+		}))
+
+		deleted, err := client.DeletePrefix("/a/")
+		require.NoError(err)
+		require.Equal(2, deleted, "%s", client.Backend)
+
+		paths, err := client.List("/")
+		require.NoError(err)
+		require.Equal([]string{"/b/1"}, paths)
+	}
+
+}
+
+func (suite *JsobsTestSuite) TestDeletePrefixFailsEmpty() {
+
+	require := suite.Require()
+
+	_, err := suite.Client.DeletePrefix("")
+	require.ErrorIs(err, jsobs.ErrInvalidPath)
+	require.Empty(suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestDeletePrefixFallbackError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+
+	deleted, err := suite.Client.DeletePrefix("/a/")
+	require.EqualError(err, "oops")
+	require.Equal(0, deleted)
+	require.EqualValues([]string{"List"}, suite.Backend.allCalls, "calls")
+
+}
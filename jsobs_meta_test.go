@@ -0,0 +1,136 @@
+// jsobs_meta_test.go -- tests for user-defined metadata.
+
+package jsobs_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/backend"
+)
+
+func (suite *JsobsTestSuite) TestSaveWithMetaOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	meta := map[string]string{"source": "import", "owner": "bob"}
+	require.NoError(client.SaveWithMeta("/m", 1, meta))
+
+	detail, err := client.LoadDetail("/m")
+	require.NoError(err)
+	require.Equal(meta, detail.Meta())
+	require.False(detail.Expires())
+
+	// Patches keep it.
+	require.NoError(client.SaveWithMeta("/p", map[string]int{"n": 1}, meta))
+	require.NoError(client.MergePatch("/p", map[string]int{"n": 2}))
+	detail, err = client.LoadDetail("/p")
+	require.NoError(err)
+	require.Equal(meta, detail.Meta())
+
+}
+
+func (suite *JsobsTestSuite) TestSaveExpiryWithMetaOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	expiry := time.Now().Add(time.Hour)
+	meta := map[string]string{"owner": "bob"}
+	require.NoError(client.SaveExpiryWithMeta("/m", 1, expiry, meta))
+
+	detail, err := client.LoadDetail("/m")
+	require.NoError(err)
+	require.Equal(meta, detail.Meta())
+	require.True(expiry.Equal(detail.Expiry()))
+
+}
+
+func (suite *JsobsTestSuite) TestSaveWithMetaFailsUnsupported() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveWithMeta("/m", 1, nil)
+	require.ErrorIs(err, jsobs.ErrUnsupported)
+	err = suite.Client.SaveExpiryWithMeta("/m", 1, time.Now(), nil)
+	require.ErrorIs(err, jsobs.ErrUnsupported)
+	require.Equal([]string{"String", "String"}, suite.Backend.allCalls)
+
+}
+
+func (suite *JsobsTestSuite) TestSaveWithMetaJsonError() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	err := client.SaveWithMeta("/m", func() {}, nil)
+	require.ErrorContains(err, "Failed to marshal JSON")
+
+}
+
+func (suite *JsobsTestSuite) TestListDetailMetaFallbackOK() {
+
+	require := suite.Require()
+
+	suite.Backend.nextDetailers = []backend.Detailer{
+		&TestDetailer{path: "/a", meta: map[string]string{"k": "v", "x": "y"}},
+		&TestDetailer{path: "/b"},
+		&TestDetailer{path: "/c", meta: map[string]string{"k": "v"}},
+		&TestDetailer{path: "/d", meta: map[string]string{"k": "w"}},
+	}
+
+	detailers, err := suite.Client.ListDetailMeta("/", map[string]string{"k": "v"})
+	require.NoError(err)
+	require.Len(detailers, 2)
+	require.Equal("/a", detailers[0].Path())
+	require.Equal("/c", detailers[1].Path())
+	require.Equal("/", suite.Backend.lastPrefix)
+
+	count, err := suite.Client.CountMeta("/", map[string]string{"x": "y"})
+	require.NoError(err)
+	require.Equal(1, count)
+
+	count, err = suite.Client.CountMeta("/", nil)
+	require.NoError(err)
+	require.Equal(4, count, "nil matches all")
+
+	detailers, err = suite.Client.ListDetailMeta("/", map[string]string{"k": "z"})
+	require.NoError(err)
+	require.NotNil(detailers)
+	require.Empty(detailers)
+
+}
+
+func (suite *JsobsTestSuite) TestListDetailMetaError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+	_, err := suite.Client.ListDetailMeta("/", nil)
+	require.EqualError(err, "oops")
+	_, err = suite.Client.CountMeta("/", nil)
+	require.EqualError(err, "oops")
+
+}
+
+func (suite *JsobsTestSuite) TestMetaCtxMethodsFailCanceled() {
+
+	require := suite.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := suite.memClient()
+	require.ErrorIs(client.SaveWithMetaCtx(ctx, "/m", 1, nil),
+		context.Canceled, "SaveWithMetaCtx")
+	require.ErrorIs(client.SaveExpiryWithMetaCtx(ctx, "/m", 1, time.Now(), nil),
+		context.Canceled, "SaveExpiryWithMetaCtx")
+	_, err := client.ListDetailMetaCtx(ctx, "/", nil)
+	require.ErrorIs(err, context.Canceled, "ListDetailMetaCtx")
+	_, err = client.CountMetaCtx(ctx, "/", nil)
+	require.ErrorIs(err, context.Canceled, "CountMetaCtx")
+
+}
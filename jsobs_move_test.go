@@ -0,0 +1,135 @@
+// jsobs_move_test.go -- tests for copying and moving objects.
+
+package jsobs_test
+
+import (
+	"time"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/fsclient"
+)
+
+// fsClient returns a client for an fsclient, which is not a backend.Mover.
+func (suite *JsobsTestSuite) fsClient() *jsobs.Client {
+
+	require := suite.Require()
+
+	client, err := jsobs.New(fsclient.NewForRoot(suite.T().TempDir()))
+	require.NoError(err)
+	return client
+
+}
+
+func (suite *JsobsTestSuite) TestMoveMoverOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.SaveWithMeta("/a", 1, map[string]string{"k": "v"}))
+	require.NoError(client.Copy("/a", "/b", jsobs.OverwriteNever))
+	require.NoError(client.Move("/a", "/c", jsobs.OverwriteNever))
+	moved, err := client.MovePrefix("/", "/x/", jsobs.OverwriteNever)
+	require.ErrorIs(err, jsobs.ErrInvalidPath)
+	require.Equal(0, moved)
+
+	paths, err := client.List("/")
+	require.NoError(err)
+	require.Equal([]string{"/b", "/c"}, paths)
+	detail, err := client.LoadDetail("/c")
+	require.NoError(err)
+	require.Equal(map[string]string{"k": "v"}, detail.Meta())
+
+}
+
+func (suite *JsobsTestSuite) TestCopyFallbackOK() {
+
+	require := suite.Require()
+
+	client := suite.fsClient()
+	meta := map[string]string{"owner": "bob"}
+	expiry := time.Now().Add(time.Hour)
+	require.NoError(client.SaveExpiryWithMeta("/src", "a", expiry, meta))
+	require.NoError(client.Save("/dst", "b"))
+
+	err := client.Copy("/src", "/dst", jsobs.OverwriteNever)
+	require.ErrorIs(err, jsobs.ErrConflict)
+	require.NoError(client.Copy("/src", "/dst", jsobs.OverwriteSkip))
+	var s string
+	require.NoError(client.Load("/dst", &s))
+	require.Equal("b", s)
+
+	require.NoError(client.Copy("/src", "/dst", jsobs.OverwriteAlways))
+	require.NoError(client.Load("/dst", &s))
+	require.Equal("a", s)
+	detail, err := client.LoadDetail("/dst")
+	require.NoError(err)
+	require.Equal(meta, detail.Meta())
+	require.WithinDuration(expiry, detail.Expiry(), time.Millisecond)
+	require.NoError(client.Load("/src", &s), "source kept")
+
+	require.ErrorIs(client.Copy("/none", "/dst", jsobs.OverwriteAlways),
+		jsobs.ErrNotFound)
+	require.ErrorIs(client.Copy("/src", "/src", jsobs.OverwriteAlways),
+		jsobs.ErrInvalidPath)
+
+}
+
+func (suite *JsobsTestSuite) TestMoveFallbackOK() {
+
+	require := suite.Require()
+
+	client := suite.fsClient()
+	require.NoError(client.Save("/src", "a"))
+	require.NoError(client.Save("/dst", "b"))
+
+	require.NoError(client.Move("/src", "/dst", jsobs.OverwriteSkip))
+	var s string
+	require.NoError(client.Load("/src", &s), "skipped source kept")
+
+	require.NoError(client.Move("/src", "/dst", jsobs.OverwriteAlways))
+	require.ErrorIs(client.Load("/src", &s), jsobs.ErrNotFound)
+	require.NoError(client.Load("/dst", &s))
+	require.Equal("a", s)
+
+	require.ErrorIs(client.Move("/dst", "/dst", jsobs.OverwriteAlways),
+		jsobs.ErrInvalidPath)
+
+}
+
+func (suite *JsobsTestSuite) TestMovePrefixFallbackOK() {
+
+	require := suite.Require()
+
+	client := suite.fsClient()
+	for _, path := range []string{"/staging/a", "/staging/b", "/staging/c/d"} {
+		require.NoError(client.Save(path, path))
+	}
+	require.NoError(client.Save("/archive/b", "old"))
+
+	moved, err := client.MovePrefix("/staging/", "/archive/", jsobs.OverwriteNever)
+	require.ErrorIs(err, jsobs.ErrConflict)
+	require.Equal(0, moved)
+	count, err := client.Count("/staging/")
+	require.NoError(err)
+	require.Equal(3, count, "nothing moved")
+
+	moved, err = client.MovePrefix("/staging/", "/archive/", jsobs.OverwriteSkip)
+	require.NoError(err)
+	require.Equal(2, moved)
+	paths, err := client.List("/staging/")
+	require.NoError(err)
+	require.Equal([]string{"/staging/b"}, paths)
+
+	moved, err = client.MovePrefix("/staging/", "/archive/", jsobs.OverwriteAlways)
+	require.NoError(err)
+	require.Equal(1, moved)
+	var s string
+	require.NoError(client.Load("/archive/c/d", &s))
+	require.Equal("/staging/c/d", s)
+	require.NoError(client.Load("/archive/b", &s))
+	require.Equal("/staging/b", s)
+
+	_, err = client.MovePrefix("/archive/", "/archive/x/", jsobs.OverwriteAlways)
+	require.ErrorIs(err, jsobs.ErrInvalidPath)
+
+}
@@ -0,0 +1,168 @@
+// jsobs_page_test.go -- tests for paged listings.
+
+package jsobs_test
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/backend"
+)
+
+// pagedClient returns a memory client with count objects under /p/, and a
+// few others around them to be ignored.
+func (suite *JsobsTestSuite) pagedClient(count int) *jsobs.Client {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	for i := 0; i < count; i++ {
+		require.NoError(client.Save(fmt.Sprintf("/p/%02d", i), i))
+	}
+	require.NoError(client.Save("/o", 0))
+	require.NoError(client.Save("/q", 0))
+	require.NoError(client.SaveExpiry("/p/05x", 0, time.Now().Add(-time.Second)))
+	return client
+
+}
+
+func (suite *JsobsTestSuite) TestListPageFallbackOK() {
+
+	require := suite.Require()
+
+	client := suite.pagedClient(10)
+
+	pages := [][]string{}
+	cursor := ""
+	for {
+		paths, next, err := client.ListPage("/p/", cursor, 3)
+		require.NoError(err)
+		pages = append(pages, paths)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	require.Equal([][]string{
+		{"/p/00", "/p/01", "/p/02"},
+		{"/p/03", "/p/04", "/p/05"},
+		{"/p/06", "/p/07", "/p/08"},
+		{"/p/09"},
+	}, pages)
+
+}
+
+func (suite *JsobsTestSuite) TestListPageFallbackExactOK() {
+
+	require := suite.Require()
+
+	client := suite.pagedClient(6)
+
+	paths, next, err := client.ListPage("/p/", "", 3)
+	require.NoError(err)
+	require.Equal([]string{"/p/00", "/p/01", "/p/02"}, paths)
+	require.NotEmpty(next)
+
+	paths, next, err = client.ListPage("/p/", next, 3)
+	require.NoError(err)
+	require.Equal([]string{"/p/03", "/p/04", "/p/05"}, paths)
+	require.Empty(next, "no empty last page")
+
+}
+
+func (suite *JsobsTestSuite) TestListPageFallbackEmptyOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	paths, next, err := client.ListPage("/p/", "", 3)
+	require.NoError(err)
+	require.Empty(paths)
+	require.Empty(next)
+
+}
+
+func (suite *JsobsTestSuite) TestListDetailPageFallbackOK() {
+
+	require := suite.Require()
+
+	client := suite.pagedClient(5)
+
+	detailers, next, err := client.ListDetailPage("/p/", "", 4)
+	require.NoError(err)
+	require.Len(detailers, 4)
+	require.Equal("/p/00", detailers[0].Path())
+	require.Equal("/p/03", detailers[3].Path())
+
+	detailers, next, err = client.ListDetailPage("/p/", next, 4)
+	require.NoError(err)
+	require.Len(detailers, 1)
+	require.Equal("/p/04", detailers[0].Path())
+	require.Empty(next)
+
+}
+
+func (suite *JsobsTestSuite) TestListPageFailsBadArgs() {
+
+	require := suite.Require()
+
+	_, _, err := suite.Client.ListPage("/p/", "not*base64", 3)
+	require.ErrorIs(err, jsobs.ErrInvalidCursor)
+	_, _, err = suite.Client.ListDetailPage("/p/", "not*base64", 3)
+	require.ErrorIs(err, jsobs.ErrInvalidCursor)
+
+	_, _, err = suite.Client.ListPage("/p/", "", 0)
+	require.ErrorContains(err, "page limit must be positive")
+	_, _, err = suite.Client.ListDetailPage("/p/", "", -1)
+	require.ErrorContains(err, "page limit must be positive")
+
+	require.Nil(suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestListPageFallbackError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+
+	_, _, err := suite.Client.ListPage("/p/", "", 3)
+	require.EqualError(err, "oops")
+	_, _, err = suite.Client.ListDetailPage("/p/", "", 3)
+	require.EqualError(err, "oops")
+	require.EqualValues([]string{"List", "ListDetail"},
+		suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestListPageUsesPagerBackendOK() {
+
+	require := suite.Require()
+
+	b := &TestPagerBackend{TestBackend: suite.Backend}
+	c := &jsobs.Client{Backend: b}
+
+	b.nextPaths = []string{"/a"}
+	b.nextDetailers = []backend.Detailer{&TestDetailer{path: "/a"}}
+	b.nextCursor = "next"
+
+	paths, next, err := c.ListPage("/", "curs", 7)
+	require.NoError(err)
+	require.Equal([]string{"/a"}, paths)
+	require.Equal("next", next)
+	require.Equal("curs", b.lastCursor)
+	require.Equal(7, b.lastLimit)
+
+	detailers, next, err := c.ListDetailPage("/", "curs2", 8)
+	require.NoError(err)
+	require.Equal(b.nextDetailers, detailers)
+	require.Equal("next", next)
+	require.Equal("curs2", b.lastCursor)
+	require.Equal(8, b.lastLimit)
+
+	require.EqualValues([]string{"ListPageCtx", "ListDetailPageCtx"},
+		b.allCalls, "calls")
+
+}
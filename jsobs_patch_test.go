@@ -0,0 +1,221 @@
+// jsobs_patch_test.go -- tests for partial updates.
+
+package jsobs_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/memclient"
+)
+
+// conflictingBackend is a MemClient whose conditional saves always conflict.
+type conflictingBackend struct {
+	*memclient.MemClient
+	saves int
+}
+
+func (b *conflictingBackend) SaveRawIfVersionCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, version int64) error {
+	b.saves++
+	return fmt.Errorf("%w: always", jsobs.ErrConflict)
+}
+
+func (suite *JsobsTestSuite) TestMergePatchOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/m", map[string]any{
+		"name": "foo", "count": 1, "sub": map[string]any{"a": 1, "b": 2}}))
+
+	err := client.MergePatch("/m", map[string]any{
+		"count": 2, "sub": map[string]any{"b": nil, "c": 3}})
+	require.NoError(err)
+
+	raw, version, err := client.LoadRawWithVersion("/m")
+	require.NoError(err)
+	require.JSONEq(`{"name":"foo","count":2,"sub":{"a":1,"c":3}}`, string(raw))
+	require.EqualValues(2, version)
+
+}
+
+func (suite *JsobsTestSuite) TestPatchOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/p", map[string]any{
+		"name": "foo", "tags": []string{"a"}}))
+
+	err := client.Patch("/p", []jsobs.PatchOp{
+		{Op: "test", Path: "/name", Value: "foo"},
+		{Op: "replace", Path: "/name", Value: "bar"},
+		{Op: "add", Path: "/tags/-", Value: "b"},
+		{Op: "add", Path: "/none", Value: nil},
+		{Op: "copy", From: "/name", Path: "/old"},
+	})
+	require.NoError(err)
+
+	raw, err := client.LoadRaw("/p")
+	require.NoError(err)
+	require.JSONEq(`{"name":"bar","tags":["a","b"],"none":null,"old":"bar"}`,
+		string(raw))
+
+}
+
+func (suite *JsobsTestSuite) TestPatchKeepsExpiryOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	expiry := time.Now().Add(time.Hour)
+	require.NoError(client.SaveExpiry("/p", map[string]int{"n": 1}, expiry))
+
+	require.NoError(client.MergePatch("/p", map[string]int{"n": 2}))
+
+	detail, err := client.LoadDetail("/p")
+	require.NoError(err)
+	require.True(detail.Expires())
+	require.True(expiry.Equal(detail.Expiry()), "same expiry")
+
+}
+
+func (suite *JsobsTestSuite) TestPatchFailedTest() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/p", map[string]any{"name": "foo"}))
+
+	err := client.Patch("/p", []jsobs.PatchOp{
+		{Op: "replace", Path: "/name", Value: "bar"},
+		{Op: "test", Path: "/name", Value: "foo"},
+	})
+	require.ErrorIs(err, jsobs.ErrPatchFailed)
+	require.ErrorContains(err, "test failed")
+
+	raw, version, err := client.LoadRawWithVersion("/p")
+	require.NoError(err)
+	require.JSONEq(`{"name":"foo"}`, string(raw), "unchanged")
+	require.EqualValues(1, version, "not saved")
+
+}
+
+func (suite *JsobsTestSuite) TestPatchBadOpFails() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/p", map[string]any{"name": "foo"}))
+
+	err := client.Patch("/p", []jsobs.PatchOp{{Op: "frob", Path: "/name"}})
+	require.ErrorIs(err, jsobs.ErrPatchFailed)
+	err = client.Patch("/p", []jsobs.PatchOp{{Op: "remove", Path: "/none"}})
+	require.ErrorIs(err, jsobs.ErrPatchFailed)
+
+}
+
+func (suite *JsobsTestSuite) TestPatchNotFound() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.ErrorIs(client.MergePatch("/none", map[string]int{"n": 1}),
+		jsobs.ErrNotFound)
+	require.ErrorIs(client.Patch("/none", nil), jsobs.ErrNotFound)
+
+}
+
+func (suite *JsobsTestSuite) TestPatchJsonError() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	err := client.MergePatch("/p", func() {})
+	require.ErrorContains(err, "Failed to marshal JSON")
+	err = client.Patch("/p", []jsobs.PatchOp{{Op: "add", Path: "/x",
+		Value: func() {}}})
+	require.ErrorContains(err, "Failed to marshal JSON")
+
+}
+
+func (suite *JsobsTestSuite) TestPatchFailsUnsupported() {
+
+	require := suite.Require()
+
+	err := suite.Client.MergePatch("/p", map[string]int{"n": 1})
+	require.ErrorIs(err, jsobs.ErrUnsupported)
+	err = suite.Client.Patch("/p", nil)
+	require.ErrorIs(err, jsobs.ErrUnsupported)
+
+}
+
+func (suite *JsobsTestSuite) TestPatchConflictRetriesFail() {
+
+	require := suite.Require()
+
+	defer func(n int) { jsobs.UpdateRetries = n }(jsobs.UpdateRetries)
+	jsobs.UpdateRetries = 3
+
+	backend := &conflictingBackend{MemClient: memclient.New()}
+	client := &jsobs.Client{Backend: backend}
+	require.NoError(client.Save("/p", map[string]int{"n": 1}))
+
+	err := client.MergePatch("/p", map[string]int{"n": 2})
+	require.ErrorIs(err, jsobs.ErrConflict)
+	require.ErrorContains(err, "/p changed during 4 tries")
+	require.Equal(4, backend.saves, "saves tried")
+
+}
+
+func (suite *JsobsTestSuite) TestMergePatchConcurrentOK() {
+
+	require := suite.Require()
+
+	defer func(n int) { jsobs.UpdateRetries = n }(jsobs.UpdateRetries)
+	jsobs.UpdateRetries = 1000
+
+	client := suite.memClient()
+	require.NoError(client.Save("/c", map[string]bool{}))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.MergePatch("/c",
+				map[string]bool{fmt.Sprintf("k%02d", i): true})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		require.NoError(err, "patch %d", i)
+	}
+
+	var got map[string]bool
+	version, err := client.LoadWithVersion("/c", &got)
+	require.NoError(err)
+	require.Len(got, 20, "no lost updates")
+	require.EqualValues(21, version)
+
+}
+
+func (suite *JsobsTestSuite) TestPatchCtxFailsCanceled() {
+
+	require := suite.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/p", map[string]int{"n": 1}))
+	require.ErrorIs(client.MergePatchCtx(ctx, "/p", map[string]int{"n": 2}),
+		context.Canceled, "MergePatchCtx")
+	require.ErrorIs(client.PatchCtx(ctx, "/p", nil),
+		context.Canceled, "PatchCtx")
+
+}
@@ -0,0 +1,125 @@
+// jsobs_purger_test.go -- tests for the background purger.
+
+package jsobs_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/purger"
+)
+
+func (suite *JsobsTestSuite) TestStartPurgerFailsUnsupported() {
+
+	require := suite.Require()
+
+	err := suite.Client.StartPurger(time.Millisecond, 0, nil)
+	require.ErrorIs(err, jsobs.ErrUnsupported)
+	require.ErrorContains(err, "can not purge")
+
+}
+
+func (suite *JsobsTestSuite) TestStartPurgerFailsBadInterval() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	err := client.StartPurger(0, 0, nil)
+	require.ErrorContains(err, "interval")
+
+}
+
+func (suite *JsobsTestSuite) TestStartPurgerFailsRunning() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.StartPurger(time.Hour, 0, nil))
+	defer client.StopPurger()
+
+	err := client.StartPurger(time.Hour, 0, nil)
+	require.ErrorIs(err, purger.ErrRunning)
+
+}
+
+func (suite *JsobsTestSuite) TestStartPurgerOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.SaveExpiry("/old", 1, time.Now().Add(-time.Second)))
+	require.NoError(client.Save("/new", 2))
+
+	var mutex sync.Mutex
+	total := 0
+	hook := func(purged int, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		require.NoError(err)
+		total += purged
+	}
+	require.NoError(client.StartPurger(time.Millisecond, 0, hook))
+	require.Eventually(func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return total == 1
+	}, time.Second, time.Millisecond, "purged")
+	client.StopPurger()
+
+	list, err := client.List("/")
+	require.NoError(err)
+	require.Equal([]string{"/new"}, list, "unexpired kept")
+
+	// Can start again once stopped.
+	require.NoError(client.StartPurger(time.Hour, 0, nil))
+	client.StopPurger()
+
+}
+
+func (suite *JsobsTestSuite) TestStopPurgerNotStartedOK() {
+
+	require := suite.Require()
+
+	require.NotPanics(suite.Client.StopPurger)
+
+}
+
+func (suite *JsobsTestSuite) TestShutdownStopsPurgerOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.StartPurger(time.Hour, 0, nil))
+
+	client.Shutdown(0)
+	require.True(suite.Exited)
+	require.Equal(0, suite.ExitCode)
+	require.NoError(client.StartPurger(time.Hour, 0, nil), "stopped")
+	client.StopPurger()
+
+}
+
+// Run with -race to check the purger is guarded.
+func (suite *JsobsTestSuite) TestStartPurgerConcurrentCloseOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			client.StartPurger(time.Hour, 0, nil)
+		}()
+		go func() {
+			defer wg.Done()
+			client.StopPurger()
+		}()
+	}
+	wg.Wait()
+	require.NoError(client.Close(context.Background()))
+
+}
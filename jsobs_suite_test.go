@@ -3,12 +3,14 @@
 package jsobs_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/biztos/jsobs"
 	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/memclient"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -24,6 +26,8 @@ type TestDetailer struct {
 	size     int
 	expiry   *time.Time
 	modified time.Time
+	version  int64
+	meta     map[string]string
 }
 
 func (d *TestDetailer) Path() string {
@@ -44,6 +48,12 @@ func (d *TestDetailer) Expiry() time.Time {
 func (d *TestDetailer) Modified() time.Time {
 	return d.modified
 }
+func (d *TestDetailer) Version() int64 {
+	return d.version
+}
+func (d *TestDetailer) Meta() map[string]string {
+	return d.meta
+}
 
 // This is a VERY simple mock because we don't really have any use case in
 // which more than one backend call happens in a row before we can inspect
@@ -134,6 +144,124 @@ func (t *TestBackend) Shutdown() error {
 	return t.nextError
 }
 
+// TestCtxBackend adds the context-taking methods to TestBackend, recording
+// them as e.g. "SaveRawCtx" and keeping the context for inspection.
+type TestCtxBackend struct {
+	*TestBackend
+	lastCtx context.Context
+}
+
+func (t *TestCtxBackend) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	t.lastCtx = ctx
+	t.addCall("SaveRawCtx")
+	t.lastPath = path
+	t.lastData = raw_obj
+	return t.nextError
+}
+func (t *TestCtxBackend) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	t.lastCtx = ctx
+	t.addCall("SaveRawExpiryCtx")
+	t.lastPath = path
+	t.lastData = raw_obj
+	t.lastExpiry = expiry
+	return t.nextError
+}
+func (t *TestCtxBackend) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+	t.lastCtx = ctx
+	t.addCall("LoadRawCtx")
+	t.lastPath = path
+	return t.nextData, t.nextError
+}
+func (t *TestCtxBackend) LoadDetailCtx(ctx context.Context, path string) (backend.Detailer, error) {
+	t.lastCtx = ctx
+	t.addCall("LoadDetailCtx")
+	t.lastPath = path
+	return t.nextDetailer, t.nextError
+}
+func (t *TestCtxBackend) DeleteCtx(ctx context.Context, path string) error {
+	t.lastCtx = ctx
+	t.addCall("DeleteCtx")
+	t.lastPath = path
+	return t.nextError
+}
+func (t *TestCtxBackend) ListCtx(ctx context.Context, prefix string) ([]string, error) {
+	t.lastCtx = ctx
+	t.addCall("ListCtx")
+	t.lastPrefix = prefix
+	return t.nextPaths, t.nextError
+}
+func (t *TestCtxBackend) ListDetailCtx(ctx context.Context, prefix string) ([]backend.Detailer, error) {
+	t.lastCtx = ctx
+	t.addCall("ListDetailCtx")
+	t.lastPrefix = prefix
+	return t.nextDetailers, t.nextError
+}
+func (t *TestCtxBackend) CountCtx(ctx context.Context, prefix string) (int, error) {
+	t.lastCtx = ctx
+	t.addCall("CountCtx")
+	t.lastPrefix = prefix
+	return t.nextCount, t.nextError
+}
+func (t *TestCtxBackend) CountAllCtx(ctx context.Context) (int, error) {
+	t.lastCtx = ctx
+	t.addCall("CountAllCtx")
+	return t.nextCount, t.nextError
+}
+func (t *TestCtxBackend) ShutdownCtx(ctx context.Context) error {
+	t.lastCtx = ctx
+	t.addCall("ShutdownCtx")
+	return t.nextError
+}
+
+// TestBatchBackend adds the backend.BatchClient methods to TestBackend,
+// recording them as e.g. "SaveRawManyCtx".
+type TestBatchBackend struct {
+	*TestBackend
+	lastObjs  map[string][]byte
+	lastPaths []string
+	nextMany  map[string][]byte
+}
+
+func (t *TestBatchBackend) SaveRawManyCtx(ctx context.Context, raw_objs map[string][]byte) error {
+	t.addCall("SaveRawManyCtx")
+	t.lastObjs = raw_objs
+	return t.nextError
+}
+func (t *TestBatchBackend) LoadRawManyCtx(ctx context.Context, paths []string) (map[string][]byte, error) {
+	t.addCall("LoadRawManyCtx")
+	t.lastPaths = paths
+	return t.nextMany, t.nextError
+}
+func (t *TestBatchBackend) DeleteManyCtx(ctx context.Context, paths []string) (int, error) {
+	t.addCall("DeleteManyCtx")
+	t.lastPaths = paths
+	return t.nextCount, t.nextError
+}
+
+// TestPagerBackend adds the backend.Pager methods to TestBackend, recording
+// them as e.g. "ListPageCtx".
+type TestPagerBackend struct {
+	*TestBackend
+	lastCursor string
+	lastLimit  int
+	nextCursor string
+}
+
+func (t *TestPagerBackend) ListPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]string, string, error) {
+	t.addCall("ListPageCtx")
+	t.lastPrefix = prefix
+	t.lastCursor = cursor
+	t.lastLimit = limit
+	return t.nextPaths, t.nextCursor, t.nextError
+}
+func (t *TestPagerBackend) ListDetailPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]backend.Detailer, string, error) {
+	t.addCall("ListDetailPageCtx")
+	t.lastPrefix = prefix
+	t.lastCursor = cursor
+	t.lastLimit = limit
+	return t.nextDetailers, t.nextCursor, t.nextError
+}
+
 type JsobsTestSuite struct {
 	suite.Suite
 	Client   *jsobs.Client
@@ -159,6 +287,18 @@ func (suite *JsobsTestSuite) SetupTest() {
 
 }
 
+// memClient returns a client with an empty in-memory backend, for tests that
+// need real storage behind the client.
+func (suite *JsobsTestSuite) memClient() *jsobs.Client {
+
+	require := suite.Require()
+
+	client, err := jsobs.New(memclient.New(), nil)
+	require.NoError(err)
+	return client
+
+}
+
 // The actual runner func:
 func TestJsobsTestSuite(t *testing.T) {
 	suite.Run(t, new(JsobsTestSuite))
@@ -3,13 +3,19 @@
 package jsobs_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/biztos/jsobs"
 	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/fsclient"
+	"github.com/biztos/jsobs/memclient"
 	"github.com/biztos/jsobs/pgclient"
+	"github.com/biztos/jsobs/s3client"
+	"github.com/biztos/jsobs/sqliteclient"
 )
 
 func (suite *JsobsTestSuite) TestNewPgClientFailsBadUrl() {
@@ -102,6 +108,56 @@ func (suite *JsobsTestSuite) TestShutdownWithBackendErrorOK() {
 
 }
 
+func (suite *JsobsTestSuite) TestCloseOK() {
+
+	require := suite.Require()
+
+	require.NoError(suite.Client.Close(context.Background()))
+	require.EqualValues([]string{"Shutdown"}, suite.Backend.allCalls, "calls")
+	require.False(suite.Exited)
+
+}
+
+func (suite *JsobsTestSuite) TestCloseError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("backend fail")
+
+	err := suite.Client.Close(context.Background())
+	require.EqualError(err, "backend fail")
+	require.EqualValues([]string{"Shutdown"}, suite.Backend.allCalls, "calls")
+	require.False(suite.Exited)
+
+}
+
+func (suite *JsobsTestSuite) TestCloseCanceledFails() {
+
+	require := suite.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := suite.Client.Close(ctx)
+	require.ErrorIs(err, context.Canceled)
+	require.Nil(suite.Backend.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestCloseUsesCtxBackendOK() {
+
+	require := suite.Require()
+
+	b := &TestCtxBackend{TestBackend: suite.Backend}
+	c := &jsobs.Client{Backend: b}
+	ctx := context.WithValue(context.Background(), ctxKey("k"), "close")
+
+	require.NoError(c.Close(ctx))
+	require.EqualValues([]string{"ShutdownCtx"}, b.allCalls, "calls")
+	require.Equal(ctx, b.lastCtx, "ctx")
+
+}
+
 func (suite *JsobsTestSuite) TestListDetailError() {
 
 	require := suite.Require()
@@ -493,8 +549,33 @@ func (suite *JsobsTestSuite) TestIsNotFound() {
 
 	require := suite.Require()
 
-	// right now it's just dealing with pg so... Ye Olde Filler Teste!
-	require.True(jsobs.IsNotFound(pgclient.ErrNotFound), "not found")
+	require.True(jsobs.IsNotFound(jsobs.ErrNotFound), "not found")
+	require.True(jsobs.IsNotFound(jsobs.ErrExpired), "expired")
+	require.True(jsobs.IsNotFound(fmt.Errorf("wrapped: %w", jsobs.ErrNotFound)),
+		"wrapped")
+	require.True(jsobs.IsNotFound(pgclient.ErrNotFound), "pg not found")
+	require.True(jsobs.IsNotFound(memclient.ErrNotFound), "mem not found")
+	require.True(jsobs.IsNotFound(sqliteclient.ErrNotFound), "sqlite not found")
+	require.True(jsobs.IsNotFound(fsclient.ErrNotFound), "fs not found")
+	require.True(jsobs.IsNotFound(s3client.ErrNotFound), "s3 not found")
 	require.False(jsobs.IsNotFound(errors.New("X")), "not not found")
+	require.False(jsobs.IsNotFound(jsobs.ErrConflict), "conflict")
+
+}
+
+func (suite *JsobsTestSuite) TestIsNotFoundFromBackendOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.SaveExpiry("/old", 1, time.Now().Add(-time.Second)))
+
+	err := client.Load("/none", new(int))
+	require.True(jsobs.IsNotFound(err), "missing")
+	require.NotErrorIs(err, jsobs.ErrExpired, "missing not expired")
+
+	err = client.Load("/old", new(int))
+	require.True(jsobs.IsNotFound(err), "expired")
+	require.ErrorIs(err, jsobs.ErrExpired, "expired")
 
 }
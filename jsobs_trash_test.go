@@ -0,0 +1,54 @@
+// jsobs_trash_test.go -- tests for undeleting soft-deleted objects.
+
+package jsobs_test
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/sqliteclient"
+)
+
+func (suite *JsobsTestSuite) TestTrashMethodsFailUnsupported() {
+
+	require := suite.Require()
+
+	c := suite.Client
+	require.ErrorIs(c.Undelete("/any"), jsobs.ErrUnsupported, "Undelete")
+	_, err := c.ListDeleted("/")
+	require.ErrorIs(err, jsobs.ErrUnsupported, "ListDeleted")
+
+}
+
+func (suite *JsobsTestSuite) TestUndeleteOK() {
+
+	require := suite.Require()
+
+	sc, err := sqliteclient.NewForFile(filepath.Join(suite.T().TempDir(), "db"))
+	require.NoError(err)
+	require.NoError(sc.CreateTable())
+	require.NoError(sc.CreateTrash())
+	sc.SoftDelete = true
+	client := &jsobs.Client{Backend: sc}
+	defer client.Close(context.Background())
+
+	require.NoError(client.Save("/t/a", "a"))
+	require.NoError(client.Save("/t/b", "b"))
+	require.NoError(client.Delete("/t/a"))
+	_, err = client.DeleteMany([]string{"/t/b"})
+	require.NoError(err)
+	require.ErrorIs(client.Load("/t/a", new(string)), jsobs.ErrNotFound)
+
+	deleted, err := client.ListDeleted("/t/")
+	require.NoError(err)
+	require.Len(deleted, 2)
+	require.Equal("/t/a", deleted[0].Path())
+
+	require.NoError(client.Undelete("/t/a"))
+	var s string
+	require.NoError(client.Load("/t/a", &s))
+	require.Equal("a", s)
+	require.ErrorIs(client.Undelete("/t/a"), jsobs.ErrNotFound)
+
+}
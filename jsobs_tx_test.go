@@ -0,0 +1,141 @@
+// jsobs_tx_test.go -- tests for transactions.
+
+package jsobs_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/biztos/jsobs"
+	"github.com/biztos/jsobs/sqliteclient"
+)
+
+func (suite *JsobsTestSuite) txClient() *jsobs.Client {
+
+	require := suite.Require()
+
+	sc, err := sqliteclient.NewForFile(filepath.Join(suite.T().TempDir(), "db"))
+	require.NoError(err)
+	require.NoError(sc.CreateTable())
+	client := &jsobs.Client{Backend: sc}
+	suite.T().Cleanup(func() { client.Close(context.Background()) })
+	return client
+
+}
+
+func (suite *JsobsTestSuite) TestTxFailsUnsupported() {
+
+	require := suite.Require()
+
+	called := false
+	err := suite.memClient().Tx(context.Background(), func(tx *jsobs.Tx) error {
+		called = true
+		return nil
+	})
+	require.ErrorIs(err, jsobs.ErrUnsupported)
+	require.ErrorContains(err, "has no transactions")
+	require.False(called, "fn called")
+
+}
+
+func (suite *JsobsTestSuite) TestTxCommitOK() {
+
+	require := suite.Require()
+
+	client := suite.txClient()
+	require.NoError(client.Save("/old", 1))
+	err := client.Tx(context.Background(), func(tx *jsobs.Tx) error {
+		if err := tx.Save("/orders/1", map[string]int{"n": 1}); err != nil {
+			return err
+		}
+		if err := tx.SaveExpiry("/index/1", true, time.Now().Add(time.Hour)); err != nil {
+			return err
+		}
+		if err := tx.SaveRaw("/raw", []byte(`"r"`)); err != nil {
+			return err
+		}
+		var order map[string]int
+		if err := tx.Load("/orders/1", &order); err != nil {
+			return err
+		}
+		if order["n"] != 1 {
+			return errors.New("not loaded")
+		}
+		if _, err := tx.LoadRaw("/old"); err != nil {
+			return err
+		}
+		return tx.Delete("/old")
+	})
+	require.NoError(err)
+
+	paths, err := client.List("/")
+	require.NoError(err)
+	require.Equal([]string{"/index/1", "/orders/1", "/raw"}, paths)
+
+}
+
+func (suite *JsobsTestSuite) TestTxRollbackOK() {
+
+	require := suite.Require()
+
+	client := suite.txClient()
+	oops := errors.New("oops")
+	var listed []string
+	err := client.Tx(context.Background(), func(tx *jsobs.Tx) error {
+		if err := tx.Save("/orders/1", 1); err != nil {
+			return err
+		}
+		var err error
+		listed, err = tx.List("/")
+		if err != nil {
+			return err
+		}
+		return oops
+	})
+	require.ErrorIs(err, oops)
+	require.Equal([]string{"/orders/1"}, listed, "seen in transaction")
+
+	count, err := client.CountAll()
+	require.NoError(err)
+	require.Equal(0, count, "rolled back")
+
+}
+
+func (suite *JsobsTestSuite) TestTxPanicRollsBack() {
+
+	require := suite.Require()
+
+	client := suite.txClient()
+	require.Panics(func() {
+		client.Tx(context.Background(), func(tx *jsobs.Tx) error {
+			tx.Save("/orders/1", 1)
+			panic("oops")
+		})
+	})
+
+	count, err := client.CountAll()
+	require.NoError(err)
+	require.Equal(0, count, "rolled back")
+
+}
+
+func (suite *JsobsTestSuite) TestTxJsonErrors() {
+
+	require := suite.Require()
+
+	client := suite.txClient()
+	err := client.Tx(context.Background(), func(tx *jsobs.Tx) error {
+		err := tx.Save("/x", DoesNotMarshal("nope"))
+		require.ErrorContains(err, "Failed to marshal JSON: ")
+		err = tx.SaveExpiry("/x", DoesNotMarshal("nope"), time.Now())
+		require.ErrorContains(err, "Failed to marshal JSON: ")
+		require.NoError(tx.Save("/x", "string"))
+		err = tx.Load("/x", new(int))
+		require.ErrorContains(err, "Failed to marshal JSON: ")
+		return tx.Delete("/none")
+	})
+	require.ErrorIs(err, jsobs.ErrNotFound)
+
+}
@@ -0,0 +1,135 @@
+// jsobs_version_test.go -- tests for versions and conditional saves.
+
+package jsobs_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/biztos/jsobs"
+)
+
+func (suite *JsobsTestSuite) TestVersionMethodsFailUnsupported() {
+
+	require := suite.Require()
+
+	c := suite.Client
+	_, err := c.LoadWithVersion("/any", new(int))
+	require.ErrorIs(err, jsobs.ErrUnsupported, "LoadWithVersion")
+	_, _, err = c.LoadRawWithVersion("/any")
+	require.ErrorIs(err, jsobs.ErrUnsupported, "LoadRawWithVersion")
+	require.ErrorIs(c.SaveIfVersion("/any", 1, 1), jsobs.ErrUnsupported,
+		"SaveIfVersion")
+	require.ErrorIs(c.SaveIfNotExists("/any", 1), jsobs.ErrUnsupported,
+		"SaveIfNotExists")
+	require.ErrorIs(c.SaveRawIfVersion("/any", []byte("1"), 1),
+		jsobs.ErrUnsupported, "SaveRawIfVersion")
+
+}
+
+func (suite *JsobsTestSuite) TestSaveIfVersionJsonError() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	err := client.SaveIfVersion("/any", func() {}, 0)
+	require.ErrorContains(err, "Failed to marshal JSON")
+	err = client.SaveIfNotExists("/any", func() {})
+	require.ErrorContains(err, "Failed to marshal JSON")
+
+}
+
+func (suite *JsobsTestSuite) TestLoadWithVersionJsonError() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/any", "string"))
+	_, err := client.LoadWithVersion("/any", new(int))
+	require.ErrorContains(err, "Failed to marshal JSON")
+
+}
+
+func (suite *JsobsTestSuite) TestLoadWithVersionNotFound() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	_, err := client.LoadWithVersion("/any", new(int))
+	require.ErrorIs(err, jsobs.ErrNotFound)
+
+}
+
+func (suite *JsobsTestSuite) TestSaveIfVersionOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+
+	require.NoError(client.SaveIfNotExists("/n", 1), "create")
+	err := client.SaveIfNotExists("/n", 2)
+	require.ErrorIs(err, jsobs.ErrConflict, "create again")
+
+	var n int
+	version, err := client.LoadWithVersion("/n", &n)
+	require.NoError(err)
+	require.Equal(1, n)
+	require.EqualValues(1, version)
+
+	require.NoError(client.SaveIfVersion("/n", 2, version), "update")
+	err = client.SaveIfVersion("/n", 3, version)
+	require.ErrorIs(err, jsobs.ErrConflict, "stale update")
+
+	// Plain saves count too.
+	require.NoError(client.Save("/n", 4))
+	detail, err := client.LoadDetail("/n")
+	require.NoError(err)
+	require.EqualValues(3, detail.Version())
+
+	raw, version, err := client.LoadRawWithVersion("/n")
+	require.NoError(err)
+	require.Equal("4", string(raw))
+	require.EqualValues(3, version)
+	require.NoError(client.SaveRawIfVersion("/n", []byte("5"), 3))
+
+	version, err = client.LoadWithVersion("/n", &n)
+	require.NoError(err)
+	require.Equal(5, n)
+	require.EqualValues(4, version)
+
+}
+
+func (suite *JsobsTestSuite) TestSaveIfNotExistsExpiredOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	past := time.Now().Add(-time.Second)
+	require.NoError(client.SaveExpiry("/n", 1, past))
+	require.NoError(client.SaveExpiry("/n", 1, past))
+
+	require.NoError(client.SaveIfNotExists("/n", 2), "replace expired")
+	var n int
+	version, err := client.LoadWithVersion("/n", &n)
+	require.NoError(err)
+	require.Equal(2, n)
+	require.EqualValues(1, version, "starts over")
+
+}
+
+func (suite *JsobsTestSuite) TestVersionCtxMethodsFailCanceled() {
+
+	require := suite.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := suite.memClient()
+	_, err := client.LoadWithVersionCtx(ctx, "/any", new(int))
+	require.ErrorIs(err, context.Canceled, "LoadWithVersionCtx")
+	require.ErrorIs(client.SaveIfVersionCtx(ctx, "/any", 1, 1),
+		context.Canceled, "SaveIfVersionCtx")
+	require.ErrorIs(client.SaveIfNotExistsCtx(ctx, "/any", 1),
+		context.Canceled, "SaveIfNotExistsCtx")
+
+}
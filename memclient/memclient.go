@@ -0,0 +1,590 @@
+// memclient.go - in-memory backend client
+//
+// Everything lives in a map guarded by a RWMutex, so the client is safe for
+// concurrent use but nothing survives the process.  Intended for tests and
+// short-lived tools that want jsobs semantics without a database.
+
+// Package memclient implements backend.BackendClient in process memory.
+package memclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// Errors as defined in the backend package; expired objects are reported as
+// backend.ErrExpired, which is also an ErrNotFound.
+var (
+	ErrNotFound    = backend.ErrNotFound
+	ErrInvalidJson = backend.ErrInvalidJson
+)
+
+// MemDetailer implements backend.Detailer to describe an object.
+type MemDetailer struct {
+	path     string
+	size     int
+	expiry   *time.Time
+	modified time.Time
+	version  int64
+	meta     map[string]string
+}
+
+// Path implements backend.Detailer.
+func (d *MemDetailer) Path() string {
+	return d.path
+}
+
+// Size implements backend.Detailer.
+func (d *MemDetailer) Size() int {
+	return d.size
+}
+
+// Expires implements backend.Detailer.
+func (d *MemDetailer) Expires() bool {
+	return d.expiry != nil
+}
+
+// Expiry implements backend.Detailer. If Expires returns false then Expiry must
+// be ignored.
+func (d *MemDetailer) Expiry() time.Time {
+	if d.expiry == nil {
+		return time.Time{} // "zero time"
+	}
+	return *d.expiry
+}
+
+// Modified implements backend.Detailer.
+func (d *MemDetailer) Modified() time.Time {
+	return d.modified
+}
+
+// Version implements backend.Detailer.
+func (d *MemDetailer) Version() int64 {
+	return d.version
+}
+
+// Meta implements backend.Detailer.
+func (d *MemDetailer) Meta() map[string]string {
+	return d.meta
+}
+
+// memObject is what we actually keep in the map.
+type memObject struct {
+	data     []byte
+	expiry   *time.Time
+	modified time.Time
+	version  int64
+	meta     map[string]string // never changed once set
+}
+
+func (o *memObject) expired(now time.Time) bool {
+	return o.expiry != nil && !o.expiry.After(now)
+}
+
+func (o *memObject) detailer(path string) *MemDetailer {
+	return &MemDetailer{
+		path:     path,
+		size:     len(o.data),
+		expiry:   o.expiry,
+		modified: o.modified,
+		version:  o.version,
+		meta:     backend.CopyMeta(o.meta),
+	}
+}
+
+// MemClient is a BackendClient that keeps all objects in memory.
+//
+// If Binary is true the data is not required to be valid JSON, for use
+// under wrappers such as compressclient.
+type MemClient struct {
+	PurgeOnShutdown bool
+	Binary          bool
+
+	mutex   sync.RWMutex
+	objects map[string]*memObject
+}
+
+// StoresBinary implements backend.BinaryClient.
+func (c *MemClient) StoresBinary() bool {
+	return c.Binary
+}
+
+// New returns a new, empty MemClient with PurgeOnShutdown true.
+func New() *MemClient {
+	return &MemClient{
+		PurgeOnShutdown: true,
+		objects:         map[string]*memObject{},
+	}
+}
+
+// String returns an identifying string.
+func (c *MemClient) String() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return fmt.Sprintf("memclient (objects=%d)", len(c.objects))
+}
+
+func (c *MemClient) save(path string, raw_obj []byte, expiry *time.Time) error {
+	return c.saveIf(path, raw_obj, expiry, -1, nil)
+}
+
+// saveIf saves raw_obj if the live version at path is version, with zero
+// for no live object; a negative version saves unconditionally.  The live
+// object's metadata is kept unless meta is not nil.
+func (c *MemClient) saveIf(path string, raw_obj []byte, expiry *time.Time, version int64, meta *map[string]string) error {
+
+	if !c.Binary && !json.Valid(raw_obj) {
+		return ErrInvalidJson
+	}
+
+	// Copy the data so the caller can't change it behind our back.
+	data := make([]byte, len(raw_obj))
+	copy(data, raw_obj)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	current := int64(0)
+	var keep map[string]string
+	if obj, err := c.get(path); err == nil {
+		current = obj.version
+		keep = obj.meta
+	}
+	if meta != nil {
+		keep = backend.CopyMeta(*meta)
+	}
+	if version >= 0 && version != current {
+		return fmt.Errorf("%w: %s not at version %d", backend.ErrConflict,
+			path, version)
+	}
+	c.objects[path] = &memObject{
+		data:     data,
+		expiry:   expiry,
+		modified: time.Now(),
+		version:  current + 1,
+		meta:     keep,
+	}
+	return nil
+}
+
+// SaveRaw saves the raw_obj with no expiry.
+func (c *MemClient) SaveRaw(path string, raw_obj []byte) error {
+	return c.SaveRawCtx(context.Background(), path, raw_obj)
+}
+
+// SaveRawCtx is SaveRaw with a context.
+func (c *MemClient) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.save(path, raw_obj, nil)
+}
+
+// SaveRawExpiry saves the raw bytes for availability until expiry.
+func (c *MemClient) SaveRawExpiry(path string, raw_obj []byte, expiry time.Time) error {
+	return c.SaveRawExpiryCtx(context.Background(), path, raw_obj, expiry)
+}
+
+// SaveRawExpiryCtx is SaveRawExpiry with a context.
+func (c *MemClient) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.save(path, raw_obj, &expiry)
+}
+
+// get returns the live object at path, or ErrNotFound if it does not exist
+// or ErrExpired if it is expired.  The caller must hold the lock.
+func (c *MemClient) get(path string) (*memObject, error) {
+	obj := c.objects[path]
+	if obj == nil {
+		return nil, ErrNotFound
+	}
+	if obj.expired(time.Now()) {
+		return nil, backend.ErrExpired
+	}
+	return obj, nil
+}
+
+// LoadRaw retrieves the object at path and returns its raw value.
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *MemClient) LoadRaw(path string) ([]byte, error) {
+	return c.LoadRawCtx(context.Background(), path)
+}
+
+// LoadRawCtx is LoadRaw with a context.
+func (c *MemClient) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	obj, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, len(obj.data))
+	copy(data, obj.data)
+	return data, nil
+}
+
+// LoadRawVersionCtx implements backend.Versioner.
+func (c *MemClient) LoadRawVersionCtx(ctx context.Context, path string) ([]byte, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	obj, err := c.get(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]byte, len(obj.data))
+	copy(data, obj.data)
+	return data, obj.version, nil
+}
+
+// SaveRawIfVersionCtx implements backend.Versioner.
+func (c *MemClient) SaveRawIfVersionCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if version < 0 {
+		return fmt.Errorf("%w: negative version %d", backend.ErrConflict, version)
+	}
+	if expiry != nil {
+		e := *expiry // don't keep the caller's pointer
+		expiry = &e
+	}
+	return c.saveIf(path, raw_obj, expiry, version, nil)
+}
+
+// SaveRawMetaCtx implements backend.MetaClient.
+func (c *MemClient) SaveRawMetaCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, meta map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if expiry != nil {
+		e := *expiry // don't keep the caller's pointer
+		expiry = &e
+	}
+	return c.saveIf(path, raw_obj, expiry, -1, &meta)
+}
+
+// SetExpiryCtx implements backend.Expirer.
+func (c *MemClient) SetExpiryCtx(ctx context.Context, path string, expiry *time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if expiry != nil {
+		e := *expiry // don't keep the caller's pointer
+		expiry = &e
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	obj, err := c.get(path)
+	if err != nil {
+		return err
+	}
+	changed := *obj // objects are replaced, not changed
+	changed.expiry = expiry
+	c.objects[path] = &changed
+	return nil
+}
+
+// copyObject copies the live object at src to dst according to overwrite,
+// returning false if it was skipped.  The caller must hold the lock.
+func (c *MemClient) copyObject(src string, dst string, overwrite backend.Overwrite) (bool, error) {
+	obj, err := c.get(src)
+	if err != nil {
+		return false, err
+	}
+	current := int64(0)
+	if existing, err := c.get(dst); err == nil {
+		switch overwrite {
+		case backend.OverwriteNever:
+			return false, fmt.Errorf("%w: %s exists", backend.ErrConflict, dst)
+		case backend.OverwriteSkip:
+			return false, nil
+		}
+		current = existing.version
+	}
+	copied := *obj // data and meta are never changed once set
+	copied.modified = time.Now()
+	copied.version = current + 1
+	c.objects[dst] = &copied
+	return true, nil
+}
+
+// CopyCtx implements backend.Mover.
+func (c *MemClient) CopyCtx(ctx context.Context, src string, dst string, overwrite backend.Overwrite) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := backend.CheckMove(src, dst); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, err := c.copyObject(src, dst, overwrite)
+	return err
+}
+
+// MoveCtx implements backend.Mover.
+func (c *MemClient) MoveCtx(ctx context.Context, src string, dst string, overwrite backend.Overwrite) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := backend.CheckMove(src, dst); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	moved, err := c.copyObject(src, dst, overwrite)
+	if moved {
+		delete(c.objects, src)
+	}
+	return err
+}
+
+// MovePrefixCtx implements backend.Mover.
+func (c *MemClient) MovePrefixCtx(ctx context.Context, src_prefix string, dst_prefix string, overwrite backend.Overwrite) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := backend.CheckMovePrefix(src_prefix, dst_prefix); err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	paths := c.livePaths(src_prefix)
+	dsts := make([]string, len(paths))
+	for i, path := range paths {
+		dsts[i] = dst_prefix + strings.TrimPrefix(path, src_prefix)
+		if overwrite == backend.OverwriteNever {
+			if _, err := c.get(dsts[i]); err == nil {
+				return 0, fmt.Errorf("%w: %s exists", backend.ErrConflict, dsts[i])
+			}
+		}
+	}
+	count := 0
+	for i, path := range paths {
+		moved, err := c.copyObject(path, dsts[i], overwrite)
+		if err != nil {
+			return count, err // can not happen
+		}
+		if moved {
+			delete(c.objects, path)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// LoadDetail retrieves the details of the object at path and returns its
+// a jsobs.Detailer.
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *MemClient) LoadDetail(path string) (backend.Detailer, error) {
+	return c.LoadDetailCtx(context.Background(), path)
+}
+
+// LoadDetailCtx is LoadDetail with a context.
+func (c *MemClient) LoadDetailCtx(ctx context.Context, path string) (backend.Detailer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	obj, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+	return obj.detailer(path), nil
+}
+
+// Delete deletes the object at path.
+//
+// As with pgclient, an expired object will still be deleted.
+//
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *MemClient) Delete(path string) error {
+	return c.DeleteCtx(context.Background(), path)
+}
+
+// DeleteCtx is Delete with a context.
+func (c *MemClient) DeleteCtx(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.objects[path]; !ok {
+		return ErrNotFound
+	}
+	delete(c.objects, path)
+	return nil
+}
+
+// DeletePrefixCtx implements backend.PrefixDeleter.
+func (c *MemClient) DeletePrefixCtx(ctx context.Context, prefix string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := backend.CheckDeletePrefix(prefix); err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	deleted := 0
+	for path := range c.objects {
+		if strings.HasPrefix(path, prefix) {
+			delete(c.objects, path)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// livePaths returns the sorted paths of all non-expired objects beginning
+// with prefix.  The caller must hold the lock.
+func (c *MemClient) livePaths(prefix string) []string {
+	now := time.Now()
+	paths := []string{}
+	for path, obj := range c.objects {
+		if strings.HasPrefix(path, prefix) && !obj.expired(now) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// List returns an array of all objects beginning with prefix.  An empty array
+// is not considered an error.
+func (c *MemClient) List(prefix string) ([]string, error) {
+	return c.ListCtx(context.Background(), prefix)
+}
+
+// ListCtx is List with a context.
+func (c *MemClient) ListCtx(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.livePaths(prefix), nil
+}
+
+// ListDetail returns an array of all Detailers describing all objects
+// beginning with prefix.  An empty array is not considered an error.
+func (c *MemClient) ListDetail(prefix string) ([]backend.Detailer, error) {
+	return c.ListDetailCtx(context.Background(), prefix)
+}
+
+// ListDetailCtx is ListDetail with a context.
+func (c *MemClient) ListDetailCtx(ctx context.Context, prefix string) ([]backend.Detailer, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	paths := c.livePaths(prefix)
+	detailers := make([]backend.Detailer, len(paths))
+	for i, path := range paths {
+		detailers[i] = c.objects[path].detailer(path)
+	}
+	return detailers, nil
+}
+
+// Count returns the number of non-expired objects beginning with prefix.
+// If none are found, zero is returned.
+func (c *MemClient) Count(prefix string) (int, error) {
+	return c.CountCtx(context.Background(), prefix)
+}
+
+// CountCtx is Count with a context.
+func (c *MemClient) CountCtx(ctx context.Context, prefix string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := time.Now()
+	count := 0
+	for path, obj := range c.objects {
+		if strings.HasPrefix(path, prefix) && !obj.expired(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountAll returns the total number of non-expired objects.
+func (c *MemClient) CountAll() (int, error) {
+	return c.CountAllCtx(context.Background())
+}
+
+// CountAllCtx is CountAll with a context.
+func (c *MemClient) CountAllCtx(ctx context.Context) (int, error) {
+	return c.CountCtx(ctx, "")
+}
+
+// Purge deletes expired items from memory.  Returns the number of objects
+// deleted.
+func (c *MemClient) Purge() (int, error) {
+	return c.PurgeCtx(context.Background())
+}
+
+// PurgeCtx is Purge with a context.
+func (c *MemClient) PurgeCtx(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := time.Now()
+	purged := 0
+	for path, obj := range c.objects {
+		if obj.expired(now) {
+			delete(c.objects, path)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Shutdown calls Purge if PurgeOnShutdown is true.
+//
+// There is not much point in purging memory that is about to go away, but
+// this keeps the behavior in line with the other backends.
+func (c *MemClient) Shutdown() error {
+	return c.ShutdownCtx(context.Background())
+}
+
+// ShutdownCtx is Shutdown with a context.
+func (c *MemClient) ShutdownCtx(ctx context.Context) error {
+	if c.PurgeOnShutdown {
+		_, err := c.PurgeCtx(ctx)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// memclient_suite_test.go -- test suite rigging
+
+package memclient_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/biztos/jsobs/memclient"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MemClientTestSuite struct {
+	suite.Suite
+	Client *memclient.MemClient
+}
+
+// Memory is cheap, so use a new client for every test.
+func (suite *MemClientTestSuite) SetupTest() {
+	suite.Client = memclient.New()
+}
+
+// see below...
+type SaveSetResult struct {
+	Paths    []string
+	PathData map[string][]byte
+}
+
+// We need sets of data a lot, with expiry in future or not at all.
+func (suite *MemClientTestSuite) SaveSet(count int, pfmt string, exp *time.Time) *SaveSetResult {
+
+	require := suite.Require() // bail out on err
+
+	paths := make([]string, count)
+	path_data := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		path := fmt.Sprintf(pfmt, i)
+		data := []byte(fmt.Sprintf(`{"n":%d}`, i))
+		paths[i] = path
+		path_data[path] = data
+		var err error
+		if exp == nil {
+			err = suite.Client.SaveRaw(path, data)
+		} else {
+			err = suite.Client.SaveRawExpiry(path, data, *exp)
+		}
+
+		require.NoError(err, "save error")
+
+	}
+	return &SaveSetResult{
+		Paths:    paths,
+		PathData: path_data,
+	}
+
+}
+
+// The actual runner func:
+func TestMemClientTestSuite(t *testing.T) {
+	suite.Run(t, new(MemClientTestSuite))
+}
@@ -0,0 +1,686 @@
+// memclient_test.go
+//
+// suite rigging is in memclient_suite_test.go, actual tests are here.
+
+package memclient_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
+	"github.com/biztos/jsobs/memclient"
+)
+
+func (suite *MemClientTestSuite) TestStringOK() {
+
+	require := suite.Require()
+
+	suite.SaveSet(3, "/any/%d", nil)
+	require.Equal("memclient (objects=3)", suite.Client.String(), "String")
+}
+
+func (suite *MemClientTestSuite) TestSaveRawFailsBadJson() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveRaw("/any", []byte("not json"))
+	require.ErrorIs(err, memclient.ErrInvalidJson)
+
+}
+
+func (suite *MemClientTestSuite) TestSaveRawExpiryFailsBadJson() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveRawExpiry("/any", []byte("not json"), time.Now())
+	require.ErrorIs(err, memclient.ErrInvalidJson)
+
+}
+
+func (suite *MemClientTestSuite) TestLoadRawFailsNotFound() {
+
+	require := suite.Require()
+
+	data, err := suite.Client.LoadRaw("/not/here")
+	require.ErrorIs(err, memclient.ErrNotFound)
+	require.ErrorIs(err, backend.ErrNotFound)
+	require.NotErrorIs(err, backend.ErrExpired)
+	require.Nil(data)
+
+}
+
+func (suite *MemClientTestSuite) TestLoadRawFailsExpired() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/expired/%d", &past)
+
+	data, err := suite.Client.LoadRaw("/expired/0")
+	require.ErrorIs(err, memclient.ErrNotFound)
+	require.ErrorIs(err, backend.ErrExpired)
+	require.Nil(data)
+
+	detail, err := suite.Client.LoadDetail("/expired/0")
+	require.ErrorIs(err, backend.ErrExpired)
+	require.Nil(detail)
+
+}
+
+func (suite *MemClientTestSuite) TestSaveRawLoadRawOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	err := suite.Client.SaveRaw("/any/thing.json", data)
+	require.NoError(err)
+
+	// Changing our copy must not change the stored one.
+	data[0] = '['
+
+	fetched, err := suite.Client.LoadRaw("/any/thing.json")
+	require.NoError(err)
+	require.EqualValues([]byte(`{"json": true}`), fetched)
+
+}
+
+func (suite *MemClientTestSuite) TestSaveRawExpiryLoadRawOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	err := suite.Client.SaveRawExpiry("/any/thing.json", data,
+		time.Now().Add(time.Hour))
+	require.NoError(err)
+
+	fetched, err := suite.Client.LoadRaw("/any/thing.json")
+	require.NoError(err)
+	require.EqualValues(data, fetched)
+
+}
+
+func (suite *MemClientTestSuite) TestLoadDetailFailsNotFound() {
+
+	require := suite.Require()
+	detail, err := suite.Client.LoadDetail("/nopers.json")
+	require.ErrorIs(err, memclient.ErrNotFound, "not found")
+	require.Nil(detail, "detail returned")
+}
+
+func (suite *MemClientTestSuite) TestLoadDetailWithExpiryOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+
+	suite.SaveSet(1, "/detail/%d", &future)
+
+	detail, err := suite.Client.LoadDetail("/detail/0")
+	require.NoError(err, "load detail")
+
+	require.Equal("/detail/0", detail.Path(), "path")
+	require.Equal(7, detail.Size(), "size")
+	require.True(detail.Expires(), "expires")
+	require.WithinDuration(future, detail.Expiry(), time.Second, "expiry")
+	require.Less(time.Since(detail.Modified()), time.Second,
+		"modified not too old")
+
+}
+
+func (suite *MemClientTestSuite) TestLoadDetailWithoutExpiryOK() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/detail/%d", nil)
+
+	detail, err := suite.Client.LoadDetail("/detail/0")
+	require.NoError(err, "load detail")
+
+	require.Equal("/detail/0", detail.Path(), "path")
+	require.Equal(7, detail.Size(), "size")
+	require.False(detail.Expires(), "expires")
+	require.True(detail.Expiry().IsZero(), "expiry is zero time")
+
+}
+
+func (suite *MemClientTestSuite) TestCountingOK() {
+
+	require := suite.Require()
+
+	precount, err := suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(0, precount, "nothing there yet")
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(15, "/foo/%d", nil)
+	suite.SaveSet(5, "/foo/expired/%d", &past)
+	suite.SaveSet(5, "/bar/%d", nil)
+
+	count, err := suite.Client.Count("/foo")
+	require.NoError(err)
+	require.Equal(15, count, "prefix count")
+
+	count, err = suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(20, count, "full count")
+
+}
+
+func (suite *MemClientTestSuite) TestListOK() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	saved := suite.SaveSet(15, "/any/thing/%02d.json", nil)
+	suite.SaveSet(5, "/any/thing/expired/%02d.json", &past)
+
+	paths, err := suite.Client.List("/any/thing")
+	require.NoError(err)
+	require.EqualValues(saved.Paths, paths, "paths")
+
+}
+
+func (suite *MemClientTestSuite) TestListEmptyOK() {
+
+	require := suite.Require()
+	paths, err := suite.Client.List("/no/such/stuff")
+	require.NoError(err)
+	require.EqualValues([]string{}, paths, "empty path list")
+
+}
+
+func (suite *MemClientTestSuite) TestListDetailEmptyOK() {
+
+	require := suite.Require()
+	detailers, err := suite.Client.ListDetail("/")
+	require.NoError(err)
+	require.EqualValues([]backend.Detailer{}, detailers, "empty list")
+}
+
+func (suite *MemClientTestSuite) TestListDetailsOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+	suite.SaveSet(3, "/detail/exp/%d", &future)
+	suite.SaveSet(3, "/detail/noexp/%d", nil)
+
+	detailers, err := suite.Client.ListDetail("/detail/")
+	require.NoError(err)
+	require.Equal(6, len(detailers), "detailers returned")
+
+	// They will have come back in path order.
+	for i, d := range detailers {
+		if i < 3 {
+			require.Equal(fmt.Sprintf("/detail/exp/%d", i), d.Path(), "path %d", i)
+			require.True(d.Expires(), "expires %d", i)
+			require.WithinDuration(future, d.Expiry(), time.Second, "expiry %d", i)
+
+		} else {
+			require.Equal(fmt.Sprintf("/detail/noexp/%d", i-3), d.Path(), "path %d", i)
+			require.False(d.Expires(), "expires %d", i)
+			require.True(d.Expiry().IsZero(), "expiry is zero time %d", i)
+
+		}
+	}
+
+}
+
+func (suite *MemClientTestSuite) TestDeleteFailsNotFound() {
+
+	require := suite.Require()
+
+	err := suite.Client.Delete("/not/there")
+	require.ErrorIs(err, memclient.ErrNotFound)
+}
+
+func (suite *MemClientTestSuite) TestDeleteOK() {
+
+	require := suite.Require()
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/any/thing/%d.json", nil)
+	suite.SaveSet(1, "/any/expired/%d.json", &past)
+
+	require.NoError(suite.Client.Delete("/any/thing/0.json"))
+	require.NoError(suite.Client.Delete("/any/expired/0.json"), "expired")
+	require.Equal("memclient (objects=0)", suite.Client.String())
+
+}
+
+func (suite *MemClientTestSuite) TestPurgeOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-1 * time.Hour)
+	keep_a := suite.SaveSet(5, "/keep/a/%02d.json", &future)
+	suite.SaveSet(5, "/purge/%02d.json", &past)
+	keep_b := suite.SaveSet(5, "/keep/b/%02d.json", nil)
+
+	require.Equal("memclient (objects=15)", suite.Client.String())
+
+	purged, err := suite.Client.Purge()
+	require.NoError(err, "purge")
+	require.Equal(5, purged, "purge count")
+
+	require.Equal("memclient (objects=10)", suite.Client.String())
+
+	keep_paths := make([]string, 0, len(keep_a.Paths)+len(keep_b.Paths))
+	keep_paths = append(keep_paths, keep_a.Paths...)
+	keep_paths = append(keep_paths, keep_b.Paths...)
+
+	paths, err := suite.Client.List("/keep/")
+	require.NoError(err)
+	require.EqualValues(keep_paths, paths, "path list")
+
+}
+
+func (suite *MemClientTestSuite) TestShutdownOK() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(50, "/purge/%02d.json", &past)
+
+	suite.Client.PurgeOnShutdown = false
+	require.NoError(suite.Client.Shutdown(), "shutdown without purge")
+	require.Equal("memclient (objects=50)", suite.Client.String())
+
+	suite.Client.PurgeOnShutdown = true
+	require.NoError(suite.Client.Shutdown(), "shutdown with purge")
+	require.Equal("memclient (objects=0)", suite.Client.String())
+
+}
+
+func (suite *MemClientTestSuite) TestConcurrentUseOK() {
+
+	require := suite.Require()
+
+	// Mostly useful with -race, but also proves nothing gets lost.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/conc/%02d", i)
+			suite.Client.SaveRaw(path, []byte(`{}`))
+			suite.Client.LoadRaw(path)
+			suite.Client.List("/conc/")
+		}(i)
+	}
+	wg.Wait()
+
+	count, err := suite.Client.Count("/conc/")
+	require.NoError(err)
+	require.Equal(20, count)
+
+}
+
+func (suite *MemClientTestSuite) TestImplementsContextBackendClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.ContextBackendClient)(nil), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestContextCanceledFails() {
+
+	require := suite.Require()
+
+	suite.SaveSet(3, "/ctx/%d", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(suite.Client.SaveRawCtx(ctx, "/ctx/x", []byte("{}")),
+		context.Canceled, "SaveRawCtx")
+	require.ErrorIs(suite.Client.SaveRawExpiryCtx(ctx, "/ctx/x", []byte("{}"),
+		time.Now()), context.Canceled, "SaveRawExpiryCtx")
+	_, err := suite.Client.LoadRawCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadRawCtx")
+	_, err = suite.Client.LoadDetailCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadDetailCtx")
+	require.ErrorIs(suite.Client.DeleteCtx(ctx, "/ctx/0"), context.Canceled,
+		"DeleteCtx")
+	_, err = suite.Client.ListCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListCtx")
+	_, err = suite.Client.ListDetailCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListDetailCtx")
+	_, err = suite.Client.CountCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "CountCtx")
+	_, err = suite.Client.CountAllCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "CountAllCtx")
+	_, err = suite.Client.PurgeCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "PurgeCtx")
+	require.ErrorIs(suite.Client.ShutdownCtx(ctx), context.Canceled,
+		"ShutdownCtx")
+
+	// Nothing happened.
+	paths, err := suite.Client.List("/ctx/")
+	require.NoError(err)
+	require.Equal([]string{"/ctx/0", "/ctx/1", "/ctx/2"}, paths)
+
+}
+
+func (suite *MemClientTestSuite) TestImplementsVersioner() {
+
+	require := suite.Require()
+	require.Implements((*backend.Versioner)(nil), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestVersionsOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+
+	_, _, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.ErrorIs(err, backend.ErrNotFound, "not yet saved")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "update missing")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 0)
+	require.NoError(err, "create")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 0)
+	require.ErrorIs(err, backend.ErrConflict, "create existing")
+
+	data, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+	require.EqualValues(1, version)
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 1)
+	require.NoError(err, "update")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "stale update")
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":4}`)), "plain save")
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(3, detail.Version(), "detail version")
+
+	details, err := suite.Client.ListDetail("/ver/")
+	require.NoError(err)
+	require.Len(details, 1)
+	require.EqualValues(3, details[0].Version(), "list detail version")
+
+	data, version, err = suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":4}`, string(data))
+	require.EqualValues(3, version)
+
+}
+
+func (suite *MemClientTestSuite) TestVersionsExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":1}`), past))
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":2}`), past))
+
+	err := suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 2)
+	require.ErrorIs(err, backend.ErrConflict, "update expired")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), &future, 0)
+	require.NoError(err, "replace expired")
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(1, detail.Version(), "starts over")
+	require.True(detail.Expires(), "expiry saved")
+	require.WithinDuration(future, detail.Expiry(), time.Second)
+
+}
+
+func (suite *MemClientTestSuite) TestSaveIfVersionConcurrentOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/conc"
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":0}`)))
+
+	// Everybody read version 1, so only one of them may win.
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf(`{"n":%d}`, i+1))
+			errs[i] = suite.Client.SaveRawIfVersionCtx(ctx, path, data, nil, 1)
+		}(i)
+	}
+	wg.Wait()
+
+	wins := 0
+	for _, err := range errs {
+		if err == nil {
+			wins++
+		} else {
+			require.ErrorIs(err, backend.ErrConflict)
+		}
+	}
+	require.Equal(1, wins, "one winner")
+
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.EqualValues(2, version)
+
+}
+
+func (suite *MemClientTestSuite) TestImplementsMetaClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.MetaClient)(nil), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestSaveRawMetaOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	meta := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	exp := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	meta["Owner"] = "changed" // not kept by reference
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Equal(exp, detail.Meta())
+	require.False(detail.Expires())
+
+	// Other saves keep it.
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, version)
+	require.NoError(err)
+	detailers, err := suite.Client.ListDetail("/meta/")
+	require.NoError(err)
+	require.Len(detailers, 1)
+	require.Equal(exp, detailers[0].Meta(), "kept")
+
+	// Saving with nil removes it.
+	err = suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":4}`), nil, nil)
+	require.NoError(err)
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta())
+
+}
+
+func (suite *MemClientTestSuite) TestSaveRawMetaExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	past := time.Now().Add(-time.Hour)
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), &past,
+		map[string]string{"k": "v"})
+	require.NoError(err)
+	_, err = suite.Client.LoadDetail(path)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta(), "not kept from expired object")
+
+}
+
+func (suite *MemClientTestSuite) TestImplementsExpirer() {
+
+	require := suite.Require()
+	require.Implements((*backend.Expirer)(nil), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestSetExpiryOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/expiry/x"
+	meta := map[string]string{"owner": "bob"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	before, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+
+	expiry := time.Now().Add(time.Hour).Round(time.Millisecond)
+	require.NoError(suite.Client.SetExpiryCtx(ctx, path, &expiry))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.True(expiry.Equal(detail.Expiry()), "expiry set")
+	require.Equal(before.Version(), detail.Version(), "version unchanged")
+	require.True(before.Modified().Equal(detail.Modified()), "modified unchanged")
+	require.Equal(meta, detail.Meta(), "meta unchanged")
+	data, err := suite.Client.LoadRaw(path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+
+	require.NoError(suite.Client.SetExpiryCtx(ctx, path, nil))
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.False(detail.Expires(), "expiry cleared")
+
+}
+
+func (suite *MemClientTestSuite) TestSetExpiryFailsNotFound() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+	err := suite.Client.SetExpiryCtx(ctx, "/expiry/none", &expiry)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	past := time.Now().Add(-time.Hour)
+	require.NoError(suite.Client.SaveRawExpiry("/expiry/old", []byte(`1`), past))
+	err = suite.Client.SetExpiryCtx(ctx, "/expiry/old", &expiry)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+}
+
+func (suite *MemClientTestSuite) TestImplementsMover() {
+
+	require := suite.Require()
+	require.Implements((*backend.Mover)(nil), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestCopyOK() {
+	backendtest.CopyOK(suite.Require(), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestCopyExistingOK() {
+	backendtest.CopyExistingOK(suite.Require(), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestCopyFails() {
+	backendtest.CopyFails(suite.Require(), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestMoveOK() {
+	backendtest.MoveOK(suite.Require(), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestMovePrefixOK() {
+	backendtest.MovePrefixOK(suite.Require(), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestMovePrefixFailsOverlap() {
+	backendtest.MovePrefixFailsOverlap(suite.Require(), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestImplementsPrefixDeleter() {
+
+	require := suite.Require()
+	require.Implements((*backend.PrefixDeleter)(nil), suite.Client)
+}
+
+func (suite *MemClientTestSuite) TestDeletePrefixOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(3, "/del/thing/%d.json", nil)
+	suite.SaveSet(2, "/del/expired/%d.json", &past)
+	suite.SaveSet(2, "/kept/%d.json", nil)
+
+	deleted, err := suite.Client.DeletePrefixCtx(ctx, "/del/")
+	require.NoError(err)
+	require.Equal(5, deleted, "expired too")
+	count, err := suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(2, count)
+
+	deleted, err = suite.Client.DeletePrefixCtx(ctx, "/del/")
+	require.NoError(err)
+	require.Equal(0, deleted)
+
+}
+
+func (suite *MemClientTestSuite) TestDeletePrefixFailsEmpty() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/any/%d.json", nil)
+	_, err := suite.Client.DeletePrefixCtx(context.Background(), "")
+	require.ErrorIs(err, backend.ErrInvalidPath)
+	require.Equal("memclient (objects=1)", suite.Client.String())
+
+}
+
+func (suite *MemClientTestSuite) TestImplementsBinaryClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.BinaryClient)(nil), suite.Client)
+	require.False(suite.Client.StoresBinary())
+}
+
+func (suite *MemClientTestSuite) TestSaveRawBinaryOK() {
+
+	require := suite.Require()
+
+	suite.Client.Binary = true
+	require.True(suite.Client.StoresBinary())
+	require.NoError(suite.Client.SaveRaw("/any", []byte("not json")))
+	b, err := suite.Client.LoadRaw("/any")
+	require.NoError(err)
+	require.Equal([]byte("not json"), b)
+
+}
@@ -0,0 +1,99 @@
+// meta.go -- user-defined metadata.
+
+package jsobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// SaveWithMeta marshals obj to json and stores it at path with no expiry,
+// and with meta as its metadata, available from Detailer.Meta.  A nil meta
+// removes any metadata.  Other saves, including patches, keep the metadata
+// the object already has.
+//
+// Backends not implementing backend.MetaClient return ErrUnsupported.
+func (c *Client) SaveWithMeta(path string, obj any, meta map[string]string) error {
+	return c.SaveWithMetaCtx(context.Background(), path, obj, meta)
+}
+
+// SaveWithMetaCtx is SaveWithMeta with a context.
+func (c *Client) SaveWithMetaCtx(ctx context.Context, path string, obj any, meta map[string]string) error {
+	return c.saveMeta(ctx, path, obj, nil, meta)
+}
+
+// SaveExpiryWithMeta is SaveWithMeta with expiry set.
+func (c *Client) SaveExpiryWithMeta(path string, obj any, expiry time.Time, meta map[string]string) error {
+	return c.SaveExpiryWithMetaCtx(context.Background(), path, obj, expiry, meta)
+}
+
+// SaveExpiryWithMetaCtx is SaveExpiryWithMeta with a context.
+func (c *Client) SaveExpiryWithMetaCtx(ctx context.Context, path string, obj any, expiry time.Time, meta map[string]string) error {
+	return c.saveMeta(ctx, path, obj, &expiry, meta)
+}
+
+func (c *Client) saveMeta(ctx context.Context, path string, obj any, expiry *time.Time, meta map[string]string) error {
+
+	mc, ok := c.Backend.(backend.MetaClient)
+	if !ok {
+		return fmt.Errorf("%w: %s has no metadata", ErrUnsupported, c.Backend)
+	}
+	b, err := c.marshal(obj)
+	if err != nil {
+		return err
+	}
+	return mc.SaveRawMetaCtx(ctx, path, b, expiry, meta)
+
+}
+
+// ListDetailMeta is ListDetail for only those objects having all the keys
+// in meta with the same values.  An empty meta matches every object.
+//
+// If the Backend implements backend.MetaLister it does the filtering.
+// Otherwise the whole listing is read and filtered here.
+func (c *Client) ListDetailMeta(prefix string, meta map[string]string) ([]backend.Detailer, error) {
+	return c.ListDetailMetaCtx(context.Background(), prefix, meta)
+}
+
+// ListDetailMetaCtx is ListDetailMeta with a context.
+func (c *Client) ListDetailMetaCtx(ctx context.Context, prefix string, meta map[string]string) ([]backend.Detailer, error) {
+
+	if ml, ok := c.Backend.(backend.MetaLister); ok {
+		return ml.ListDetailMetaCtx(ctx, prefix, meta)
+	}
+	detailers, err := c.ListDetailCtx(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	matched := []backend.Detailer{}
+	for _, d := range detailers {
+		if backend.MatchMeta(d.Meta(), meta) {
+			matched = append(matched, d)
+		}
+	}
+	return matched, nil
+
+}
+
+// CountMeta is Count for only those objects having all the keys in meta
+// with the same values, as for ListDetailMeta.
+func (c *Client) CountMeta(prefix string, meta map[string]string) (int, error) {
+	return c.CountMetaCtx(context.Background(), prefix, meta)
+}
+
+// CountMetaCtx is CountMeta with a context.
+func (c *Client) CountMetaCtx(ctx context.Context, prefix string, meta map[string]string) (int, error) {
+
+	if ml, ok := c.Backend.(backend.MetaLister); ok {
+		return ml.CountMetaCtx(ctx, prefix, meta)
+	}
+	detailers, err := c.ListDetailMetaCtx(ctx, prefix, meta)
+	if err != nil {
+		return 0, err
+	}
+	return len(detailers), nil
+
+}
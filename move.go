@@ -0,0 +1,176 @@
+// move.go -- copying and moving objects.
+
+package jsobs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// Copy copies the live object at src to dst, keeping its expiry and
+// metadata.  If there is no live object at src the error is ErrNotFound.
+// If there is one at dst, overwrite decides what happens: OverwriteNever
+// returns ErrConflict, OverwriteAlways replaces it and OverwriteSkip leaves
+// it in place without error.
+//
+// If the Backend implements backend.Mover the copy is made atomically by
+// the backend.  Otherwise the object is loaded and saved again in separate
+// steps, and metadata is only kept by backends implementing
+// backend.MetaClient.
+func (c *Client) Copy(src string, dst string, overwrite Overwrite) error {
+	return c.CopyCtx(context.Background(), src, dst, overwrite)
+}
+
+// CopyCtx is Copy with a context.
+func (c *Client) CopyCtx(ctx context.Context, src string, dst string, overwrite Overwrite) error {
+
+	if m, ok := c.Backend.(backend.Mover); ok {
+		return m.CopyCtx(ctx, src, dst, overwrite)
+	}
+	if err := backend.CheckMove(src, dst); err != nil {
+		return err
+	}
+	_, err := c.copyObject(ctx, src, dst, overwrite)
+	return err
+
+}
+
+// Move moves the live object at src to dst, as for Copy followed by Delete.
+// If overwrite is OverwriteSkip and there is a live object at dst, the
+// object at src is not deleted.
+//
+// As for Copy, the move is atomic only if the Backend implements
+// backend.Mover.  The object at src is deleted outright, even if the backend
+// supports soft deletion.
+func (c *Client) Move(src string, dst string, overwrite Overwrite) error {
+	return c.MoveCtx(context.Background(), src, dst, overwrite)
+}
+
+// MoveCtx is Move with a context.
+func (c *Client) MoveCtx(ctx context.Context, src string, dst string, overwrite Overwrite) error {
+
+	if m, ok := c.Backend.(backend.Mover); ok {
+		return m.MoveCtx(ctx, src, dst, overwrite)
+	}
+	if err := backend.CheckMove(src, dst); err != nil {
+		return err
+	}
+	_, err := c.moveObject(ctx, src, dst, overwrite)
+	return err
+
+}
+
+// MovePrefix moves every live object whose path begins with src_prefix to
+// the same path beginning with dst_prefix instead, e.g. /staging/a/b to
+// /archive/a/b, and returns the number of objects moved.  Overlapping
+// prefixes are an ErrInvalidPath.
+//
+// With OverwriteNever nothing is moved if any object already exists at its
+// destination, and the error is ErrConflict.  With OverwriteSkip those
+// objects are left in place.
+//
+// If the Backend implements backend.Mover the whole move is atomic.
+// Otherwise the objects are moved one at a time as for Move, after
+// checking all the destinations for OverwriteNever.
+func (c *Client) MovePrefix(src_prefix string, dst_prefix string, overwrite Overwrite) (int, error) {
+	return c.MovePrefixCtx(context.Background(), src_prefix, dst_prefix, overwrite)
+}
+
+// MovePrefixCtx is MovePrefix with a context.
+func (c *Client) MovePrefixCtx(ctx context.Context, src_prefix string, dst_prefix string, overwrite Overwrite) (int, error) {
+
+	if m, ok := c.Backend.(backend.Mover); ok {
+		return m.MovePrefixCtx(ctx, src_prefix, dst_prefix, overwrite)
+	}
+	if err := backend.CheckMovePrefix(src_prefix, dst_prefix); err != nil {
+		return 0, err
+	}
+	paths, err := c.ListCtx(ctx, src_prefix)
+	if err != nil {
+		return 0, err
+	}
+	dsts := make([]string, len(paths))
+	for i, path := range paths {
+		dsts[i] = dst_prefix + strings.TrimPrefix(path, src_prefix)
+		if overwrite != OverwriteNever {
+			continue
+		}
+		if _, err := c.LoadDetailCtx(ctx, dsts[i]); err == nil {
+			return 0, fmt.Errorf("%w: %s exists", ErrConflict, dsts[i])
+		} else if !IsNotFound(err) {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for i, path := range paths {
+		moved, err := c.moveObject(ctx, path, dsts[i], overwrite)
+		if IsNotFound(err) {
+			continue // expired or deleted since listing
+		}
+		if err != nil {
+			return count, err
+		}
+		if moved {
+			count++
+		}
+	}
+	return count, nil
+
+}
+
+// copyObject copies src to dst without a backend.Mover, returning false if
+// it was skipped.
+func (c *Client) copyObject(ctx context.Context, src string, dst string, overwrite Overwrite) (bool, error) {
+
+	raw_obj, err := c.LoadRawCtx(ctx, src)
+	if err != nil {
+		return false, err
+	}
+	detail, err := c.LoadDetailCtx(ctx, src)
+	if err != nil {
+		return false, err
+	}
+	if overwrite != OverwriteAlways {
+		_, err := c.LoadDetailCtx(ctx, dst)
+		if err == nil {
+			if overwrite == OverwriteNever {
+				return false, fmt.Errorf("%w: %s exists", ErrConflict, dst)
+			}
+			return false, nil
+		}
+		if !IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	var expiry *time.Time
+	if detail.Expires() {
+		e := detail.Expiry()
+		expiry = &e
+	}
+	if mc, ok := c.Backend.(backend.MetaClient); ok {
+		return true, mc.SaveRawMetaCtx(ctx, dst, raw_obj, expiry, detail.Meta())
+	}
+	if expiry != nil {
+		return true, c.SaveRawExpiryCtx(ctx, dst, raw_obj, *expiry)
+	}
+	return true, c.SaveRawCtx(ctx, dst, raw_obj)
+
+}
+
+// moveObject copies src to dst without a backend.Mover and deletes src if
+// it was copied.
+func (c *Client) moveObject(ctx context.Context, src string, dst string, overwrite Overwrite) (bool, error) {
+
+	moved, err := c.copyObject(ctx, src, dst, overwrite)
+	if err != nil || !moved {
+		return false, err
+	}
+	return true, c.DeleteCtx(ctx, src)
+
+}
@@ -0,0 +1,119 @@
+// patch.go -- partial updates with JSON patches.
+
+package jsobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// UpdateRetries is the number of times MergePatch and Patch try again after
+// a conflict, for backends that do not implement backend.Updater.
+var UpdateRetries = 10
+
+// PatchOp is a single RFC 6902 JSON Patch operation.  Value is ignored by
+// operations that do not use it.
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value"`
+}
+
+// MergePatch marshals patch to json and applies it to the object at path as
+// an RFC 7396 JSON Merge Patch, keeping the object's expiry.  Members set to
+// nil in the patch are removed from the object.
+//
+// If the Backend implements backend.Updater it makes the update atomic.
+// Otherwise the object is loaded and saved with SaveRawIfVersion, starting
+// over on conflict up to UpdateRetries times, and backends not implementing
+// backend.Versioner return ErrUnsupported.
+//
+// If the patch can not be applied ErrPatchFailed is returned.
+func (c *Client) MergePatch(path string, patch any) error {
+	return c.MergePatchCtx(context.Background(), path, patch)
+}
+
+// MergePatchCtx is MergePatch with a context.
+func (c *Client) MergePatchCtx(ctx context.Context, path string, patch any) error {
+
+	raw_patch, err := c.marshal(patch)
+	if err != nil {
+		return err
+	}
+	return c.update(ctx, path, func(raw_obj []byte) ([]byte, error) {
+		return backend.MergePatch(raw_obj, raw_patch)
+	})
+
+}
+
+// Patch applies ops to the object at path as an RFC 6902 JSON Patch,
+// keeping the object's expiry.  Either all of the operations are applied or
+// none are, so a failing "test" operation leaves the object as it was.
+//
+// The update is made as described for MergePatch.
+func (c *Client) Patch(path string, ops []PatchOp) error {
+	return c.PatchCtx(context.Background(), path, ops)
+}
+
+// PatchCtx is Patch with a context.
+func (c *Client) PatchCtx(ctx context.Context, path string, ops []PatchOp) error {
+
+	raw_patch, err := c.marshal(ops)
+	if err != nil {
+		return err
+	}
+	return c.update(ctx, path, func(raw_obj []byte) ([]byte, error) {
+		return backend.ApplyPatch(raw_obj, raw_patch)
+	})
+
+}
+
+// update replaces the object at path with the result of fn, atomically if
+// the Backend can or optimistically if it has versions.
+func (c *Client) update(ctx context.Context, path string, fn func([]byte) ([]byte, error)) error {
+
+	if u, ok := c.Backend.(backend.Updater); ok {
+		return u.UpdateRawCtx(ctx, path, fn)
+	}
+	v, err := c.versioner()
+	if err != nil {
+		return err
+	}
+
+	for tries := 0; ; tries++ {
+		raw_obj, version, err := v.LoadRawVersionCtx(ctx, path)
+		if err != nil {
+			return err
+		}
+		// The detail is only for the expiry, and must be of the same version.
+		detail, err := c.LoadDetailCtx(ctx, path)
+		if err != nil {
+			return err
+		}
+		if detail.Version() == version {
+			patched, err := fn(raw_obj)
+			if err != nil {
+				return err
+			}
+			var expiry *time.Time
+			if detail.Expires() {
+				t := detail.Expiry()
+				expiry = &t
+			}
+			err = v.SaveRawIfVersionCtx(ctx, path, patched, expiry, version)
+			if !errors.Is(err, ErrConflict) {
+				return err
+			}
+		}
+		if tries >= UpdateRetries {
+			return fmt.Errorf("%w: %s changed during %d tries", ErrConflict,
+				path, tries+1)
+		}
+	}
+
+}
@@ -5,6 +5,25 @@ CREATE TABLE obj_store (
 	data JSONB NOT NULL,
 	size INT NOT NULL,
 	expiry TIMESTAMP WITH TIME ZONE NULL,
-	modified TIMESTAMP WITH TIME ZONE NOT NULL
+	modified TIMESTAMP WITH TIME ZONE NOT NULL,
+	version BIGINT NOT NULL DEFAULT 1,
+	meta JSONB NULL
 );
-CREATE INDEX obj_store_expiry_idx ON obj_store USING btree (expiry) ;
\ No newline at end of file
+CREATE INDEX obj_store_expiry_idx ON obj_store USING btree (expiry) ;
+
+-- Tables created before versions or metadata were added can be upgraded in
+-- place:
+-- ALTER TABLE obj_store ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+-- ALTER TABLE obj_store ADD COLUMN meta JSONB NULL;
+
+-- For a client with Binary set, e.g. under compressclient, the data column
+-- (also in the history and trash tables) is BYTEA instead:
+-- data BYTEA NOT NULL,
+
+-- Optional, for searching by content with FindContains and FindPath:
+-- CREATE INDEX obj_store_data_idx ON obj_store USING GIN (data jsonb_path_ops);
+
+-- Optional, for keeping prior revisions: see PgClient.HistorySchema for the
+-- history table and trigger.
+
+-- Optional, for soft deletion: see PgClient.TrashSchema for the trash table.
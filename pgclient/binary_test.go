@@ -0,0 +1,131 @@
+// binary_test.go -- tests for the BYTEA storage mode.
+
+package pgclient_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/compressclient"
+	"github.com/biztos/jsobs/pgclient"
+)
+
+// binaryClient returns a Binary client on its own tables, dropped when the
+// test is done.
+func (suite *PgClientTestSuite) binaryClient() *pgclient.PgClient {
+
+	require := suite.Require()
+
+	client := pgclient.NewForPool(suite.Client.Pool)
+	client.Table = suite.Client.Table + "_bin"
+	client.Binary = true
+	suite.T().Cleanup(func() {
+		sql := fmt.Sprintf(`DROP TABLE IF EXISTS %[1]s;
+DROP TABLE IF EXISTS %[1]s_history;
+DROP TABLE IF EXISTS %[1]s_trash;
+DROP FUNCTION IF EXISTS %[1]s_history_fn;`, client.Table)
+		_, err := client.Pool.Exec(context.Background(), sql)
+		require.NoError(err, "drop binary tables")
+	})
+	require.NoError(client.CreateTable(), "create table")
+	require.NoError(client.CreateHistory(), "create history")
+	require.NoError(client.CreateTrash(), "create trash")
+	return client
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsBinaryClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.BinaryClient)(nil), suite.Client)
+	require.False(suite.Client.StoresBinary())
+}
+
+func (suite *PgClientTestSuite) TestBinarySchemaOK() {
+
+	require := suite.Require()
+
+	require.Contains(suite.Client.Schema(), "data JSONB NOT NULL")
+	client := pgclient.NewForPool(suite.Client.Pool)
+	client.Binary = true
+	require.True(client.StoresBinary())
+	require.Contains(client.Schema(), "data BYTEA NOT NULL")
+	require.Contains(client.HistorySchema(), "data BYTEA NOT NULL")
+	require.Contains(client.TrashSchema(), "data BYTEA NOT NULL")
+
+}
+
+func (suite *PgClientTestSuite) TestBinarySaveLoadOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	client := suite.binaryClient()
+	data := []byte{0x1f, 0x8b, 0, 0xff}
+	require.NoError(client.SaveRaw("/b", data))
+	require.NoError(client.SaveRaw("/b", []byte("not json")))
+
+	loaded, err := client.LoadRaw("/b")
+	require.NoError(err)
+	require.Equal([]byte("not json"), loaded)
+	old, err := client.LoadRawAtVersionCtx(ctx, "/b", 1)
+	require.NoError(err)
+	require.Equal(data, old)
+
+}
+
+func (suite *PgClientTestSuite) TestBinaryFindUnsupported() {
+
+	require := suite.Require()
+
+	client := suite.binaryClient()
+	_, err := client.FindContains("/", map[string]any{"a": 1}, nil)
+	require.ErrorIs(err, backend.ErrUnsupported)
+	_, err = client.FindPath("/", "$.a", nil, nil)
+	require.ErrorIs(err, backend.ErrUnsupported)
+	require.ErrorIs(client.CreateDataIndex(), backend.ErrUnsupported)
+
+}
+
+func (suite *PgClientTestSuite) TestBinaryCompressOK() {
+
+	require := suite.Require()
+
+	client, err := compressclient.New(suite.binaryClient())
+	require.NoError(err)
+	client.Threshold = 0
+	raw := []byte(`{"text":"` + fmt.Sprintf("%0500d", 0) + `"}`)
+	require.NoError(client.SaveRaw("/c", raw))
+
+	loaded, err := client.LoadRaw("/c")
+	require.NoError(err)
+	require.Equal(raw, loaded)
+	detail, err := client.LoadDetail("/c")
+	require.NoError(err)
+	require.Equal("gzip", detail.Meta()[compressclient.MetaKey])
+	require.Less(detail.Size(), len(raw))
+
+}
+
+func (suite *PgClientTestSuite) TestBinaryCompressIgnoresSiblingOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	client, err := compressclient.New(suite.binaryClient())
+	require.NoError(err)
+	client.Threshold = 0
+	big := []byte(`{"text":"` + fmt.Sprintf("%0500d", 0) + `"}`)
+	require.NoError(client.SaveRawMetaCtx(ctx, "/ab", big, nil,
+		map[string]string{"owner": "bob"}))
+
+	require.NoError(client.SaveRaw("/a", []byte(`1`)))
+	detail, err := client.LoadDetail("/a")
+	require.NoError(err)
+	require.Nil(detail.Meta(), "nothing from /ab")
+	data, err := client.LoadRaw("/a")
+	require.NoError(err)
+	require.Equal(`1`, string(data))
+
+}
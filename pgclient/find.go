@@ -0,0 +1,138 @@
+// find.go - querying objects by content
+//
+// These are specific to PostgreSQL and its JSONB operators, so they are not
+// part of any backend interface.  Use CreateDataIndex to make them fast.
+
+package pgclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// FindOptions control the results of FindContains and FindPath.  A nil
+// FindOptions returns all matches in path order, without data.
+type FindOptions struct {
+	Limit      int  // Maximum number of matches, if positive.
+	ByModified bool // Order by modification time instead of path.
+	Descending bool // Reverse the order.
+	WithData   bool // Include the objects' data in the matches.
+}
+
+// Match describes an object found by FindContains or FindPath.
+type Match struct {
+	PgDetailer
+	Data []byte // Only set if FindOptions.WithData was true.
+}
+
+// Decode unmarshals the Match's Data into obj.
+func (m *Match) Decode(obj any) error {
+	if m.Data == nil {
+		return fmt.Errorf("No data for %s: find with WithData", m.path)
+	}
+	if err := json.Unmarshal(m.Data, obj); err != nil {
+		return fmt.Errorf("Failed to unmarshal JSON for %s: %w", m.path, err)
+	}
+	return nil
+}
+
+// FindContains returns the objects beginning with prefix whose data
+// contains fragment, using the JSONB @> operator.  For example the fragment
+// map[string]any{"status": "open"} matches every object with that status.
+//
+// The fragment is marshaled to JSON; pass a json.RawMessage to send JSON
+// as-is.
+func (c *PgClient) FindContains(prefix string, fragment any, opts *FindOptions) ([]*Match, error) {
+	return c.FindContainsCtx(context.Background(), prefix, fragment, opts)
+}
+
+// FindContainsCtx is FindContains with a context.
+func (c *PgClient) FindContainsCtx(ctx context.Context, prefix string, fragment any, opts *FindOptions) ([]*Match, error) {
+
+	if err := c.checkJson(); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal JSON fragment: %w", err)
+	}
+	return c.find(ctx, c.findSql("data @> $2::jsonb", opts), opts, prefix, b)
+
+}
+
+// FindPath returns the objects beginning with prefix for which the SQL/JSON
+// path expression jsonpath returns any item, e.g. `$.tags[*] ? (@ == "x")`.
+//
+// If vars is not empty it is passed to jsonb_path_exists for use as
+// variables in jsonpath, e.g. `$.count ? (@ > $min)`.  Note that only
+// queries without vars can use the index from CreateDataIndex.
+func (c *PgClient) FindPath(prefix string, jsonpath string, vars map[string]any, opts *FindOptions) ([]*Match, error) {
+	return c.FindPathCtx(context.Background(), prefix, jsonpath, vars, opts)
+}
+
+// FindPathCtx is FindPath with a context.
+func (c *PgClient) FindPathCtx(ctx context.Context, prefix string, jsonpath string, vars map[string]any, opts *FindOptions) ([]*Match, error) {
+
+	if err := c.checkJson(); err != nil {
+		return nil, err
+	}
+	if len(vars) == 0 {
+		return c.find(ctx, c.findSql("data @? $2::jsonpath", opts), opts,
+			prefix, jsonpath)
+	}
+	b, err := json.Marshal(vars)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal JSON vars: %w", err)
+	}
+	cond := "jsonb_path_exists(data, $2::jsonpath, $3::jsonb)"
+	return c.find(ctx, c.findSql(cond, opts), opts, prefix, jsonpath, b)
+
+}
+
+// checkJson returns ErrUnsupported if the data is not stored as JSONB.
+func (c *PgClient) checkJson() error {
+	if c.Binary {
+		return fmt.Errorf("%w: %s stores binary data", backend.ErrUnsupported, c)
+	}
+	return nil
+}
+
+func (c *PgClient) find(ctx context.Context, query string, opts *FindOptions, args ...any) ([]*Match, error) {
+
+	with_data := opts != nil && opts.WithData
+	rows, _ := c.Pool.Query(ctx, query, args...)
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Match, error) {
+		m := &Match{}
+		dest := []any{&m.path, &m.size, &m.expiry, &m.modified, &m.version,
+			&m.meta}
+		if with_data {
+			dest = append(dest, &m.Data)
+		}
+		return m, row.Scan(dest...)
+	})
+
+}
+
+// DataIndexSchema returns the SQL required to create a GIN index on the
+// data column of this client's Table, for FindContains and FindPath.
+func (c *PgClient) DataIndexSchema() string {
+	return c.dataIndexSql()
+}
+
+// CreateDataIndex executes the SQL returned from DataIndexSchema on the
+// current database.  If the index exists nothing is done.
+//
+// Building the index on a big table takes a while and blocks writes.
+func (c *PgClient) CreateDataIndex() error {
+
+	if err := c.checkJson(); err != nil {
+		return err
+	}
+	_, err := c.Pool.Exec(context.Background(), c.DataIndexSchema())
+	return err
+}
@@ -0,0 +1,149 @@
+// find_test.go -- tests for querying by content.
+
+package pgclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/biztos/jsobs/pgclient"
+)
+
+// saveThings saves a few objects worth finding, and one expired.
+func (suite *PgClientTestSuite) saveThings() {
+
+	require := suite.Require()
+
+	things := map[string]string{
+		"/things/a": `{"kind":"box","count":1,"tags":["red"]}`,
+		"/things/b": `{"kind":"ball","count":5,"tags":["red","blue"]}`,
+		"/things/c": `{"kind":"box","count":9,"tags":["blue"]}`,
+		"/other/d":  `{"kind":"box","count":3,"tags":["red"]}`,
+	}
+	for _, path := range []string{"/things/a", "/things/b", "/things/c", "/other/d"} {
+		require.NoError(suite.Client.SaveRaw(path, []byte(things[path])))
+		time.Sleep(time.Millisecond) // distinct modification times
+	}
+	require.NoError(suite.Client.SaveRawExpiry("/things/old",
+		[]byte(`{"kind":"box","count":2,"tags":["red"]}`),
+		time.Now().Add(-time.Hour)))
+
+}
+
+func matchPaths(matches []*pgclient.Match) []string {
+	paths := make([]string, len(matches))
+	for i, m := range matches {
+		paths[i] = m.Path()
+	}
+	return paths
+}
+
+func (suite *PgClientTestSuite) TestFindContainsOK() {
+
+	require := suite.Require()
+
+	suite.saveThings()
+
+	matches, err := suite.Client.FindContains("/things/",
+		map[string]any{"kind": "box"}, nil)
+	require.NoError(err)
+	require.Equal([]string{"/things/a", "/things/c"}, matchPaths(matches))
+	require.Nil(matches[0].Data, "no data by default")
+
+	matches, err = suite.Client.FindContains("/things/",
+		json.RawMessage(`{"tags":["red"]}`),
+		&pgclient.FindOptions{Descending: true, WithData: true})
+	require.NoError(err)
+	require.Equal([]string{"/things/b", "/things/a"}, matchPaths(matches))
+
+	var thing struct {
+		Kind  string
+		Count int
+	}
+	require.NoError(matches[0].Decode(&thing))
+	require.Equal("ball", thing.Kind)
+	require.Equal(5, thing.Count)
+
+}
+
+func (suite *PgClientTestSuite) TestFindContainsNoneOK() {
+
+	require := suite.Require()
+
+	suite.saveThings()
+
+	matches, err := suite.Client.FindContains("/things/",
+		map[string]any{"kind": "cube"}, nil)
+	require.NoError(err)
+	require.Empty(matches)
+
+}
+
+func (suite *PgClientTestSuite) TestFindContainsJsonError() {
+
+	require := suite.Require()
+
+	_, err := suite.Client.FindContains("/things/", func() {}, nil)
+	require.ErrorContains(err, "Failed to marshal JSON fragment")
+
+}
+
+func (suite *PgClientTestSuite) TestFindPathOK() {
+
+	require := suite.Require()
+
+	suite.saveThings()
+
+	matches, err := suite.Client.FindPath("/things/",
+		`$.tags[*] ? (@ == "blue")`, nil, nil)
+	require.NoError(err)
+	require.Equal([]string{"/things/b", "/things/c"}, matchPaths(matches))
+
+	matches, err = suite.Client.FindPath("/",
+		`$.count ? (@ > $min)`, map[string]any{"min": 2},
+		&pgclient.FindOptions{ByModified: true, Descending: true, Limit: 2})
+	require.NoError(err)
+	require.Equal([]string{"/other/d", "/things/c"}, matchPaths(matches))
+
+}
+
+func (suite *PgClientTestSuite) TestFindPathBadPathFails() {
+
+	require := suite.Require()
+
+	_, err := suite.Client.FindPath("/things/", `$.[`, nil, nil)
+	require.Error(err)
+
+}
+
+func (suite *PgClientTestSuite) TestMatchDecodeFailsWithoutData() {
+
+	require := suite.Require()
+
+	suite.saveThings()
+
+	matches, err := suite.Client.FindContains("/things/",
+		map[string]any{"kind": "ball"}, nil)
+	require.NoError(err)
+	require.Len(matches, 1)
+	err = matches[0].Decode(&map[string]any{})
+	require.EqualError(err, "No data for /things/b: find with WithData")
+
+}
+
+func (suite *PgClientTestSuite) TestCreateDataIndexOK() {
+
+	require := suite.Require()
+
+	require.Contains(suite.Client.DataIndexSchema(), "USING GIN (data")
+	require.NoError(suite.Client.CreateDataIndex(), "create")
+	require.NoError(suite.Client.CreateDataIndex(), "create again")
+
+	suite.saveThings()
+	matches, err := suite.Client.FindContainsCtx(context.Background(),
+		"/things/", map[string]any{"count": 9}, nil)
+	require.NoError(err)
+	require.Equal([]string{"/things/c"}, matchPaths(matches))
+
+}
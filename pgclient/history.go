@@ -0,0 +1,127 @@
+// history.go - keeping prior revisions of objects
+//
+// History is kept in a second table, <Table>_history, by a trigger on the
+// Table, so it covers every write including those made by other clients.
+// It is off until CreateHistory is called, and grows until pruned, either
+// by PruneHistoryCtx or by Purge with HistoryKeep or HistoryMaxAge set.
+
+package pgclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// HistorySchema returns the SQL required to create the history table and
+// trigger for this client's Table.
+func (c *PgClient) HistorySchema() string {
+	return c.historySchemaSql()
+}
+
+// CreateHistory executes the SQL returned from HistorySchema on the current
+// database, after which every live object replaced or deleted is kept as a
+// revision.  It may be called again safely.
+func (c *PgClient) CreateHistory() error {
+
+	_, err := c.Pool.Exec(context.Background(), c.HistorySchema())
+	return err
+}
+
+// ListVersionsCtx implements backend.Historian.
+func (c *PgClient) ListVersionsCtx(ctx context.Context, path string) ([]backend.Detailer, error) {
+
+	rows, _ := c.Pool.Query(ctx, c.listVersionsSql(), path)
+	return pgx.CollectRows(rows,
+		func(row pgx.CollectableRow) (backend.Detailer, error) {
+			d := &PgDetailer{}
+			err := d.Scan(row)
+			return d, err
+		})
+
+}
+
+// LoadRawAtVersionCtx implements backend.Historian.
+func (c *PgClient) LoadRawAtVersionCtx(ctx context.Context, path string, version int64) ([]byte, error) {
+
+	row := c.Pool.QueryRow(ctx, c.loadAtVersionSql(), path, version)
+	var data []byte
+	err := row.Scan(&data)
+	return data, translate(err)
+
+}
+
+// LoadRawAsOfCtx implements backend.Historian.
+func (c *PgClient) LoadRawAsOfCtx(ctx context.Context, path string, t time.Time) ([]byte, int64, error) {
+
+	row := c.Pool.QueryRow(ctx, c.loadAsOfSql(), path, t)
+	var data []byte
+	var version int64
+	err := row.Scan(&data, &version)
+	return data, version, translate(err)
+
+}
+
+// PruneHistoryCtx implements backend.Historian.  Call it periodically, or set
+// HistoryKeep and HistoryMaxAge to have Purge call it, to bound the size of
+// the history.
+func (c *PgClient) PruneHistoryCtx(ctx context.Context, keep int, max_age time.Duration) (int, error) {
+
+	if err := backend.CheckPrune(keep, max_age); err != nil {
+		return 0, err
+	}
+	pruned := 0
+	if keep > 0 {
+		tag, err := c.Pool.Exec(ctx, c.pruneHistoryKeepSql(), keep)
+		if err != nil {
+			return pruned, err
+		}
+		pruned += int(tag.RowsAffected())
+	}
+	if max_age > 0 {
+		tag, err := c.Pool.Exec(ctx, c.pruneHistoryAgeSql(),
+			time.Now().Add(-max_age))
+		if err != nil {
+			return pruned, err
+		}
+		pruned += int(tag.RowsAffected())
+	}
+	return pruned, nil
+
+}
+
+// boundsHistory returns true if Purge should prune the history.
+func (c *PgClient) boundsHistory() bool {
+	return c.HistoryKeep != 0 || c.HistoryMaxAge != 0
+}
+
+// pruneHistoryBatch prunes the history to HistoryKeep and HistoryMaxAge as
+// for PruneHistoryCtx, deleting at most limit revisions.
+func (c *PgClient) pruneHistoryBatch(ctx context.Context, limit int) (int, error) {
+
+	if err := backend.CheckPrune(c.HistoryKeep, c.HistoryMaxAge); err != nil {
+		return 0, err
+	}
+	pruned := 0
+	if c.HistoryKeep > 0 {
+		tag, err := c.Pool.Exec(ctx, c.pruneHistoryKeepBatchSql(),
+			c.HistoryKeep, limit)
+		pruned += int(tag.RowsAffected())
+		if err != nil || pruned >= limit {
+			return pruned, err
+		}
+	}
+	if c.HistoryMaxAge > 0 {
+		tag, err := c.Pool.Exec(ctx, c.pruneHistoryAgeBatchSql(),
+			time.Now().Add(-c.HistoryMaxAge), limit-pruned)
+		pruned += int(tag.RowsAffected())
+		if err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+
+}
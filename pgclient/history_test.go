@@ -0,0 +1,91 @@
+// history_test.go -- tests for prior revisions of objects.
+//
+// History is created for the whole suite, so every other test also runs
+// with the triggers in place.  The tests common to all Historian backends
+// are in backendtest.
+
+package pgclient_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
+)
+
+func (suite *PgClientTestSuite) TestImplementsHistorian() {
+
+	require := suite.Require()
+	require.Implements((*backend.Historian)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestCreateHistoryAgainOK() {
+
+	require := suite.Require()
+
+	require.Contains(suite.Client.HistorySchema(), "CREATE TRIGGER")
+	require.NoError(suite.Client.CreateHistory())
+
+}
+
+func (suite *PgClientTestSuite) TestListVersionsOK() {
+	backendtest.ListVersionsOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestHistoryKeepsDeletedOK() {
+	backendtest.HistoryKeepsDeletedOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestHistorySkipsExpiredOK() {
+	backendtest.HistorySkipsExpiredOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestLoadRawAtVersionFailsNotFound() {
+	backendtest.LoadRawAtVersionFailsNotFound(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestPruneHistoryOK() {
+	backendtest.PruneHistoryOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestPruneHistoryFailsNegative() {
+	backendtest.PruneHistoryFailsNegative(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestHistoryKeepsUpdatesOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	c := suite.Client
+	require.NoError(c.SaveRaw("/h", []byte(`1`)))
+	require.NoError(c.UpdateRawCtx(ctx, "/h", func([]byte) ([]byte, error) {
+		return []byte(`22`), nil
+	}))
+
+	detailers, err := c.ListVersionsCtx(ctx, "/h")
+	require.NoError(err)
+	require.Len(detailers, 2)
+	data, err := c.LoadRawAtVersionCtx(ctx, "/h", 1)
+	require.NoError(err)
+	require.Equal(`1`, string(data))
+
+}
+
+// boundHistory sets HistoryKeep and HistoryMaxAge for the rest of the test.
+func (suite *PgClientTestSuite) boundHistory(keep int, max_age time.Duration) {
+
+	suite.Client.HistoryKeep = keep
+	suite.Client.HistoryMaxAge = max_age
+	suite.T().Cleanup(func() {
+		suite.Client.HistoryKeep = 0
+		suite.Client.HistoryMaxAge = 0
+	})
+
+}
+
+func (suite *PgClientTestSuite) TestPurgePrunesHistoryOK() {
+	backendtest.PurgePrunesHistoryOK(suite.Require(), suite.Client,
+		suite.boundHistory)
+}
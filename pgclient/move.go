@@ -0,0 +1,85 @@
+// move.go - copying and moving objects
+//
+// Each operation is a single statement, run in a transaction so that it can
+// be rolled back if an object would be overwritten.
+
+package pgclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// move runs the moveSql for cond and dst, returning the numbers of objects
+// found and copied.  With OverwriteNever nothing is changed if any object
+// was not copied.
+func (c *PgClient) move(ctx context.Context, cond string, dst string, remove bool, overwrite backend.Overwrite, src_arg string, dst_arg string) (int, int, error) {
+
+	tx, err := c.Pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := c.moveSql(cond, dst, overwrite == backend.OverwriteAlways, remove)
+	var found, copied int
+	row := tx.QueryRow(ctx, query, src_arg, dst_arg, time.Now())
+	if err := row.Scan(&found, &copied); err != nil {
+		return 0, 0, err
+	}
+	if copied < found && overwrite == backend.OverwriteNever {
+		return found, copied, backend.ErrConflict
+	}
+	return found, copied, tx.Commit(ctx)
+
+}
+
+// moveOne moves or copies a single object.
+func (c *PgClient) moveOne(ctx context.Context, src string, dst string, remove bool, overwrite backend.Overwrite) error {
+
+	if err := backend.CheckMove(src, dst); err != nil {
+		return err
+	}
+	found, _, err := c.move(ctx, "obj_path = $1", "$2::text", remove,
+		overwrite, src, dst)
+	if found == 0 && err == nil {
+		return ErrNotFound
+	}
+	if errors.Is(err, backend.ErrConflict) {
+		return fmt.Errorf("%w: %s exists", backend.ErrConflict, dst)
+	}
+	return err
+
+}
+
+// CopyCtx implements backend.Mover with a single statement.
+func (c *PgClient) CopyCtx(ctx context.Context, src string, dst string, overwrite backend.Overwrite) error {
+	return c.moveOne(ctx, src, dst, false, overwrite)
+}
+
+// MoveCtx implements backend.Mover with a single statement.  The source is
+// deleted outright even if SoftDelete is true.
+func (c *PgClient) MoveCtx(ctx context.Context, src string, dst string, overwrite backend.Overwrite) error {
+	return c.moveOne(ctx, src, dst, true, overwrite)
+}
+
+// MovePrefixCtx implements backend.Mover with a single statement.
+func (c *PgClient) MovePrefixCtx(ctx context.Context, src_prefix string, dst_prefix string, overwrite backend.Overwrite) (int, error) {
+
+	if err := backend.CheckMovePrefix(src_prefix, dst_prefix); err != nil {
+		return 0, err
+	}
+	found, copied, err := c.move(ctx, "starts_with(obj_path,$1)",
+		"$2::text || substr(obj_path,length($1)+1)", true,
+		overwrite, src_prefix, dst_prefix)
+	if errors.Is(err, backend.ErrConflict) {
+		return 0, fmt.Errorf("%w: %d of %d objects under %s exist under %s",
+			backend.ErrConflict, found-copied, found, src_prefix, dst_prefix)
+	}
+	return copied, err
+
+}
@@ -0,0 +1,74 @@
+// move_test.go -- tests for copying and moving objects.
+//
+// The tests common to all Mover backends are in backendtest.
+
+package pgclient_test
+
+import (
+	"context"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
+)
+
+func (suite *PgClientTestSuite) TestImplementsMover() {
+
+	require := suite.Require()
+	require.Implements((*backend.Mover)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestCopyOK() {
+	backendtest.CopyOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestCopyExistingOK() {
+	backendtest.CopyExistingOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestCopyFails() {
+	backendtest.CopyFails(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestMoveOK() {
+	backendtest.MoveOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestMovePrefixOK() {
+	backendtest.MovePrefixOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestMovePrefixFailsOverlap() {
+	backendtest.MovePrefixFailsOverlap(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestMoveSkipsTrashOK() {
+	suite.softDelete()
+	backendtest.MoveSkipsTrashOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestMovePrefixConflictRollsBackOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	c := suite.Client
+	require.NoError(c.SaveRaw("/staging/a", []byte(`1`)))
+	require.NoError(c.SaveRaw("/staging/b", []byte(`1`)))
+	require.NoError(c.SaveRaw("/archive/b", []byte(`2`)))
+
+	// /staging/a is copied before the conflict is seen, and must not stay.
+	moved, err := c.MovePrefixCtx(ctx, "/staging/", "/archive/",
+		backend.OverwriteNever)
+	require.ErrorIs(err, backend.ErrConflict)
+	require.Equal(0, moved)
+	paths, err := c.List("/archive/")
+	require.NoError(err)
+	require.Equal([]string{"/archive/b"}, paths)
+	versions, err := c.ListVersionsCtx(ctx, "/archive/a")
+	require.NoError(err)
+	require.Empty(versions, "no history of the copy")
+	versions, err = c.ListVersionsCtx(ctx, "/staging/a")
+	require.NoError(err)
+	require.Len(versions, 1, "source never deleted")
+
+}
@@ -15,12 +15,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/purger"
 )
 
 // Override if using multiple databases in your process.
@@ -28,7 +32,34 @@ var DatabaseUrlEnvVar = "DATABASE_URL"
 
 var DefaultTable = "obj_store"
 
-var ErrNotFound = pgx.ErrNoRows
+// DefaultTrashRetention is how long objects stay in the trash when
+// SoftDelete is set, for new clients.
+var DefaultTrashRetention = 7 * 24 * time.Hour
+
+// DefaultDeleteBatchSize is the most objects removed by each statement of
+// DeletePrefix, for new clients.
+var DefaultDeleteBatchSize = 10000
+
+// ErrNotFound is returned wrapped together with pgx.ErrNoRows, so errors.Is
+// works for either.
+var ErrNotFound = backend.ErrNotFound
+
+// ErrInvalidJson is returned wrapped together with the database error when
+// the data is rejected by the JSONB column.
+var ErrInvalidJson = backend.ErrInvalidJson
+
+// translate wraps database errors in the corresponding backend errors, and
+// returns others as they are.
+func translate(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22P02" {
+		return fmt.Errorf("%w: %w", ErrInvalidJson, err)
+	}
+	return err
+}
 
 // PgDetailer implements backend.Detailer to describe an object.
 type PgDetailer struct {
@@ -36,6 +67,8 @@ type PgDetailer struct {
 	size     int
 	expiry   *time.Time
 	modified time.Time
+	version  int64
+	meta     map[string]string
 }
 
 // Scan scans a database row.
@@ -47,6 +80,8 @@ func (d *PgDetailer) Scan(row pgx.Row) error {
 		&d.size,
 		&d.expiry,
 		&d.modified,
+		&d.version,
+		&d.meta,
 	)
 }
 
@@ -79,11 +114,57 @@ func (d *PgDetailer) Modified() time.Time {
 	return d.modified
 }
 
+// Version implements backend.Detailer.
+func (d *PgDetailer) Version() int64 {
+	return d.version
+}
+
+// Meta implements backend.Detailer.
+func (d *PgDetailer) Meta() map[string]string {
+	return d.meta
+}
+
 // PgClient is a BackendClient for PostgreSQL databases.
+//
+// If SoftDelete is true, Delete, DeleteMany and DeletePrefix move objects to
+// the trash table made by CreateTrash, and Purge also deletes the objects
+// that have been in the trash longer than TrashRetention.
+//
+// If SlidingExpiry is positive, every load of an object that has an expiry
+// renews it to at least SlidingExpiry from now, so that objects in use do
+// not expire: LoadRaw, also within a transaction, LoadRawMany and
+// LoadRawVersion.  Listing, iterating and reading history do not, as they
+// scan objects rather than use them.  Objects with no expiry are not
+// affected.
+//
+// DeletePrefix removes at most DeleteBatchSize objects per statement, each in
+// its own transaction, so that deleting a huge subtree does not hold locks
+// for long.  Zero or less deletes them all in one statement.
+//
+// If Binary is true the data column is BYTEA instead of JSONB, so that
+// wrappers such as compressclient can store data that is not JSON.  It must
+// be set before CreateTable and friends, and must match the existing tables.
+// The data is then not validated, and FindContains, FindPath and the data
+// index are not supported.
+//
+// If HistoryKeep or HistoryMaxAge is positive, Purge also prunes the history
+// table made by CreateHistory as for PruneHistoryCtx, keeping at most
+// HistoryKeep revisions per object and none older than HistoryMaxAge.  Zero
+// disables either bound.
 type PgClient struct {
 	Pool            *pgxpool.Pool
 	Table           string
 	PurgeOnShutdown bool
+	SoftDelete      bool
+	TrashRetention  time.Duration
+	SlidingExpiry   time.Duration
+	DeleteBatchSize int
+	Binary          bool
+	HistoryKeep     int
+	HistoryMaxAge   time.Duration
+
+	mutex  sync.Mutex // guards purger
+	purger *purger.Purger
 }
 
 // String returns an identifying string.
@@ -91,6 +172,11 @@ func (c *PgClient) String() string {
 	return fmt.Sprintf("pgclient (table=%s)", c.Table)
 }
 
+// StoresBinary implements backend.BinaryClient.
+func (c *PgClient) StoresBinary() bool {
+	return c.Binary
+}
+
 // New returns a new PgClient using DefaultTable and a pool from
 // DatabaseUrlEnvVar, with PurgeOnShutdown true.
 func New() (*PgClient, error) {
@@ -107,6 +193,8 @@ func New() (*PgClient, error) {
 		Pool:            pool,
 		Table:           DefaultTable,
 		PurgeOnShutdown: true,
+		TrashRetention:  DefaultTrashRetention,
+		DeleteBatchSize: DefaultDeleteBatchSize,
 	}
 	return client, nil
 }
@@ -118,35 +206,161 @@ func NewForPool(pool *pgxpool.Pool) *PgClient {
 		Pool:            pool,
 		Table:           DefaultTable,
 		PurgeOnShutdown: true,
+		TrashRetention:  DefaultTrashRetention,
+		DeleteBatchSize: DefaultDeleteBatchSize,
 	}
 }
 
 // SaveRaw saves the raw_obj to the database with no expiry.
 func (c *PgClient) SaveRaw(path string, raw_obj []byte) error {
+	return c.SaveRawCtx(context.Background(), path, raw_obj)
+}
 
-	_, err := c.Pool.Exec(context.Background(), c.saveSql(),
-		path, raw_obj, len(raw_obj), nil, time.Now())
-	return err
+// SaveRawCtx is SaveRaw with a context.
+func (c *PgClient) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	return c.save(ctx, c.Pool, path, raw_obj, nil)
+}
+
+// save saves raw_obj on db, keeping the live object's metadata.
+func (c *PgClient) save(ctx context.Context, db querier, path string, raw_obj []byte, expiry *time.Time) error {
+
+	_, err := db.Exec(ctx, c.saveSql(),
+		path, raw_obj, len(raw_obj), expiry, time.Now(), nil)
+	return translate(err)
 
 }
 
 // SaveRawExpiry saves the raw bytes to the database for availability until
 // expiry.
 func (c *PgClient) SaveRawExpiry(path string, raw_obj []byte, expiry time.Time) error {
+	return c.SaveRawExpiryCtx(context.Background(), path, raw_obj, expiry)
+}
+
+// SaveRawExpiryCtx is SaveRawExpiry with a context.
+func (c *PgClient) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	return c.save(ctx, c.Pool, path, raw_obj, &expiry)
+}
+
+// SaveRawMetaCtx implements backend.MetaClient.
+func (c *PgClient) SaveRawMetaCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, meta map[string]string) error {
+
+	if meta == nil {
+		meta = map[string]string{} // removes rather than keeps
+	}
+	_, err := c.Pool.Exec(ctx, c.saveSql(),
+		path, raw_obj, len(raw_obj), expiry, time.Now(), meta)
+	return translate(err)
 
-	_, err := c.Pool.Exec(context.Background(), c.saveSql(),
-		path, raw_obj, len(raw_obj), expiry, time.Now())
-	return err
 }
 
 // LoadRaw retrieves the object at path and returns its raw value.
 // If the object does not exist, the error returned will be ErrNotFound.
 func (c *PgClient) LoadRaw(path string) ([]byte, error) {
+	return c.LoadRawCtx(context.Background(), path)
+}
+
+// LoadRawCtx is LoadRaw with a context.
+func (c *PgClient) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+	return c.loadRaw(ctx, c.Pool, path)
+}
+
+// loadRaw loads the object at path from db, renewing its expiry if
+// SlidingExpiry is set.
+func (c *PgClient) loadRaw(ctx context.Context, db querier, path string) ([]byte, error) {
 
-	row := c.Pool.QueryRow(context.Background(), c.loadSql(), path)
+	var row pgx.Row
+	if c.SlidingExpiry > 0 {
+		row = db.QueryRow(ctx, c.loadRenewSql("data", "obj_path = $1"), path,
+			c.SlidingExpiry.Microseconds())
+	} else {
+		row = db.QueryRow(ctx, c.loadSql(), path)
+	}
 	var data []byte
 	err := row.Scan(&data)
-	return data, err
+	return data, translate(err)
+
+}
+
+// SetExpiryCtx implements backend.Expirer with a single statement.
+func (c *PgClient) SetExpiryCtx(ctx context.Context, path string, expiry *time.Time) error {
+
+	tag, err := c.Pool.Exec(ctx, c.setExpirySql(), path, expiry)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+
+}
+
+// LoadRawVersionCtx implements backend.Versioner.
+func (c *PgClient) LoadRawVersionCtx(ctx context.Context, path string) ([]byte, int64, error) {
+
+	var row pgx.Row
+	if c.SlidingExpiry > 0 {
+		row = c.Pool.QueryRow(ctx,
+			c.loadRenewSql("data,version", "obj_path = $1"), path,
+			c.SlidingExpiry.Microseconds())
+	} else {
+		row = c.Pool.QueryRow(ctx, c.loadVersionSql(), path)
+	}
+	var data []byte
+	var version int64
+	err := row.Scan(&data, &version)
+	return data, version, translate(err)
+
+}
+
+// SaveRawIfVersionCtx implements backend.Versioner.
+//
+// The check and the save are a single statement, so concurrent writers can
+// not both succeed.
+func (c *PgClient) SaveRawIfVersionCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, version int64) error {
+
+	args := []any{path, raw_obj, len(raw_obj), expiry, time.Now()}
+	query := c.saveIfNotExistsSql()
+	if version != 0 {
+		query = c.saveIfVersionSql()
+		args = append(args, version)
+	}
+	tag, err := c.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return translate(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s not at version %d", backend.ErrConflict,
+			path, version)
+	}
+	return nil
+
+}
+
+// UpdateRawCtx implements backend.Updater.  The row is locked from loading
+// until saving, so concurrent updates wait for each other rather than
+// conflicting.
+func (c *PgClient) UpdateRawCtx(ctx context.Context, path string, fn func([]byte) ([]byte, error)) error {
+
+	tx, err := c.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var data []byte
+	if err := tx.QueryRow(ctx, c.loadForUpdateSql(), path).Scan(&data); err != nil {
+		return translate(err)
+	}
+	raw_obj, err := fn(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, c.updateSql(), path, raw_obj, len(raw_obj), time.Now())
+	if err != nil {
+		return translate(err)
+	}
+	return tx.Commit(ctx)
 
 }
 
@@ -154,12 +368,17 @@ func (c *PgClient) LoadRaw(path string) ([]byte, error) {
 // a jsobs.Detailer.
 // If the object does not exist, the error returned will be ErrNotFound.
 func (c *PgClient) LoadDetail(path string) (backend.Detailer, error) {
+	return c.LoadDetailCtx(context.Background(), path)
+}
 
-	row := c.Pool.QueryRow(context.Background(), c.loadDetailSql(), path)
+// LoadDetailCtx is LoadDetail with a context.
+func (c *PgClient) LoadDetailCtx(ctx context.Context, path string) (backend.Detailer, error) {
+
+	row := c.Pool.QueryRow(ctx, c.loadDetailSql(), path)
 	detail := &PgDetailer{}
 	err := detail.Scan(row)
 	if err != nil {
-		return nil, err
+		return nil, translate(err)
 	}
 	return detail, nil
 }
@@ -171,10 +390,27 @@ func (c *PgClient) LoadDetail(path string) (backend.Detailer, error) {
 // expiry and return a different error if the caller deletes an expired
 // object, but that use-case seems silly.  You want it gone, we make it gone.)
 //
+// If SoftDelete is true the object is moved to the trash instead.
+//
 // If the object does not exist, the error returned will be ErrNotFound.
 func (c *PgClient) Delete(path string) error {
+	return c.DeleteCtx(context.Background(), path)
+}
+
+// DeleteCtx is Delete with a context.
+func (c *PgClient) DeleteCtx(ctx context.Context, path string) error {
+	return c.delete(ctx, c.Pool, path)
+}
+
+// delete deletes the object at path on db, or moves it to the trash if
+// SoftDelete is true.
+func (c *PgClient) delete(ctx context.Context, db querier, path string) error {
 
-	tag, err := c.Pool.Exec(context.Background(), c.deleteSql(), path)
+	query := c.deleteSql()
+	if c.SoftDelete {
+		query = c.trashSql("obj_path = $1")
+	}
+	tag, err := db.Exec(ctx, query, path)
 	if err != nil {
 		return err
 	}
@@ -184,13 +420,118 @@ func (c *PgClient) Delete(path string) error {
 	return nil
 }
 
+// SaveRawManyCtx implements backend.BatchClient.  All the saves are sent in
+// one batch, which runs as a single transaction: if any fails, none are
+// saved.
+func (c *PgClient) SaveRawManyCtx(ctx context.Context, raw_objs map[string][]byte) error {
+
+	if len(raw_objs) == 0 {
+		return nil
+	}
+
+	// Sorted, so that concurrent batches lock rows in the same order.
+	paths := make([]string, 0, len(raw_objs))
+	for path := range raw_objs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	batch := &pgx.Batch{}
+	now := time.Now()
+	for _, path := range paths {
+		raw_obj := raw_objs[path]
+		batch.Queue(c.saveSql(), path, raw_obj, len(raw_obj), nil, now, nil)
+	}
+	return translate(c.Pool.SendBatch(ctx, batch).Close())
+
+}
+
+// LoadRawManyCtx implements backend.BatchClient with a single query.
+func (c *PgClient) LoadRawManyCtx(ctx context.Context, paths []string) (map[string][]byte, error) {
+
+	var rows pgx.Rows
+	if c.SlidingExpiry > 0 {
+		rows, _ = c.Pool.Query(ctx,
+			c.loadRenewSql("obj_path,data", "obj_path = ANY($1)"), paths,
+			c.SlidingExpiry.Microseconds())
+	} else {
+		rows, _ = c.Pool.Query(ctx, c.loadManySql(), paths)
+	}
+	loaded := make(map[string][]byte, len(paths))
+	var path string
+	var data []byte
+	_, err := pgx.ForEachRow(rows, []any{&path, &data}, func() error {
+		loaded[path] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loaded, nil
+
+}
+
+// DeleteManyCtx implements backend.BatchClient with a single statement.
+// If SoftDelete is true the objects are moved to the trash instead.
+func (c *PgClient) DeleteManyCtx(ctx context.Context, paths []string) (int, error) {
+
+	query := c.deleteManySql()
+	if c.SoftDelete {
+		query = c.trashSql("obj_path = ANY($1)")
+	}
+	tag, err := c.Pool.Exec(ctx, query, paths)
+	return int(tag.RowsAffected()), err
+
+}
+
+// DeletePrefixCtx implements backend.PrefixDeleter in batches of
+// DeleteBatchSize.  If SoftDelete is true the objects are moved to the trash
+// instead.
+func (c *PgClient) DeletePrefixCtx(ctx context.Context, prefix string) (int, error) {
+
+	if err := backend.CheckDeletePrefix(prefix); err != nil {
+		return 0, err
+	}
+	cond := "starts_with(obj_path,$1)"
+	args := []any{prefix}
+	if c.DeleteBatchSize > 0 {
+		cond = c.deletePrefixBatchCond()
+		args = append(args, c.DeleteBatchSize)
+	}
+	query := c.deleteWhereSql(cond)
+	if c.SoftDelete {
+		query = c.trashSql(cond)
+	}
+
+	deleted := 0
+	for {
+		tag, err := c.Pool.Exec(ctx, query, args...)
+		batch := int(tag.RowsAffected())
+		deleted += batch
+		if err != nil || c.DeleteBatchSize <= 0 || batch < c.DeleteBatchSize {
+			return deleted, err
+		}
+	}
+
+}
+
 // List returns an array of all objects beginning with prefix.  An empty array
 // is not considered an error.
 func (c *PgClient) List(prefix string) ([]string, error) {
+	return c.ListCtx(context.Background(), prefix)
+}
+
+// ListCtx is List with a context.
+func (c *PgClient) ListCtx(ctx context.Context, prefix string) ([]string, error) {
+	return c.list(ctx, c.Pool, prefix)
+}
+
+// list lists the live objects beginning with prefix on db.
+func (c *PgClient) list(ctx context.Context, db querier, prefix string) ([]string, error) {
 
 	// NOTE: using starts_with so don't need to %-ify prefix.
 
-	rows, _ := c.Pool.Query(context.Background(), c.listSql(), prefix)
+	rows, _ := db.Query(ctx, c.listSql(), prefix)
 	paths, err := pgx.CollectRows(rows, pgx.RowTo[string])
 	return paths, err
 
@@ -201,6 +542,11 @@ func (c *PgClient) List(prefix string) ([]string, error) {
 //
 // For S3, this operation may be slow if paged results are returned!
 func (c *PgClient) ListDetail(prefix string) ([]backend.Detailer, error) {
+	return c.ListDetailCtx(context.Background(), prefix)
+}
+
+// ListDetailCtx is ListDetail with a context.
+func (c *PgClient) ListDetailCtx(ctx context.Context, prefix string) ([]backend.Detailer, error) {
 
 	// NOTE: using starts_with so don't need to %-ify prefix.
 
@@ -211,7 +557,7 @@ func (c *PgClient) ListDetail(prefix string) ([]backend.Detailer, error) {
 	// https://dusted.codes/using-go-generics-to-pass-struct-slices-for-backendace-slices
 	//
 	// However, lucky us, CollectRows takes care of it!
-	rows, _ := c.Pool.Query(context.Background(), c.listDetailSql(), prefix)
+	rows, _ := c.Pool.Query(ctx, c.listDetailSql(), prefix)
 	detailers, err := pgx.CollectRows(rows,
 		func(row pgx.CollectableRow) (backend.Detailer, error) {
 			d := &PgDetailer{}
@@ -222,13 +568,161 @@ func (c *PgClient) ListDetail(prefix string) ([]backend.Detailer, error) {
 
 }
 
+// ListPageCtx implements backend.Pager, using the primary key so that each
+// page is a single index range scan however deep into the listing it is.
+func (c *PgClient) ListPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]string, string, error) {
+
+	after, err := backend.CheckPage(cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// One extra row tells us whether there is another page.
+	rows, _ := c.Pool.Query(ctx, c.listPageSql(), prefix, after, limit+1)
+	paths, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(paths) > limit {
+		paths = paths[:limit]
+		next = backend.EncodeCursor(paths[limit-1])
+	}
+	return paths, next, nil
+
+}
+
+// ListDetailPageCtx implements backend.Pager as for ListPageCtx.
+func (c *PgClient) ListDetailPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]backend.Detailer, string, error) {
+
+	after, err := backend.CheckPage(cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, _ := c.Pool.Query(ctx, c.listDetailPageSql(), prefix, after, limit+1)
+	detailers, err := pgx.CollectRows(rows,
+		func(row pgx.CollectableRow) (backend.Detailer, error) {
+			d := &PgDetailer{}
+			err := d.Scan(row)
+			return d, err
+		})
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(detailers) > limit {
+		detailers = detailers[:limit]
+		next = backend.EncodeCursor(detailers[limit-1].Path())
+	}
+	return detailers, next, nil
+
+}
+
+// ListDirCtx implements backend.DirLister, rolling up the common prefixes
+// in the database so that only the directory entries are returned.
+func (c *PgClient) ListDirCtx(ctx context.Context, prefix string, delimiter string) ([]string, []string, error) {
+
+	if err := backend.CheckDelimiter(delimiter); err != nil {
+		return nil, nil, err
+	}
+
+	paths := []string{}
+	prefixes := []string{}
+	rows, _ := c.Pool.Query(ctx, c.listDirSql(), prefix, delimiter)
+	var entry string
+	var is_prefix bool
+	_, err := pgx.ForEachRow(rows, []any{&entry, &is_prefix}, func() error {
+		if is_prefix {
+			prefixes = append(prefixes, entry)
+		} else {
+			paths = append(paths, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return paths, prefixes, nil
+
+}
+
+// IterateCtx implements backend.Iterator.  Rows are read from the database
+// as fn consumes them, so a single connection is held until the iteration
+// ends; fn may use the client, which will use other connections.
+func (c *PgClient) IterateCtx(ctx context.Context, prefix string, with_data bool, fn func(backend.Detailer, []byte) error) error {
+
+	query := c.listDetailSql()
+	if with_data {
+		query = c.iterateDataSql()
+	}
+	rows, err := c.Pool.Query(ctx, query, prefix)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d := &PgDetailer{}
+		var data []byte
+		if with_data {
+			err = rows.Scan(&d.path, &d.size, &d.expiry, &d.modified,
+				&d.version, &d.meta, &data)
+		} else {
+			err = d.Scan(rows)
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(d, data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+
+}
+
+// ListDetailMetaCtx implements backend.MetaLister.
+func (c *PgClient) ListDetailMetaCtx(ctx context.Context, prefix string, meta map[string]string) ([]backend.Detailer, error) {
+
+	if len(meta) == 0 {
+		return c.ListDetailCtx(ctx, prefix) // NULL meta contains nothing
+	}
+	rows, _ := c.Pool.Query(ctx, c.listDetailMetaSql(), prefix, meta)
+	return pgx.CollectRows(rows,
+		func(row pgx.CollectableRow) (backend.Detailer, error) {
+			d := &PgDetailer{}
+			err := d.Scan(row)
+			return d, err
+		})
+
+}
+
+// CountMetaCtx implements backend.MetaLister.
+func (c *PgClient) CountMetaCtx(ctx context.Context, prefix string, meta map[string]string) (int, error) {
+
+	if len(meta) == 0 {
+		return c.CountCtx(ctx, prefix)
+	}
+	count := -1
+	row := c.Pool.QueryRow(ctx, c.countMetaSql(), prefix, meta)
+	err := row.Scan(&count)
+	return count, err
+
+}
+
 // Count returns the number of non-expired objects beginning with prefix.
 // If none are found, zero is returned.
 func (c *PgClient) Count(prefix string) (int, error) {
+	return c.CountCtx(context.Background(), prefix)
+}
+
+// CountCtx is Count with a context.
+func (c *PgClient) CountCtx(ctx context.Context, prefix string) (int, error) {
 
 	// NOTE: using starts_with so don't need to %-ify prefix.
 	count := -1
-	row := c.Pool.QueryRow(context.Background(), c.countSql(),
+	row := c.Pool.QueryRow(ctx, c.countSql(),
 		prefix)
 	err := row.Scan(&count)
 	return count, err
@@ -237,25 +731,103 @@ func (c *PgClient) Count(prefix string) (int, error) {
 
 // CountAll returns the total number of non-expired objects in the database.
 func (c *PgClient) CountAll() (int, error) {
+	return c.CountAllCtx(context.Background())
+}
+
+// CountAllCtx is CountAll with a context.
+func (c *PgClient) CountAllCtx(ctx context.Context) (int, error) {
 
 	count := -1
-	row := c.Pool.QueryRow(context.Background(), c.countAllSql())
+	row := c.Pool.QueryRow(ctx, c.countAllSql())
 	err := row.Scan(&count)
 	return count, err
 }
 
-// Purge deletes expired items from the database.  Returns the number of rows
-// deleted.
+// Purge deletes expired items from the database, if SoftDelete is true the
+// items in the trash past TrashRetention, and the revisions outside
+// HistoryKeep and HistoryMaxAge.  Returns the number of rows deleted.
 func (c *PgClient) Purge() (int, error) {
+	return c.PurgeCtx(context.Background())
+}
+
+// PurgeCtx is Purge with a context.
+func (c *PgClient) PurgeCtx(ctx context.Context) (int, error) {
 
-	tag, err := c.Pool.Exec(context.Background(), c.purgeSql())
+	tag, err := c.Pool.Exec(ctx, c.purgeSql())
 
 	// NOTE: if you are purging more than two billion rows on a 32-bit system
 	// you are insane!
-	return int(tag.RowsAffected()), err
+	purged := int(tag.RowsAffected())
+	if err == nil && c.SoftDelete {
+		tag, err = c.Pool.Exec(ctx, c.purgeTrashSql(),
+			c.TrashRetention.Microseconds())
+		purged += int(tag.RowsAffected())
+	}
+	if err != nil || !c.boundsHistory() {
+		return purged, err
+	}
+	pruned, err := c.PruneHistoryCtx(ctx, c.HistoryKeep, c.HistoryMaxAge)
+	return purged + pruned, err
 
 }
 
+// PurgeBatch deletes at most limit items from the database as for Purge,
+// expired items first.  Returns the number of rows deleted.  Rows locked by
+// another purge are skipped.
+func (c *PgClient) PurgeBatch(limit int) (int, error) {
+	return c.PurgeBatchCtx(context.Background(), limit)
+}
+
+// PurgeBatchCtx is PurgeBatch with a context.
+func (c *PgClient) PurgeBatchCtx(ctx context.Context, limit int) (int, error) {
+
+	tag, err := c.Pool.Exec(ctx, c.purgeBatchSql(), limit)
+	purged := int(tag.RowsAffected())
+	if err == nil && c.SoftDelete && purged < limit {
+		tag, err = c.Pool.Exec(ctx, c.purgeTrashBatchSql(),
+			c.TrashRetention.Microseconds(), limit-purged)
+		purged += int(tag.RowsAffected())
+	}
+	if err != nil || !c.boundsHistory() || purged >= limit {
+		return purged, err
+	}
+	pruned, err := c.pruneHistoryBatch(ctx, limit-purged)
+	return purged + pruned, err
+
+}
+
+// StartPurger starts purging expired rows in the background every interval,
+// deleting at most batch_size rows per statement so that no single purge
+// holds locks for long.  A batch_size of zero means no limit.  If hook is
+// not nil it is called after each run with the rows deleted and any error.
+//
+// The purger is stopped by StopPurger or Shutdown.  StartPurger, StopPurger
+// and Shutdown are safe to call concurrently.
+func (c *PgClient) StartPurger(interval time.Duration, batch_size int, hook func(int, error)) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.purger != nil && c.purger.Running() {
+		return purger.ErrRunning
+	}
+	p := purger.New(c, interval, batch_size)
+	p.OnPurge = hook
+	if err := p.Start(); err != nil {
+		return err
+	}
+	c.purger = p
+	return nil
+}
+
+// StopPurger stops the background purger, if running, waiting for any purge
+// in progress to complete.
+func (c *PgClient) StopPurger() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.purger != nil {
+		c.purger.Stop()
+	}
+}
+
 // Schema returns the SQL required to create this client's Table.
 func (c *PgClient) Schema() string {
 	return c.schemaSql()
@@ -270,14 +842,24 @@ func (c *PgClient) CreateTable() error {
 	return err
 }
 
-// Shutdown calls Purge if PurgeOnShutdown is true.
+// Shutdown stops any background purger, then calls Purge if PurgeOnShutdown
+// is true, and finally closes the Pool.  The client can not be used after
+// Shutdown; if the Pool is shared, call Purge and StopPurger instead.
 //
 // Note that it *should* be safe to kill the client in mid-purge, but you
 // presumably want to purge at least once per run.
 func (c *PgClient) Shutdown() error {
+	return c.ShutdownCtx(context.Background())
+}
+
+// ShutdownCtx is Shutdown with a context, which is useful for limiting the
+// time spent purging.
+func (c *PgClient) ShutdownCtx(ctx context.Context) error {
+	c.StopPurger()
+	defer c.Pool.Close()
 	if c.PurgeOnShutdown == true {
 		// TODO (maybe) -- log results.
-		_, err := c.Purge()
+		_, err := c.PurgeCtx(ctx)
 		return err
 	}
 	return nil
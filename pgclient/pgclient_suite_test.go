@@ -37,12 +37,17 @@ func (suite *PgClientTestSuite) SetupSuite() {
 
 	require.NoError(suite.DropTable(), "drop table")
 	require.NoError(suite.Client.CreateTable(), "create table")
+	require.NoError(suite.Client.CreateHistory(), "create history")
+	require.NoError(suite.Client.CreateTrash(), "create trash")
 
 }
 
 func (suite *PgClientTestSuite) DropTable() error {
 
-	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s;", suite.Client.Table)
+	sql := fmt.Sprintf(`DROP TABLE IF EXISTS %[1]s;
+DROP TABLE IF EXISTS %[1]s_history;
+DROP TABLE IF EXISTS %[1]s_trash;
+DROP FUNCTION IF EXISTS %[1]s_history_fn;`, suite.Client.Table)
 	_, err := suite.Client.Pool.Exec(context.Background(), sql)
 	return err
 }
@@ -61,7 +66,7 @@ func (suite *PgClientTestSuite) TearDownSuite() {
 
 }
 
-// Zero out the table per test, so there are no fragments.
+// Zero out the tables per test, so there are no fragments.
 func (suite *PgClientTestSuite) SetupTest() {
 
 	require := suite.Require()
@@ -70,7 +75,8 @@ func (suite *PgClientTestSuite) SetupTest() {
 	require.NoError(err, "conn acquire")
 	defer conn.Release()
 
-	sql := fmt.Sprintf("TRUNCATE TABLE %s;", suite.Client.Table)
+	sql := fmt.Sprintf("TRUNCATE TABLE %[1]s, %[1]s_history, %[1]s_trash;",
+		suite.Client.Table)
 	_, err = conn.Exec(context.Background(), sql)
 	require.NoError(err, "exec truncate")
 
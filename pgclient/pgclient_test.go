@@ -20,11 +20,18 @@
 package pgclient_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+
 	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
 	"github.com/biztos/jsobs/pgclient"
 )
 
@@ -102,6 +109,7 @@ func (suite *PgClientTestSuite) TestSaveRawFailsBadJson() {
 	data := []byte("not json")
 	err := suite.Client.SaveRaw("/any", data)
 	require.ErrorContains(err, "SQLSTATE 22P02")
+	require.ErrorIs(err, backend.ErrInvalidJson)
 
 }
 
@@ -112,6 +120,7 @@ func (suite *PgClientTestSuite) TestSaveRawExpiryFailsBadJson() {
 	data := []byte("not json")
 	err := suite.Client.SaveRawExpiry("/any", data, time.Now())
 	require.ErrorContains(err, "SQLSTATE 22P02")
+	require.ErrorIs(err, backend.ErrInvalidJson)
 
 }
 
@@ -121,6 +130,8 @@ func (suite *PgClientTestSuite) TestLoadRawFailsNotFound() {
 
 	data, err := suite.Client.LoadRaw("/not/here")
 	require.ErrorIs(err, pgclient.ErrNotFound)
+	require.ErrorIs(err, backend.ErrNotFound)
+	require.ErrorIs(err, pgx.ErrNoRows, "driver error still there")
 	require.Nil(data)
 
 }
@@ -159,9 +170,31 @@ func (suite *PgClientTestSuite) TestLoadDetailFailsNotFound() {
 	require := suite.Require()
 	detail, err := suite.Client.LoadDetail("/nopers.json")
 	require.ErrorIs(err, pgclient.ErrNotFound, "not found")
+	require.ErrorIs(err, pgx.ErrNoRows, "driver error")
 	require.Nil(detail, "detail returned")
 }
 
+func (suite *PgClientTestSuite) TestLoadDetailIgnoresSiblingOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	meta := map[string]string{"owner": "bob"}
+	require.NoError(suite.Client.SaveRawMetaCtx(ctx, "/detail/ab",
+		[]byte(`1`), nil, meta))
+
+	_, err := suite.Client.LoadDetail("/detail/a")
+	require.ErrorIs(err, pgclient.ErrNotFound, "prefix of a sibling")
+
+	require.NoError(suite.Client.SaveRaw("/detail/a", []byte(`22`)))
+	detail, err := suite.Client.LoadDetail("/detail/a")
+	require.NoError(err)
+	require.Equal("/detail/a", detail.Path())
+	require.Equal(2, detail.Size())
+	require.Nil(detail.Meta())
+
+}
+
 func (suite *PgClientTestSuite) TestLoadDetailWithExpiryOK() {
 
 	require := suite.Require()
@@ -361,22 +394,825 @@ func (suite *PgClientTestSuite) TestPurgeOK() {
 
 }
 
+func (suite *PgClientTestSuite) TestPurgeBatchOK() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(7, "/purge/%02d.json", &past)
+	suite.SaveSet(3, "/keep/%02d.json", nil)
+
+	purged, err := suite.Client.PurgeBatch(5)
+	require.NoError(err, "first batch")
+	require.Equal(5, purged, "first batch count")
+	require.Equal(5, suite.FullCount(), "full count after first")
+
+	purged, err = suite.Client.PurgeBatch(5)
+	require.NoError(err, "second batch")
+	require.Equal(2, purged, "second batch count")
+
+	purged, err = suite.Client.PurgeBatch(5)
+	require.NoError(err, "third batch")
+	require.Equal(0, purged, "third batch count")
+	require.Equal(3, suite.FullCount(), "full count after all")
+
+}
+
+func (suite *PgClientTestSuite) TestStartPurgerOK() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(5, "/purge/%02d.json", &past)
+
+	require.NoError(suite.Client.StartPurger(time.Millisecond, 2, nil))
+	require.Eventually(func() bool {
+		return suite.FullCount() == 0
+	}, time.Second, time.Millisecond, "purged")
+	suite.Client.StopPurger()
+
+}
+
+func (suite *PgClientTestSuite) TestStartPurgerConcurrentStopOK() {
+
+	require := suite.Require()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			suite.Client.StartPurger(time.Hour, 0, nil)
+		}()
+		go func() {
+			defer wg.Done()
+			suite.Client.StopPurger()
+		}()
+	}
+	wg.Wait()
+	suite.Client.StopPurger()
+	require.NoError(suite.Client.StartPurger(time.Hour, 0, nil), "restart")
+	suite.Client.StopPurger()
+
+}
+
 func (suite *PgClientTestSuite) TestShutdownOK() {
 
 	require := suite.Require()
 
+	// Shutdown closes the pool, so we need our own clients here.
+	newClient := func() *pgclient.PgClient {
+		c, err := pgclient.New()
+		require.NoError(err, "connect error")
+		c.Table = suite.Client.Table
+		return c
+	}
+
 	past := time.Now().Add(-1 * time.Hour)
 	suite.SaveSet(50, "/purge/%02d.json", &past)
 	require.Equal(50, suite.FullCount(), "full count pre shutdown")
 
-	suite.Client.PurgeOnShutdown = false
-	defer func() { suite.Client.PurgeOnShutdown = true }()
-
-	require.NoError(suite.Client.Shutdown(), "shutdown without purge")
+	c := newClient()
+	c.PurgeOnShutdown = false
+	require.NoError(c.Shutdown(), "shutdown without purge")
 	require.Equal(50, suite.FullCount(), "full count after no-purge shutdown")
+	_, err := c.CountAll()
+	require.Error(err, "pool closed")
+
+	c = newClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(c.ShutdownCtx(ctx), context.Canceled, "canceled shutdown")
+	require.Equal(50, suite.FullCount(), "full count after canceled shutdown")
+	_, err = c.CountAll()
+	require.Error(err, "pool closed after canceled shutdown")
 
-	suite.Client.PurgeOnShutdown = true
-	require.NoError(suite.Client.Shutdown(), "shutdown without purge")
+	c = newClient()
+	require.NoError(c.Shutdown(), "shutdown with purge")
 	require.Equal(0, suite.FullCount(), "full count after purging shutdown")
 
 }
+
+func (suite *PgClientTestSuite) TestImplementsContextBackendClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.ContextBackendClient)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestContextCanceledFails() {
+
+	require := suite.Require()
+
+	suite.SaveSet(3, "/ctx/%d", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(suite.Client.SaveRawCtx(ctx, "/ctx/x", []byte("{}")),
+		context.Canceled, "SaveRawCtx")
+	require.ErrorIs(suite.Client.SaveRawExpiryCtx(ctx, "/ctx/x", []byte("{}"),
+		time.Now()), context.Canceled, "SaveRawExpiryCtx")
+	_, err := suite.Client.LoadRawCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadRawCtx")
+	_, err = suite.Client.LoadDetailCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadDetailCtx")
+	require.ErrorIs(suite.Client.DeleteCtx(ctx, "/ctx/0"), context.Canceled,
+		"DeleteCtx")
+	_, err = suite.Client.ListCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListCtx")
+	_, err = suite.Client.ListDetailCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListDetailCtx")
+	_, err = suite.Client.CountCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "CountCtx")
+	_, err = suite.Client.CountAllCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "CountAllCtx")
+	_, err = suite.Client.PurgeCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "PurgeCtx")
+
+	// Nothing happened.
+	paths, err := suite.Client.List("/ctx/")
+	require.NoError(err)
+	require.Equal([]string{"/ctx/0", "/ctx/1", "/ctx/2"}, paths)
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsVersioner() {
+
+	require := suite.Require()
+	require.Implements((*backend.Versioner)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestVersionsOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+
+	_, _, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.ErrorIs(err, backend.ErrNotFound, "not yet saved")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "update missing")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 0)
+	require.NoError(err, "create")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 0)
+	require.ErrorIs(err, backend.ErrConflict, "create existing")
+
+	data, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+	require.EqualValues(1, version)
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 1)
+	require.NoError(err, "update")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "stale update")
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":4}`)), "plain save")
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(3, detail.Version(), "detail version")
+
+	details, err := suite.Client.ListDetail("/ver/")
+	require.NoError(err)
+	require.Len(details, 1)
+	require.EqualValues(3, details[0].Version(), "list detail version")
+
+	data, version, err = suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":4}`, string(data))
+	require.EqualValues(3, version)
+
+}
+
+func (suite *PgClientTestSuite) TestVersionsExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":1}`), past))
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":2}`), past))
+
+	err := suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 2)
+	require.ErrorIs(err, backend.ErrConflict, "update expired")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), &future, 0)
+	require.NoError(err, "replace expired")
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(1, detail.Version(), "starts over")
+	require.True(detail.Expires(), "expiry saved")
+	require.WithinDuration(future, detail.Expiry(), time.Second)
+
+}
+
+func (suite *PgClientTestSuite) TestSaveIfVersionConcurrentOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/conc"
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":0}`)))
+
+	// Everybody read version 1, so only one of them may win.
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf(`{"n":%d}`, i+1))
+			errs[i] = suite.Client.SaveRawIfVersionCtx(ctx, path, data, nil, 1)
+		}(i)
+	}
+	wg.Wait()
+
+	wins := 0
+	for _, err := range errs {
+		if err == nil {
+			wins++
+		} else {
+			require.ErrorIs(err, backend.ErrConflict)
+		}
+	}
+	require.Equal(1, wins, "one winner")
+
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.EqualValues(2, version)
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsBatchClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.BatchClient)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestManyOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	raw_objs := map[string][]byte{}
+	for i := 0; i < 50; i++ {
+		raw_objs[fmt.Sprintf("/many/%02d", i)] = []byte(fmt.Sprintf(`{"n":%d}`, i))
+	}
+	require.NoError(suite.Client.SaveRawManyCtx(ctx, raw_objs), "save")
+	require.Equal(50, suite.FullCount(), "full count")
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/many/old/%d", &past)
+
+	loaded, err := suite.Client.LoadRawManyCtx(ctx,
+		[]string{"/many/00", "/many/49", "/many/none", "/many/old/0"})
+	require.NoError(err, "load")
+	require.Len(loaded, 2, "only live objects")
+	require.JSONEq(`{"n":0}`, string(loaded["/many/00"]))
+	require.JSONEq(`{"n":49}`, string(loaded["/many/49"]))
+
+	deleted, err := suite.Client.DeleteManyCtx(ctx,
+		[]string{"/many/00", "/many/01", "/many/none", "/many/old/0"})
+	require.NoError(err, "delete")
+	require.Equal(3, deleted, "expired deleted too")
+	require.Equal(48, suite.FullCount(), "full count after delete")
+
+}
+
+func (suite *PgClientTestSuite) TestManyEmptyOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	require.NoError(suite.Client.SaveRawManyCtx(ctx, nil), "save")
+	loaded, err := suite.Client.LoadRawManyCtx(ctx, nil)
+	require.NoError(err, "load")
+	require.Empty(loaded)
+	deleted, err := suite.Client.DeleteManyCtx(ctx, nil)
+	require.NoError(err, "delete")
+	require.Zero(deleted)
+
+}
+
+func (suite *PgClientTestSuite) TestSaveRawManyAtomicFails() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveRawManyCtx(context.Background(), map[string][]byte{
+		"/many/a": []byte(`{"n":1}`),
+		"/many/b": []byte(`not json`),
+		"/many/c": []byte(`{"n":3}`),
+	})
+	require.ErrorIs(err, backend.ErrInvalidJson)
+	require.Equal(0, suite.FullCount(), "nothing saved")
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsPager() {
+
+	require := suite.Require()
+	require.Implements((*backend.Pager)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestListPageOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	set := suite.SaveSet(10, "/page/%02d", nil)
+	suite.SaveSet(3, "/page/%02d-old", &past)
+	suite.SaveSet(2, "/pages/%02d", nil)
+
+	paths := []string{}
+	cursor := ""
+	pages := 0
+	for {
+		page, next, err := suite.Client.ListPageCtx(ctx, "/page/", cursor, 4)
+		require.NoError(err)
+		require.LessOrEqual(len(page), 4, "page size")
+		paths = append(paths, page...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	require.Equal(set.Paths, paths, "all paths in order")
+	require.Equal(3, pages, "pages")
+
+}
+
+func (suite *PgClientTestSuite) TestListDetailPageOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	set := suite.SaveSet(6, "/page/%02d", nil)
+
+	detailers, next, err := suite.Client.ListDetailPageCtx(ctx, "/page/", "", 3)
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(set.Paths[0], detailers[0].Path())
+	require.NotEmpty(next)
+
+	detailers, next, err = suite.Client.ListDetailPageCtx(ctx, "/page/", next, 3)
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(set.Paths[5], detailers[2].Path())
+	require.EqualValues(1, detailers[2].Version())
+	require.Empty(next, "exactly two pages")
+
+}
+
+func (suite *PgClientTestSuite) TestListPageFailsBadArgs() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	_, _, err := suite.Client.ListPageCtx(ctx, "/page/", "?", 3)
+	require.ErrorIs(err, backend.ErrInvalidCursor)
+	_, _, err = suite.Client.ListDetailPageCtx(ctx, "/page/", "", 0)
+	require.ErrorContains(err, "page limit must be positive")
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsIterator() {
+
+	require := suite.Require()
+	require.Implements((*backend.Iterator)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestIterateOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	set := suite.SaveSet(5, "/iter/%02d", nil)
+	suite.SaveSet(2, "/iter/%02d-old", &past)
+	suite.SaveSet(2, "/iters/%02d", nil)
+
+	paths := []string{}
+	err := suite.Client.IterateCtx(ctx, "/iter/", true, func(d backend.Detailer, raw_obj []byte) error {
+		paths = append(paths, d.Path())
+		require.JSONEq(string(set.PathData[d.Path()]), string(raw_obj))
+		require.EqualValues(1, d.Version())
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(set.Paths, paths, "all paths in order")
+
+	paths = []string{}
+	err = suite.Client.IterateCtx(ctx, "/iter/", false, func(d backend.Detailer, raw_obj []byte) error {
+		paths = append(paths, d.Path())
+		require.Nil(raw_obj)
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(set.Paths, paths, "all paths in order without data")
+
+}
+
+func (suite *PgClientTestSuite) TestIterateStopsOnError() {
+
+	require := suite.Require()
+
+	suite.SaveSet(5, "/iter/%02d", nil)
+
+	exp_err := errors.New("enough")
+	count := 0
+	err := suite.Client.IterateCtx(context.Background(), "/iter/", true,
+		func(backend.Detailer, []byte) error {
+			count++
+			return exp_err
+		})
+	require.ErrorIs(err, exp_err)
+	require.Equal(1, count)
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsDirLister() {
+
+	require := suite.Require()
+	require.Implements((*backend.DirLister)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestListDirOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(2, "/dir/%d", nil)
+	suite.SaveSet(3, "/dir/sub/%d", nil)
+	suite.SaveSet(1, "/dir/sub/deeper/%d", nil)
+	suite.SaveSet(2, "/dir/old/%d", &past)
+	suite.SaveSet(1, "/dir/trailing/%d/", nil)
+	suite.SaveSet(1, "/dirt/%d", nil)
+
+	paths, prefixes, err := suite.Client.ListDirCtx(ctx, "/dir/", "/")
+	require.NoError(err)
+	require.Equal([]string{"/dir/0", "/dir/1"}, paths, "paths")
+	require.Equal([]string{"/dir/sub/", "/dir/trailing/"}, prefixes,
+		"prefixes, not expired")
+
+	paths, prefixes, err = suite.Client.ListDirCtx(ctx, "/dir/sub/", "/")
+	require.NoError(err)
+	require.Equal([]string{"/dir/sub/0", "/dir/sub/1", "/dir/sub/2"}, paths)
+	require.Equal([]string{"/dir/sub/deeper/"}, prefixes)
+
+	paths, prefixes, err = suite.Client.ListDirCtx(ctx, "/di", "r/s")
+	require.NoError(err)
+	require.Equal([]string{"/dir/0", "/dir/1", "/dir/trailing/0/", "/dirt/0"},
+		paths, "odd delimiter paths")
+	require.Equal([]string{"/dir/s"}, prefixes, "odd delimiter prefixes")
+
+	paths, prefixes, err = suite.Client.ListDirCtx(ctx, "/none/", "/")
+	require.NoError(err)
+	require.Empty(paths)
+	require.Empty(prefixes)
+
+}
+
+func (suite *PgClientTestSuite) TestListDirFailsEmptyDelimiter() {
+
+	require := suite.Require()
+
+	_, _, err := suite.Client.ListDirCtx(context.Background(), "/dir/", "")
+	require.EqualError(err, "delimiter must not be empty")
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsUpdater() {
+
+	require := suite.Require()
+	require.Implements((*backend.Updater)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestUpdateRawOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	require.NoError(suite.Client.SaveRawExpiry("/up", []byte(`{"n":1}`), expiry))
+
+	err := suite.Client.UpdateRawCtx(ctx, "/up", func(raw_obj []byte) ([]byte, error) {
+		return backend.MergePatch(raw_obj, []byte(`{"m":2}`))
+	})
+	require.NoError(err)
+
+	raw, version, err := suite.Client.LoadRawVersionCtx(ctx, "/up")
+	require.NoError(err)
+	require.JSONEq(`{"n":1,"m":2}`, string(raw))
+	require.EqualValues(2, version)
+	detail, err := suite.Client.LoadDetail("/up")
+	require.NoError(err)
+	require.True(expiry.Equal(detail.Expiry()), "expiry kept")
+
+}
+
+func (suite *PgClientTestSuite) TestUpdateRawNotFound() {
+
+	require := suite.Require()
+
+	err := suite.Client.UpdateRawCtx(context.Background(), "/none",
+		func(raw_obj []byte) ([]byte, error) {
+			suite.Fail("should not be called")
+			return raw_obj, nil
+		})
+	require.ErrorIs(err, backend.ErrNotFound)
+
+}
+
+func (suite *PgClientTestSuite) TestUpdateRawFnError() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	require.NoError(suite.Client.SaveRaw("/up", []byte(`{"n":1}`)))
+
+	exp_err := errors.New("nope")
+	err := suite.Client.UpdateRawCtx(ctx, "/up", func([]byte) ([]byte, error) {
+		return nil, exp_err
+	})
+	require.ErrorIs(err, exp_err)
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, "/up")
+	require.NoError(err)
+	require.EqualValues(1, version, "not saved")
+
+}
+
+func (suite *PgClientTestSuite) TestUpdateRawConcurrentOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	require.NoError(suite.Client.SaveRaw("/up", []byte(`{}`)))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			patch := []byte(fmt.Sprintf(`{"k%d":true}`, i))
+			errs[i] = suite.Client.UpdateRawCtx(ctx, "/up",
+				func(raw_obj []byte) ([]byte, error) {
+					return backend.MergePatch(raw_obj, patch)
+				})
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		require.NoError(err, "update %d", i)
+	}
+
+	raw, version, err := suite.Client.LoadRawVersionCtx(ctx, "/up")
+	require.NoError(err)
+	require.EqualValues(11, version)
+	require.Equal(10, strings.Count(string(raw), "true"), "no lost updates")
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsMetaClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.MetaClient)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestSaveRawMetaOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	meta := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	exp := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	meta["Owner"] = "changed" // not kept by reference
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Equal(exp, detail.Meta())
+	require.False(detail.Expires())
+
+	// Other saves keep it.
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, version)
+	require.NoError(err)
+	detailers, err := suite.Client.ListDetail("/meta/")
+	require.NoError(err)
+	require.Len(detailers, 1)
+	require.Equal(exp, detailers[0].Meta(), "kept")
+
+	// Saving with nil removes it.
+	err = suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":4}`), nil, nil)
+	require.NoError(err)
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta())
+
+}
+
+func (suite *PgClientTestSuite) TestSaveRawMetaExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	past := time.Now().Add(-time.Hour)
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), &past,
+		map[string]string{"k": "v"})
+	require.NoError(err)
+	_, err = suite.Client.LoadDetail(path)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta(), "not kept from expired object")
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsMetaLister() {
+
+	require := suite.Require()
+	require.Implements((*backend.MetaLister)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestListDetailMetaOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	data := []byte(`{}`)
+	saves := []struct {
+		path   string
+		expiry *time.Time
+		meta   map[string]string
+	}{
+		{"/m/a", nil, map[string]string{"kind": "x", "owner": "bob"}},
+		{"/m/b", nil, map[string]string{"kind": "x"}},
+		{"/m/c", nil, nil},
+		{"/m/d", &past, map[string]string{"kind": "x"}},
+		{"/n/e", nil, map[string]string{"kind": "x"}},
+	}
+	for _, s := range saves {
+		require.NoError(suite.Client.SaveRawMetaCtx(ctx, s.path, data,
+			s.expiry, s.meta))
+	}
+
+	for _, tc := range []struct {
+		meta  map[string]string
+		paths []string
+	}{
+		{map[string]string{"kind": "x"}, []string{"/m/a", "/m/b"}},
+		{map[string]string{"kind": "x", "owner": "bob"}, []string{"/m/a"}},
+		{map[string]string{"kind": "y"}, []string{}},
+		{map[string]string{"owner": "x"}, []string{}},
+		{map[string]string{}, []string{"/m/a", "/m/b", "/m/c"}},
+	} {
+		detailers, err := suite.Client.ListDetailMetaCtx(ctx, "/m/", tc.meta)
+		require.NoError(err)
+		paths := []string{}
+		for _, d := range detailers {
+			paths = append(paths, d.Path())
+		}
+		require.Equal(tc.paths, paths, "list %v", tc.meta)
+		count, err := suite.Client.CountMetaCtx(ctx, "/m/", tc.meta)
+		require.NoError(err)
+		require.Equal(len(tc.paths), count, "count %v", tc.meta)
+	}
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsExpirer() {
+
+	require := suite.Require()
+	require.Implements((*backend.Expirer)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestSetExpiryOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/expiry/x"
+	meta := map[string]string{"owner": "bob"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	before, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+
+	expiry := time.Now().Add(time.Hour).Round(time.Millisecond)
+	require.NoError(suite.Client.SetExpiryCtx(ctx, path, &expiry))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.True(expiry.Equal(detail.Expiry()), "expiry set")
+	require.Equal(before.Version(), detail.Version(), "version unchanged")
+	require.True(before.Modified().Equal(detail.Modified()), "modified unchanged")
+	require.Equal(meta, detail.Meta(), "meta unchanged")
+	data, err := suite.Client.LoadRaw(path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+
+	require.NoError(suite.Client.SetExpiryCtx(ctx, path, nil))
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.False(detail.Expires(), "expiry cleared")
+
+}
+
+func (suite *PgClientTestSuite) TestSetExpiryFailsNotFound() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+	err := suite.Client.SetExpiryCtx(ctx, "/expiry/none", &expiry)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	past := time.Now().Add(-time.Hour)
+	require.NoError(suite.Client.SaveRawExpiry("/expiry/old", []byte(`1`), past))
+	err = suite.Client.SetExpiryCtx(ctx, "/expiry/old", &expiry)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+}
+
+func (suite *PgClientTestSuite) TestSlidingExpiryOK() {
+
+	defer func() { suite.Client.SlidingExpiry = 0 }()
+	suite.Client.SlidingExpiry = time.Hour
+	backendtest.SlidingExpiryOK(suite.Require(), suite.Client)
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsPrefixDeleter() {
+
+	require := suite.Require()
+	require.Implements((*backend.PrefixDeleter)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestDeletePrefixOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(3, "/del_/thing/%d.json", nil)
+	suite.SaveSet(2, "/del_/expired/%d.json", &past)
+	suite.SaveSet(2, "/delx/%d.json", nil)
+
+	deleted, err := suite.Client.DeletePrefixCtx(ctx, "/del_/")
+	require.NoError(err)
+	require.Equal(5, deleted, "expired too")
+	require.Equal(2, suite.FullCount(), "full count")
+
+	deleted, err = suite.Client.DeletePrefixCtx(ctx, "/del_/")
+	require.NoError(err)
+	require.Equal(0, deleted)
+
+}
+
+func (suite *PgClientTestSuite) TestDeletePrefixBatchesOK() {
+
+	require := suite.Require()
+
+	defer func(n int) { suite.Client.DeleteBatchSize = n }(suite.Client.DeleteBatchSize)
+	ctx := context.Background()
+	suite.SaveSet(1, "/kept/%d.json", nil)
+
+	for _, size := range []int{2, 0} {
+		suite.Client.DeleteBatchSize = size
+		suite.SaveSet(7, "/del/%d.json", nil)
+		deleted, err := suite.Client.DeletePrefixCtx(ctx, "/del/")
+		require.NoError(err)
+		require.Equal(7, deleted, "batch size %d", size)
+		require.Equal(1, suite.FullCount(), "full count")
+	}
+
+}
+
+func (suite *PgClientTestSuite) TestDeletePrefixFailsEmpty() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/any/%d.json", nil)
+	_, err := suite.Client.DeletePrefixCtx(context.Background(), "")
+	require.ErrorIs(err, backend.ErrInvalidPath)
+	require.Equal(1, suite.FullCount(), "full count")
+
+}
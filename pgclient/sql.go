@@ -10,15 +10,82 @@ package pgclient
 
 import "fmt"
 
+// Replacing an expired object starts it over at version 1, without
+// metadata.  A NULL meta keeps the current metadata, and the empty JSON
+// object removes it.
 func (c *PgClient) saveSql() string {
-	f := `INSERT INTO %s (obj_path,data,size,expiry,modified)
-VALUES ($1,$2,$3,$4,$5)
+	f := `INSERT INTO %[1]s (obj_path,data,size,expiry,modified,version,meta)
+VALUES ($1,$2,$3,$4,$5,1,nullif($6::jsonb,'{}'::jsonb))
 ON CONFLICT (obj_path)
 DO UPDATE SET
 	data = EXCLUDED.data,
 	size = EXCLUDED.size,
 	expiry = EXCLUDED.expiry,
-	modified = EXCLUDED.modified;`
+	modified = EXCLUDED.modified,
+	version = CASE WHEN %[1]s.expiry IS NULL OR %[1]s.expiry > now()
+		THEN %[1]s.version + 1 ELSE 1 END,
+	meta = CASE WHEN $6::jsonb IS NOT NULL THEN EXCLUDED.meta
+		WHEN %[1]s.expiry IS NULL OR %[1]s.expiry > now() THEN %[1]s.meta
+		ELSE NULL END;`
+	return fmt.Sprintf(f, c.Table)
+}
+
+func (c *PgClient) saveIfVersionSql() string {
+	f := `UPDATE %s
+SET data = $2, size = $3, expiry = $4, modified = $5, version = version + 1
+WHERE obj_path = $1 AND version = $6 AND (expiry IS NULL OR expiry > now());`
+	return fmt.Sprintf(f, c.Table)
+}
+
+func (c *PgClient) loadForUpdateSql() string {
+	f := `SELECT data
+FROM %s
+WHERE obj_path = $1 AND (expiry IS NULL or expiry > now())
+FOR UPDATE;`
+	return fmt.Sprintf(f, c.Table)
+}
+
+func (c *PgClient) updateSql() string {
+	f := `UPDATE %s
+SET data = $2, size = $3, modified = $4, version = version + 1
+WHERE obj_path = $1;`
+	return fmt.Sprintf(f, c.Table)
+}
+
+func (c *PgClient) setExpirySql() string {
+	f := `UPDATE %s
+SET expiry = $2
+WHERE obj_path = $1 AND (expiry IS NULL OR expiry > now());`
+	return fmt.Sprintf(f, c.Table)
+}
+
+// NOTE: the renewal is passed in microseconds.  Objects with no expiry are
+// not renewed, and are found by the second SELECT in the same statement.
+func (c *PgClient) loadRenewSql(cols string, cond string) string {
+	f := `WITH renewed AS (
+	UPDATE %[1]s
+	SET expiry = greatest(expiry, now() + $2 * interval '1 microsecond')
+	WHERE %[3]s AND expiry > now()
+	RETURNING %[2]s
+)
+SELECT %[2]s FROM renewed
+UNION ALL
+SELECT %[2]s FROM %[1]s WHERE %[3]s AND expiry IS NULL;`
+	return fmt.Sprintf(f, c.Table, cols, cond)
+}
+
+func (c *PgClient) saveIfNotExistsSql() string {
+	f := `INSERT INTO %[1]s (obj_path,data,size,expiry,modified,version)
+VALUES ($1,$2,$3,$4,$5,1)
+ON CONFLICT (obj_path)
+DO UPDATE SET
+	data = EXCLUDED.data,
+	size = EXCLUDED.size,
+	expiry = EXCLUDED.expiry,
+	modified = EXCLUDED.modified,
+	version = 1,
+	meta = NULL
+WHERE %[1]s.expiry IS NOT NULL AND %[1]s.expiry <= now();`
 	return fmt.Sprintf(f, c.Table)
 }
 
@@ -32,6 +99,28 @@ func (c *PgClient) deleteSql() string {
 
 }
 
+func (c *PgClient) deleteManySql() string {
+	f := "DELETE FROM %s WHERE obj_path = ANY($1);"
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) deleteWhereSql(cond string) string {
+	f := "DELETE FROM %s WHERE %s;"
+	return fmt.Sprintf(f, c.Table, cond)
+
+}
+
+// NOTE: this is the condition for one batch of DeletePrefixCtx, to be used
+// in deleteWhereSql or trashSql.
+func (c *PgClient) deletePrefixBatchCond() string {
+	f := `obj_path IN (
+	SELECT obj_path FROM %s WHERE starts_with(obj_path,$1) LIMIT $2
+)`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
 func (c *PgClient) listSql() string {
 	f := `SELECT obj_path
 FROM %s
@@ -42,7 +131,7 @@ ORDER BY obj_path;`
 }
 
 func (c *PgClient) listDetailSql() string {
-	f := `SELECT obj_path,size,expiry,modified
+	f := `SELECT obj_path,size,expiry,modified,version,meta
 FROM %s
 WHERE starts_with(obj_path,$1) = true AND (expiry IS NULL OR expiry > now())
 ORDER BY obj_path;`
@@ -50,6 +139,52 @@ ORDER BY obj_path;`
 
 }
 
+func (c *PgClient) iterateDataSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta,data
+FROM %s
+WHERE starts_with(obj_path,$1) = true AND (expiry IS NULL OR expiry > now())
+ORDER BY obj_path;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) listDirSql() string {
+	f := `SELECT DISTINCT
+CASE WHEN d > 0 THEN left(obj_path,length($1)+d+length($2)-1) ELSE obj_path END
+AS entry, d > 0 AS is_prefix
+FROM (
+    SELECT obj_path, strpos(substr(obj_path,length($1)+1),$2) AS d
+    FROM %s
+    WHERE starts_with(obj_path,$1) = true
+    AND (expiry IS NULL OR expiry > now())
+) AS objs
+ORDER BY entry;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) listPageSql() string {
+	f := `SELECT obj_path
+FROM %s
+WHERE starts_with(obj_path,$1) = true AND obj_path > $2
+AND (expiry IS NULL OR expiry > now())
+ORDER BY obj_path
+LIMIT $3;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) listDetailPageSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta
+FROM %s
+WHERE starts_with(obj_path,$1) = true AND obj_path > $2
+AND (expiry IS NULL OR expiry > now())
+ORDER BY obj_path
+LIMIT $3;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
 func (c *PgClient) countSql() string {
 	f := `SELECT COUNT(*)
 FROM %s
@@ -66,6 +201,25 @@ WHERE expiry IS NULL OR expiry > now();`
 
 }
 
+func (c *PgClient) listDetailMetaSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta
+FROM %s
+WHERE starts_with(obj_path,$1) = true AND (expiry IS NULL OR expiry > now())
+AND meta @> $2::jsonb
+ORDER BY obj_path;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) countMetaSql() string {
+	f := `SELECT COUNT(*)
+FROM %s
+WHERE starts_with(obj_path,$1) = true AND (expiry IS NULL OR expiry > now())
+AND meta @> $2::jsonb;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
 func (c *PgClient) loadSql() string {
 	f := `SELECT data
 FROM %s
@@ -74,10 +228,26 @@ WHERE obj_path = $1 AND (expiry IS NULL or expiry > now());`
 
 }
 
+func (c *PgClient) loadManySql() string {
+	f := `SELECT obj_path,data
+FROM %s
+WHERE obj_path = ANY($1) AND (expiry IS NULL or expiry > now());`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) loadVersionSql() string {
+	f := `SELECT data,version
+FROM %s
+WHERE obj_path = $1 AND (expiry IS NULL or expiry > now());`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
 func (c *PgClient) loadDetailSql() string {
-	f := `SELECT obj_path,size,expiry,modified
+	f := `SELECT obj_path,size,expiry,modified,version,meta
 FROM %s
-WHERE starts_with(obj_path,$1) = true AND (expiry IS NULL or expiry > now());`
+WHERE obj_path = $1 AND (expiry IS NULL or expiry > now());`
 	return fmt.Sprintf(f, c.Table)
 
 }
@@ -88,17 +258,352 @@ func (c *PgClient) purgeSql() string {
 
 }
 
+func (c *PgClient) purgeBatchSql() string {
+	f := `DELETE FROM %s
+WHERE obj_path IN (
+	SELECT obj_path FROM %s WHERE expiry <= now()
+	LIMIT $1 FOR UPDATE SKIP LOCKED
+);`
+	return fmt.Sprintf(f, c.Table, c.Table)
+
+}
+
+// dataType is the type of the data column in all tables.
+func (c *PgClient) dataType() string {
+	if c.Binary {
+		return "BYTEA"
+	}
+	return "JSONB"
+}
+
 func (c *PgClient) schemaSql() string {
 
-	f := `CREATE TABLE %s (
+	f := `CREATE TABLE %[1]s (
+	obj_path TEXT NOT NULL PRIMARY KEY,
+	data %[2]s NOT NULL,
+	size INT NOT NULL,
+	expiry TIMESTAMP WITH TIME ZONE NULL,
+	modified TIMESTAMP WITH TIME ZONE NOT NULL,
+	version BIGINT NOT NULL DEFAULT 1,
+	meta JSONB NULL
+);
+CREATE INDEX %[1]s_expiry_idx ON %[1]s USING btree (expiry);`
+
+	return fmt.Sprintf(f, c.Table, c.dataType())
+
+}
+
+func (c *PgClient) findSql(cond string, opts *FindOptions) string {
+
+	if opts == nil {
+		opts = &FindOptions{}
+	}
+	cols := "obj_path,size,expiry,modified,version,meta"
+	if opts.WithData {
+		cols += ",data"
+	}
+	order := "obj_path"
+	if opts.ByModified {
+		order = "modified"
+	}
+	if opts.Descending {
+		order += " DESC"
+	}
+	if opts.ByModified {
+		order += ", obj_path" // stable for equal times
+	}
+	limit := ""
+	if opts.Limit > 0 {
+		limit = fmt.Sprintf("\nLIMIT %d", opts.Limit)
+	}
+
+	f := `SELECT %s
+FROM %s
+WHERE starts_with(obj_path,$1) = true AND (expiry IS NULL OR expiry > now())
+AND %s
+ORDER BY %s%s;`
+	return fmt.Sprintf(f, cols, c.Table, cond, order, limit)
+
+}
+
+func (c *PgClient) dataIndexSql() string {
+	f := `CREATE INDEX IF NOT EXISTS %s_data_idx ON %s USING GIN (data jsonb_path_ops);`
+	return fmt.Sprintf(f, c.Table, c.Table)
+
+}
+
+// NOTE: history is written by a trigger so that every way of replacing or
+// deleting a live object is covered, but not changes to the expiry alone.
+// Updates record the new modification time as the replacement time, so that
+// both come from the same clock; deletes have no new row and use the
+// database clock instead.
+func (c *PgClient) historySchemaSql() string {
+
+	f := `CREATE TABLE IF NOT EXISTS %[1]s_history (
+	obj_path TEXT NOT NULL,
+	data %[2]s NOT NULL,
+	size INT NOT NULL,
+	expiry TIMESTAMP WITH TIME ZONE NULL,
+	modified TIMESTAMP WITH TIME ZONE NOT NULL,
+	version BIGINT NOT NULL,
+	meta JSONB NULL,
+	replaced TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS %[1]s_history_path_idx ON %[1]s_history USING btree (obj_path, replaced);
+CREATE INDEX IF NOT EXISTS %[1]s_history_replaced_idx ON %[1]s_history USING btree (replaced);
+CREATE OR REPLACE FUNCTION %[1]s_history_fn() RETURNS trigger AS $$
+DECLARE
+	replaced_at TIMESTAMP WITH TIME ZONE := clock_timestamp();
+BEGIN
+	IF OLD.expiry IS NOT NULL AND OLD.expiry <= now() THEN
+		RETURN NULL;
+	END IF;
+	IF TG_OP = 'UPDATE' THEN
+		replaced_at := NEW.modified;
+	END IF;
+	INSERT INTO %[1]s_history
+		(obj_path,data,size,expiry,modified,version,meta,replaced)
+	VALUES (OLD.obj_path,OLD.data,OLD.size,OLD.expiry,OLD.modified,
+		OLD.version,OLD.meta,replaced_at);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS %[1]s_history_trigger ON %[1]s;
+CREATE TRIGGER %[1]s_history_trigger AFTER UPDATE OF data OR DELETE ON %[1]s
+FOR EACH ROW EXECUTE FUNCTION %[1]s_history_fn();`
+
+	return fmt.Sprintf(f, c.Table, c.dataType())
+
+}
+
+func (c *PgClient) listVersionsSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta
+FROM %[1]s_history
+WHERE obj_path = $1
+UNION ALL
+SELECT obj_path,size,expiry,modified,version,meta
+FROM %[1]s
+WHERE obj_path = $1 AND (expiry IS NULL OR expiry > now())
+ORDER BY modified;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: the live object sorts after any revision with the same version.
+func (c *PgClient) loadAtVersionSql() string {
+	f := `SELECT data FROM (
+	SELECT data, now() AS at
+	FROM %[1]s
+	WHERE obj_path = $1 AND version = $2 AND (expiry IS NULL OR expiry > now())
+	UNION ALL
+	SELECT data, replaced
+	FROM %[1]s_history
+	WHERE obj_path = $1 AND version = $2
+) AS revs
+ORDER BY at DESC
+LIMIT 1;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) loadAsOfSql() string {
+	f := `SELECT data, version FROM (
+	SELECT data, version, modified
+	FROM %[1]s
+	WHERE obj_path = $1 AND modified <= $2 AND (expiry IS NULL OR expiry > $2)
+	UNION ALL
+	SELECT data, version, modified
+	FROM %[1]s_history
+	WHERE obj_path = $1 AND modified <= $2 AND replaced > $2
+	AND (expiry IS NULL OR expiry > $2)
+) AS revs
+ORDER BY modified DESC
+LIMIT 1;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) pruneHistoryKeepSql() string {
+	f := `DELETE FROM %[1]s_history
+WHERE ctid IN (
+	SELECT ctid FROM (
+		SELECT ctid, row_number() OVER (
+			PARTITION BY obj_path ORDER BY replaced DESC) AS n
+		FROM %[1]s_history
+	) AS revs
+	WHERE n > $1
+);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) pruneHistoryKeepBatchSql() string {
+	f := `DELETE FROM %[1]s_history
+WHERE ctid IN (
+	SELECT ctid FROM (
+		SELECT ctid, row_number() OVER (
+			PARTITION BY obj_path ORDER BY replaced DESC) AS n
+		FROM %[1]s_history
+	) AS revs
+	WHERE n > $1
+	LIMIT $2
+);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) pruneHistoryAgeSql() string {
+	f := "DELETE FROM %s_history WHERE replaced < $1;"
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) pruneHistoryAgeBatchSql() string {
+	f := `DELETE FROM %[1]s_history
+WHERE ctid IN (
+	SELECT ctid FROM %[1]s_history WHERE replaced < $1
+	LIMIT $2 FOR UPDATE SKIP LOCKED
+);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) trashSchemaSql() string {
+
+	f := `CREATE TABLE IF NOT EXISTS %[1]s_trash (
 	obj_path TEXT NOT NULL PRIMARY KEY,
-	data JSONB NOT NULL,
+	data %[2]s NOT NULL,
 	size INT NOT NULL,
 	expiry TIMESTAMP WITH TIME ZONE NULL,
-	modified TIMESTAMP WITH TIME ZONE NOT NULL
+	modified TIMESTAMP WITH TIME ZONE NOT NULL,
+	version BIGINT NOT NULL,
+	meta JSONB NULL,
+	deleted TIMESTAMP WITH TIME ZONE NOT NULL
 );
-CREATE INDEX %s_expiry_idx ON %s USING btree (expiry);`
+CREATE INDEX IF NOT EXISTS %[1]s_trash_deleted_idx ON %[1]s_trash USING btree (deleted);`
+
+	return fmt.Sprintf(f, c.Table, c.dataType())
+
+}
+
+// NOTE: moving to the trash is a single statement, so the object can not be
+// lost in between.  Only the latest deletion of a path is kept.
+func (c *PgClient) trashSql(cond string) string {
+	f := `WITH gone AS (
+	DELETE FROM %[1]s WHERE %[2]s
+	RETURNING obj_path,data,size,expiry,modified,version,meta
+)
+INSERT INTO %[1]s_trash (obj_path,data,size,expiry,modified,version,meta,deleted)
+SELECT obj_path,data,size,expiry,modified,version,meta,now() FROM gone
+ON CONFLICT (obj_path)
+DO UPDATE SET
+	data = EXCLUDED.data,
+	size = EXCLUDED.size,
+	expiry = EXCLUDED.expiry,
+	modified = EXCLUDED.modified,
+	version = EXCLUDED.version,
+	meta = EXCLUDED.meta,
+	deleted = EXCLUDED.deleted;`
+	return fmt.Sprintf(f, c.Table, cond)
+
+}
+
+func (c *PgClient) untrashSql() string {
+	f := `DELETE FROM %s_trash
+WHERE obj_path = $1
+RETURNING data,size,expiry,modified,version,meta;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: like saveIfNotExistsSql, an expired object may be replaced.
+func (c *PgClient) undeleteSql() string {
+	f := `INSERT INTO %[1]s (obj_path,data,size,expiry,modified,version,meta)
+VALUES ($1,$2,$3,$4,$5,$6,$7)
+ON CONFLICT (obj_path)
+DO UPDATE SET
+	data = EXCLUDED.data,
+	size = EXCLUDED.size,
+	expiry = EXCLUDED.expiry,
+	modified = EXCLUDED.modified,
+	version = EXCLUDED.version,
+	meta = EXCLUDED.meta
+WHERE %[1]s.expiry IS NOT NULL AND %[1]s.expiry <= now();`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) listDeletedSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta,deleted
+FROM %s_trash
+WHERE starts_with(obj_path,$1) = true
+ORDER BY obj_path;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: the retention is passed in microseconds, and compared to the
+// database clock as that is what set the deletion time.
+func (c *PgClient) purgeTrashSql() string {
+	f := `DELETE FROM %s_trash
+WHERE deleted <= now() - $1 * interval '1 microsecond';`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *PgClient) purgeTrashBatchSql() string {
+	f := `DELETE FROM %[1]s_trash
+WHERE obj_path IN (
+	SELECT obj_path FROM %[1]s_trash
+	WHERE deleted <= now() - $1 * interval '1 microsecond'
+	LIMIT $2 FOR UPDATE SKIP LOCKED
+);`
+	return fmt.Sprintf(f, c.Table)
+
+}
 
-	return fmt.Sprintf(f, c.Table, c.Table, c.Table)
+// NOTE: this copies the live objects matching cond to the paths given by
+// the dst expression, keeping their expiry and metadata, and deletes the
+// sources that were copied if remove is true.  Only expired objects are
+// replaced at the destination unless replace is true.  The numbers of
+// objects found and copied are returned.
+func (c *PgClient) moveSql(cond string, dst string, replace bool, remove bool) string {
+	f := `WITH src AS (
+	SELECT obj_path,%[3]s AS dst_path,data,size,expiry,meta
+	FROM %[1]s
+	WHERE %[2]s AND (expiry IS NULL OR expiry > now())
+	FOR UPDATE
+),
+copied AS (
+	INSERT INTO %[1]s (obj_path,data,size,expiry,modified,version,meta)
+	SELECT dst_path,data,size,expiry,$3::timestamptz,1,meta FROM src ORDER BY dst_path
+	ON CONFLICT (obj_path)
+	DO UPDATE SET
+		data = EXCLUDED.data,
+		size = EXCLUDED.size,
+		expiry = EXCLUDED.expiry,
+		modified = EXCLUDED.modified,
+		version = CASE WHEN %[1]s.expiry IS NULL OR %[1]s.expiry > now()
+			THEN %[1]s.version + 1 ELSE 1 END,
+		meta = EXCLUDED.meta%[4]s
+	RETURNING obj_path
+)%[5]s
+SELECT (SELECT count(*) FROM src), (SELECT count(*) FROM copied);`
+	where := ""
+	if !replace {
+		where = fmt.Sprintf(
+			"\n\tWHERE %[1]s.expiry IS NOT NULL AND %[1]s.expiry <= now()", c.Table)
+	}
+	del := ""
+	if remove {
+		del = fmt.Sprintf(`,
+removed AS (
+	DELETE FROM %s
+	WHERE obj_path IN (
+		SELECT src.obj_path FROM src JOIN copied ON copied.obj_path = src.dst_path
+	)
+)`, c.Table)
+	}
+	return fmt.Sprintf(f, c.Table, cond, dst, where, del)
 
 }
@@ -0,0 +1,91 @@
+// trash.go - soft deletion
+//
+// Deleted objects are moved to a second table, <Table>_trash, if SoftDelete
+// is set, so every other query is unaware of them.
+
+package pgclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// PgDeletedDetailer implements backend.DeletedDetailer to describe an
+// object in the trash.
+type PgDeletedDetailer struct {
+	PgDetailer
+	deleted time.Time
+}
+
+// Scan scans a database row.
+func (d *PgDeletedDetailer) Scan(row pgx.Row) error {
+	return row.Scan(&d.path, &d.size, &d.expiry, &d.modified, &d.version,
+		&d.meta, &d.deleted)
+}
+
+// Deleted implements backend.DeletedDetailer.
+func (d *PgDeletedDetailer) Deleted() time.Time {
+	return d.deleted
+}
+
+// TrashSchema returns the SQL required to create the trash table for this
+// client's Table.
+func (c *PgClient) TrashSchema() string {
+	return c.trashSchemaSql()
+}
+
+// CreateTrash executes the SQL returned from TrashSchema on the current
+// database.  It must be called before setting SoftDelete, and may be called
+// again safely.
+func (c *PgClient) CreateTrash() error {
+
+	_, err := c.Pool.Exec(context.Background(), c.TrashSchema())
+	return err
+}
+
+// UndeleteCtx implements backend.Trasher.  The object is removed from the
+// trash and restored in one transaction.
+func (c *PgClient) UndeleteCtx(ctx context.Context, path string) error {
+
+	tx, err := c.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	d := &PgDetailer{path: path}
+	var data []byte
+	row := tx.QueryRow(ctx, c.untrashSql(), path)
+	err = row.Scan(&data, &d.size, &d.expiry, &d.modified, &d.version, &d.meta)
+	if err != nil {
+		return translate(err)
+	}
+	tag, err := tx.Exec(ctx, c.undeleteSql(),
+		path, data, d.size, d.expiry, d.modified, d.version, d.meta)
+	if err != nil {
+		return translate(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s exists", backend.ErrConflict, path)
+	}
+	return tx.Commit(ctx)
+
+}
+
+// ListDeletedCtx implements backend.Trasher.
+func (c *PgClient) ListDeletedCtx(ctx context.Context, prefix string) ([]backend.DeletedDetailer, error) {
+
+	rows, _ := c.Pool.Query(ctx, c.listDeletedSql(), prefix)
+	return pgx.CollectRows(rows,
+		func(row pgx.CollectableRow) (backend.DeletedDetailer, error) {
+			d := &PgDeletedDetailer{}
+			err := d.Scan(row)
+			return d, err
+		})
+
+}
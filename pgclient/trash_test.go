@@ -0,0 +1,76 @@
+// trash_test.go -- tests for soft deletion.
+//
+// The tests common to all Trasher backends are in backendtest.
+
+package pgclient_test
+
+import (
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
+)
+
+// softDelete turns on SoftDelete for the rest of the test.
+func (suite *PgClientTestSuite) softDelete() {
+
+	suite.Client.SoftDelete = true
+	suite.T().Cleanup(func() { suite.Client.SoftDelete = false })
+
+}
+
+func (suite *PgClientTestSuite) TestImplementsTrasher() {
+
+	require := suite.Require()
+	require.Implements((*backend.Trasher)(nil), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestCreateTrashAgainOK() {
+
+	require := suite.Require()
+
+	require.Contains(suite.Client.TrashSchema(), "_trash")
+	require.NoError(suite.Client.CreateTrash())
+
+}
+
+func (suite *PgClientTestSuite) TestSoftDeleteOK() {
+	suite.softDelete()
+	backendtest.SoftDeleteOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestSoftDeleteManyOK() {
+	suite.softDelete()
+	backendtest.SoftDeleteManyOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestSoftDeletePrefixOK() {
+	suite.softDelete()
+	backendtest.SoftDeletePrefixOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestHardDeleteSkipsTrashOK() {
+	backendtest.HardDeleteSkipsTrashOK(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestUndeleteFailsNotFound() {
+	backendtest.UndeleteFailsNotFound(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestUndeleteFailsConflict() {
+	suite.softDelete()
+	backendtest.UndeleteFailsConflict(suite.Require(), suite.Client)
+}
+
+func (suite *PgClientTestSuite) TestPurgeTrashOK() {
+
+	require := suite.Require()
+
+	suite.softDelete()
+	defer func(d time.Duration) { suite.Client.TrashRetention = d }(suite.Client.TrashRetention)
+	require.Equal(7*24*time.Hour, suite.Client.TrashRetention, "default")
+	backendtest.PurgeTrashOK(require, suite.Client, func() {
+		suite.Client.TrashRetention = 0
+	})
+
+}
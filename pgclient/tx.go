@@ -0,0 +1,62 @@
+// tx.go - transactions spanning several operations
+
+package pgclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// querier runs statements on the Pool, or within a transaction for PgTx.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// PgTx implements backend.TxClient within a pgx.Tx.  The client's settings,
+// such as SoftDelete, apply as they do outside the transaction.
+type PgTx struct {
+	client *PgClient
+	tx     pgx.Tx
+}
+
+// TxCtx implements backend.Transactor over a pgx.Tx, which is committed if
+// fn returns nil and otherwise rolled back.
+func (c *PgClient) TxCtx(ctx context.Context, fn func(backend.TxClient) error) error {
+
+	return pgx.BeginFunc(ctx, c.Pool, func(tx pgx.Tx) error {
+		return fn(&PgTx{client: c, tx: tx})
+	})
+
+}
+
+// SaveRawCtx implements backend.TxClient.
+func (t *PgTx) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	return t.client.save(ctx, t.tx, path, raw_obj, nil)
+}
+
+// SaveRawExpiryCtx implements backend.TxClient.
+func (t *PgTx) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	return t.client.save(ctx, t.tx, path, raw_obj, &expiry)
+}
+
+// LoadRawCtx implements backend.TxClient.
+func (t *PgTx) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+	return t.client.loadRaw(ctx, t.tx, path)
+}
+
+// DeleteCtx implements backend.TxClient.
+func (t *PgTx) DeleteCtx(ctx context.Context, path string) error {
+	return t.client.delete(ctx, t.tx, path)
+}
+
+// ListCtx implements backend.TxClient.
+func (t *PgTx) ListCtx(ctx context.Context, prefix string) ([]string, error) {
+	return t.client.list(ctx, t.tx, prefix)
+}
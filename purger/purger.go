@@ -0,0 +1,147 @@
+// purger.go -- background purging of expired objects.
+//
+// Lives in its own package so that both jsobs.Client and the backends can
+// use it without circular imports.
+
+// Package purger runs a backend's Purge in the background on a timer.
+package purger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// ErrRunning is returned by Start if the Purger is already running.
+var ErrRunning = errors.New("purger already running")
+
+// Purger calls its Target's purge function every Interval until stopped.
+//
+// If BatchSize is positive and the Target is a backend.BatchPurger, each
+// run deletes at most BatchSize objects per statement, repeating until a
+// batch comes up short.  Otherwise each run is a single full purge.
+//
+// OnPurge, if set, is called after every run with the number of objects
+// deleted in that run and any error.  It is called from the purging
+// goroutine, so it should not block for long.
+type Purger struct {
+	Target    backend.Purger
+	Interval  time.Duration
+	BatchSize int
+	OnPurge   func(purged int, err error)
+
+	mutex sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// New returns a Purger for target with the given interval and batch size,
+// which is not yet running.
+func New(target backend.Purger, interval time.Duration, batch_size int) *Purger {
+	return &Purger{
+		Target:    target,
+		Interval:  interval,
+		BatchSize: batch_size,
+	}
+}
+
+// Start starts purging in the background.  The first purge happens after
+// one Interval has passed.
+func (p *Purger) Start() error {
+
+	if p.Interval <= 0 {
+		return errors.New("purger interval must be positive")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.stop != nil {
+		return ErrRunning
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.loop(p.stop, p.done)
+	return nil
+}
+
+// Running returns true if the Purger has been started and not stopped.
+func (p *Purger) Running() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.stop != nil
+}
+
+// Stop stops the Purger, waiting for any purge in progress to complete.
+// A batched purge stops after the current batch.  Calling Stop on a Purger
+// that is not running does nothing.
+func (p *Purger) Stop() {
+
+	p.mutex.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mutex.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (p *Purger) loop(stop, done chan struct{}) {
+
+	defer close(done)
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// Both may be ready, in which case select picks at random.
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			purged, err := p.run(context.Background(), stop)
+			if p.OnPurge != nil {
+				p.OnPurge(purged, err)
+			}
+		}
+	}
+}
+
+// RunOnce performs a single purge run as the background loop would, and
+// returns the number of objects deleted.  It does not call OnPurge.
+func (p *Purger) RunOnce(ctx context.Context) (int, error) {
+	return p.run(ctx, nil)
+}
+
+func (p *Purger) run(ctx context.Context, stop chan struct{}) (int, error) {
+
+	bp, ok := p.Target.(backend.BatchPurger)
+	if !ok || p.BatchSize <= 0 {
+		if cp, ok := p.Target.(backend.ContextPurger); ok {
+			return cp.PurgeCtx(ctx)
+		}
+		return p.Target.Purge()
+	}
+
+	total := 0
+	for {
+		purged, err := bp.PurgeBatchCtx(ctx, p.BatchSize)
+		total += purged
+		if err != nil || purged < p.BatchSize {
+			return total, err
+		}
+		select {
+		case <-stop:
+			return total, nil
+		default:
+		}
+	}
+}
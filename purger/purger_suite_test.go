@@ -0,0 +1,90 @@
+// purger_suite_test.go -- test suite rigging
+
+package purger_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// pretendStore has a pretend number of expired objects, and records calls.
+type pretendStore struct {
+	mutex     sync.Mutex
+	expired   int
+	calls     []string
+	delay     time.Duration
+	nextError error
+}
+
+func (t *pretendStore) record(name string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.calls = append(t.calls, name)
+}
+
+func (t *pretendStore) Calls() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]string{}, t.calls...)
+}
+
+func (t *pretendStore) take(limit int) int {
+	time.Sleep(t.delay)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	n := t.expired
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	t.expired -= n
+	return n
+}
+
+func (t *pretendStore) Expired() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.expired
+}
+
+// TestTarget is a BatchPurger.
+type TestTarget struct {
+	pretendStore
+}
+
+func (t *TestTarget) Purge() (int, error) {
+	t.record("Purge")
+	return t.take(0), t.nextError
+}
+
+func (t *TestTarget) PurgeBatchCtx(ctx context.Context, limit int) (int, error) {
+	t.record("PurgeBatchCtx")
+	return t.take(limit), t.nextError
+}
+
+// TestCtxTarget is a ContextPurger but not a BatchPurger.
+type TestCtxTarget struct {
+	pretendStore
+}
+
+func (t *TestCtxTarget) Purge() (int, error) {
+	t.record("Purge")
+	return t.take(0), t.nextError
+}
+
+func (t *TestCtxTarget) PurgeCtx(ctx context.Context) (int, error) {
+	t.record("PurgeCtx")
+	return t.take(0), t.nextError
+}
+
+type PurgerTestSuite struct {
+	suite.Suite
+}
+
+// The actual runner func:
+func TestPurgerTestSuite(t *testing.T) {
+	suite.Run(t, new(PurgerTestSuite))
+}
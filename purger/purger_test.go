@@ -0,0 +1,175 @@
+// purger_test.go
+//
+// suite rigging is in purger_suite_test.go, actual tests are here.
+
+package purger_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/biztos/jsobs/memclient"
+	"github.com/biztos/jsobs/purger"
+)
+
+func (suite *PurgerTestSuite) TestStartFailsBadInterval() {
+
+	require := suite.Require()
+
+	p := purger.New(&TestTarget{}, 0, 0)
+	require.ErrorContains(p.Start(), "interval must be positive")
+	require.False(p.Running())
+
+}
+
+func (suite *PurgerTestSuite) TestStartFailsRunning() {
+
+	require := suite.Require()
+
+	p := purger.New(&TestTarget{}, time.Hour, 0)
+	require.NoError(p.Start())
+	defer p.Stop()
+	require.ErrorIs(p.Start(), purger.ErrRunning)
+	require.True(p.Running())
+
+}
+
+func (suite *PurgerTestSuite) TestStopNotRunningOK() {
+
+	require := suite.Require()
+
+	p := purger.New(&TestTarget{}, time.Hour, 0)
+	p.Stop()
+	require.NoError(p.Start())
+	p.Stop()
+	p.Stop()
+	require.False(p.Running())
+
+	// Restartable.
+	require.NoError(p.Start())
+	p.Stop()
+
+}
+
+func (suite *PurgerTestSuite) TestRunOnceFullOK() {
+
+	require := suite.Require()
+
+	target := &TestTarget{pretendStore{expired: 25}}
+	p := purger.New(target, time.Hour, 0)
+
+	purged, err := p.RunOnce(context.Background())
+	require.NoError(err)
+	require.Equal(25, purged)
+	require.Equal([]string{"Purge"}, target.Calls())
+
+}
+
+func (suite *PurgerTestSuite) TestRunOnceContextOK() {
+
+	require := suite.Require()
+
+	target := &TestCtxTarget{pretendStore{expired: 25}}
+	p := purger.New(target, time.Hour, 10)
+
+	purged, err := p.RunOnce(context.Background())
+	require.NoError(err)
+	require.Equal(25, purged)
+	require.Equal([]string{"PurgeCtx"}, target.Calls(), "not batched")
+
+}
+
+func (suite *PurgerTestSuite) TestRunOnceBatchedOK() {
+
+	require := suite.Require()
+
+	target := &TestTarget{pretendStore{expired: 25}}
+	p := purger.New(target, time.Hour, 10)
+
+	purged, err := p.RunOnce(context.Background())
+	require.NoError(err)
+	require.Equal(25, purged)
+	require.Equal([]string{"PurgeBatchCtx", "PurgeBatchCtx", "PurgeBatchCtx"},
+		target.Calls())
+
+}
+
+func (suite *PurgerTestSuite) TestRunOnceBatchedError() {
+
+	require := suite.Require()
+
+	target := &TestTarget{pretendStore{expired: 25, nextError: errors.New("oops")}}
+	p := purger.New(target, time.Hour, 10)
+
+	purged, err := p.RunOnce(context.Background())
+	require.ErrorContains(err, "oops")
+	require.Equal(10, purged)
+	require.Equal([]string{"PurgeBatchCtx"}, target.Calls())
+
+}
+
+func (suite *PurgerTestSuite) TestBackgroundHookOK() {
+
+	require := suite.Require()
+
+	client := memclient.New()
+	past := time.Now().Add(-time.Hour)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		require.NoError(client.SaveRawExpiry(path, []byte(`{}`), past))
+	}
+
+	var mutex sync.Mutex
+	runs := []int{}
+	p := purger.New(client, 5*time.Millisecond, 0)
+	p.OnPurge = func(purged int, err error) {
+		require.NoError(err)
+		mutex.Lock()
+		runs = append(runs, purged)
+		mutex.Unlock()
+	}
+	require.NoError(p.Start())
+	require.Eventually(func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(runs) >= 2
+	}, time.Second, time.Millisecond)
+	p.Stop()
+
+	require.Equal(3, runs[0], "first run")
+	require.Equal(0, runs[1], "second run")
+	require.Equal("memclient (objects=0)", client.String())
+
+}
+
+func (suite *PurgerTestSuite) TestStopWaitsForBatchOK() {
+
+	require := suite.Require()
+
+	target := &TestTarget{pretendStore{expired: 100, delay: 20 * time.Millisecond}}
+	started := make(chan struct{})
+	var once sync.Once
+	done := make(chan int, 1)
+	p := purger.New(target, time.Millisecond, 10)
+	p.OnPurge = func(purged int, err error) {
+		done <- purged
+	}
+
+	// Wait for the first batch to start, then stop.
+	go func() {
+		for len(target.Calls()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		once.Do(func() { close(started) })
+	}()
+	require.NoError(p.Start())
+	<-started
+	p.Stop()
+
+	// The run was cut short after the in-flight batch completed.
+	purged := <-done
+	require.Equal(10, purged, "one batch")
+	require.Equal(90, target.Expired(), "rest left for later")
+
+}
@@ -0,0 +1,281 @@
+// fakes3_test.go -- a tiny in-process S3 server, just enough for the tests.
+//
+// Supports path-style PUT, GET, HEAD and DELETE of objects with user
+// metadata, and ListObjectsV2 (without delimiters) with forced small pages
+// so that paging is exercised.  PUT honors If-Match, and If-None-Match for
+// "*" only.  Authentication is ignored.
+
+package s3client_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type fakeObject struct {
+	data     []byte
+	meta     http.Header
+	modified time.Time
+	etag     string
+}
+
+type FakeS3 struct {
+	Bucket   string
+	PageSize int
+
+	mutex   sync.Mutex
+	objects map[string]*fakeObject
+	Lists   int         // ListObjectsV2 calls, for checking paging
+	LastPut http.Header // headers of the last PUT, for checking options
+}
+
+func NewFakeS3(bucket string) *FakeS3 {
+	return &FakeS3{
+		Bucket:   bucket,
+		PageSize: 4,
+		objects:  map[string]*fakeObject{},
+	}
+}
+
+// Keys returns the stored keys, sorted.
+func (f *FakeS3) Keys() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	keys := []string{}
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Reset removes all objects.
+func (f *FakeS3) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.objects = map[string]*fakeObject{}
+	f.Lists = 0
+	f.LastPut = nil
+}
+
+func (f *FakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>",
+		code, code)
+}
+
+func (f *FakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if parts[0] != f.Bucket {
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if len(parts) == 1 || parts[1] == "" {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r)
+			return
+		}
+		f.error(w, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+	key := parts[1]
+
+	switch r.Method {
+	case http.MethodPut:
+		f.put(w, r, key)
+	case http.MethodGet, http.MethodHead:
+		f.get(w, r, key)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// readChunked decodes an aws-chunked body as sent with streaming signatures.
+func readChunked(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	var out bytes.Buffer
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.ParseInt(strings.SplitN(strings.TrimSpace(line), ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return out.Bytes(), nil
+		}
+		if _, err := io.CopyN(&out, br, size); err != nil {
+			return nil, err
+		}
+		if _, err := br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *FakeS3) put(w http.ResponseWriter, r *http.Request, key string) {
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		data, err = readChunked(r.Body)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		f.error(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+	f.LastPut = r.Header.Clone()
+	if match := r.Header.Get("If-None-Match"); strings.Trim(match, `"`) == "*" {
+		if f.objects[key] != nil {
+			f.error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+	}
+	if match := r.Header.Get("If-Match"); match != "" {
+		if old := f.objects[key]; old == nil || old.etag != match {
+			f.error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+	}
+	meta := http.Header{}
+	for k, v := range r.Header {
+		if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") ||
+			k == "Content-Type" {
+			meta[k] = v
+		}
+	}
+	sum := md5.Sum(data)
+	obj := &fakeObject{
+		data:     data,
+		meta:     meta,
+		modified: time.Now(),
+		etag:     `"` + hex.EncodeToString(sum[:]) + `"`,
+	}
+	f.objects[key] = obj
+	w.Header().Set("ETag", obj.etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *FakeS3) get(w http.ResponseWriter, r *http.Request, key string) {
+
+	obj := f.objects[key]
+	if obj == nil {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	for k, v := range obj.meta {
+		w.Header()[k] = v
+	}
+	w.Header().Set("ETag", obj.etag)
+	w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(obj.data)
+	}
+}
+
+type listContents struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int
+	StorageClass string
+}
+
+type listResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Name                  string
+	Prefix                string
+	KeyCount              int
+	MaxKeys               int
+	IsTruncated           bool
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	StartAfter            string `xml:",omitempty"`
+	Contents              []listContents
+}
+
+func (f *FakeS3) list(w http.ResponseWriter, r *http.Request) {
+
+	f.Lists++
+
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	after := q.Get("start-after")
+	if token := q.Get("continuation-token"); token != "" {
+		after = token
+	}
+	max := f.PageSize
+	if mk, err := strconv.Atoi(q.Get("max-keys")); err == nil && mk > 0 && mk < max {
+		max = mk
+	}
+
+	keys := []string{}
+	for k := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	res := &listResult{
+		Name:              f.Bucket,
+		Prefix:            prefix,
+		MaxKeys:           max,
+		ContinuationToken: q.Get("continuation-token"),
+		StartAfter:        q.Get("start-after"),
+	}
+	last := ""
+	for _, k := range keys {
+		if k <= after {
+			continue
+		}
+		if res.KeyCount == max {
+			res.IsTruncated = true
+			res.NextContinuationToken = last
+			break
+		}
+		obj := f.objects[k]
+		res.Contents = append(res.Contents, listContents{
+			Key:          k,
+			LastModified: obj.modified.UTC().Format(time.RFC3339Nano),
+			ETag:         obj.etag,
+			Size:         len(obj.data),
+			StorageClass: "STANDARD",
+		})
+		res.KeyCount++
+		last = k
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	xml.NewEncoder(w).Encode(res)
+}
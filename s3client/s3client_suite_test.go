@@ -0,0 +1,98 @@
+// s3client_suite_test.go -- test suite rigging
+//
+// Tests run against the fake server in fakes3_test.go, so no S3 account is
+// needed.
+
+package s3client_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/biztos/jsobs/s3client"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type S3ClientTestSuite struct {
+	suite.Suite
+	Client *s3client.S3Client
+	Fake   *FakeS3
+	Server *httptest.Server
+	Url    string
+}
+
+// One fake server and client per suite.
+func (suite *S3ClientTestSuite) SetupSuite() {
+
+	require := suite.Require()
+
+	suite.Fake = NewFakeS3("jsobs-test")
+	suite.Server = httptest.NewServer(suite.Fake)
+	host := strings.TrimPrefix(suite.Server.URL, "http://")
+	suite.Url = fmt.Sprintf("http://key:secret@%s/jsobs-test?region=us-east-1", host)
+
+	mc, err := minio.New(host, &minio.Options{
+		Creds:        credentials.NewStaticV4("key", "secret", ""),
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	require.NoError(err, "minio client")
+	suite.Client = s3client.NewForClient(mc, "jsobs-test")
+
+}
+
+func (suite *S3ClientTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+// Empty the bucket per test, so there are no fragments.
+func (suite *S3ClientTestSuite) SetupTest() {
+	suite.Fake.Reset()
+}
+
+// see below...
+type SaveSetResult struct {
+	Paths    []string
+	PathData map[string][]byte
+}
+
+// We need sets of data a lot, with expiry in future or not at all.
+func (suite *S3ClientTestSuite) SaveSet(count int, pfmt string, exp *time.Time) *SaveSetResult {
+
+	require := suite.Require() // bail out on err
+
+	paths := make([]string, count)
+	path_data := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		path := fmt.Sprintf(pfmt, i)
+		data := []byte(fmt.Sprintf(`{"n":%d}`, i))
+		paths[i] = path
+		path_data[path] = data
+		var err error
+		if exp == nil {
+			err = suite.Client.SaveRaw(path, data)
+		} else {
+			err = suite.Client.SaveRawExpiry(path, data, *exp)
+		}
+
+		require.NoError(err, "save error")
+
+	}
+	return &SaveSetResult{
+		Paths:    paths,
+		PathData: path_data,
+	}
+
+}
+
+// The actual runner func:
+func TestS3ClientTestSuite(t *testing.T) {
+	suite.Run(t, new(S3ClientTestSuite))
+}
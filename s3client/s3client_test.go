@@ -0,0 +1,732 @@
+// s3client_test.go
+//
+// suite rigging is in s3client_suite_test.go, actual tests are here.
+
+package s3client_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/s3client"
+)
+
+func (suite *S3ClientTestSuite) TestNewFailsNoUrl() {
+
+	require := suite.Require()
+
+	s3client.S3UrlEnvVar = "OTHER_S3_URL"
+	os.Setenv("OTHER_S3_URL", "")
+	defer func() { s3client.S3UrlEnvVar = "S3_URL" }()
+
+	_, err := s3client.New()
+	require.ErrorContains(err, "OTHER_S3_URL not defined in env")
+
+}
+
+func (suite *S3ClientTestSuite) TestNewFailsBadUrls() {
+
+	require := suite.Require()
+
+	orig := os.Getenv("S3_URL")
+	defer os.Setenv("S3_URL", orig)
+
+	bad := map[string]string{
+		"::nope":                 "missing protocol scheme",
+		"ftp://host/bucket":      "scheme must be http or https",
+		"https://host":           "must specify exactly one bucket",
+		"https://host/b/c":       "must specify exactly one bucket",
+		"https://ho st/bucket":   "invalid character",
+		"https://host:xx/bucket": "invalid port",
+	}
+	for u, msg := range bad {
+		os.Setenv("S3_URL", u)
+		_, err := s3client.New()
+		require.ErrorContains(err, msg, u)
+	}
+
+}
+
+func (suite *S3ClientTestSuite) TestNewUsesEnvOK() {
+
+	require := suite.Require()
+
+	orig := os.Getenv("S3_URL")
+	defer os.Setenv("S3_URL", orig)
+	os.Setenv("S3_URL", suite.Url)
+
+	c, err := s3client.New()
+	require.NoError(err)
+	require.Equal("jsobs-test", c.Bucket)
+	require.True(c.PurgeOnShutdown)
+
+	require.NoError(c.SaveRaw("/via/env", []byte(`{}`)))
+	require.Equal([]string{"via/env"}, suite.Fake.Keys())
+
+}
+
+func (suite *S3ClientTestSuite) TestStringOK() {
+
+	require := suite.Require()
+
+	require.Equal("s3client (bucket=jsobs-test)", suite.Client.String(), "String")
+}
+
+func (suite *S3ClientTestSuite) TestInvalidPathsFail() {
+
+	require := suite.Require()
+
+	for _, path := range []string{"", "/", "no/slash"} {
+		err := suite.Client.SaveRaw(path, []byte(`{}`))
+		require.ErrorIs(err, s3client.ErrInvalidPath, "save %q", path)
+		_, err = suite.Client.LoadRaw(path)
+		require.ErrorIs(err, s3client.ErrInvalidPath, "load %q", path)
+		_, err = suite.Client.LoadDetail(path)
+		require.ErrorIs(err, s3client.ErrInvalidPath, "detail %q", path)
+		err = suite.Client.Delete(path)
+		require.ErrorIs(err, s3client.ErrInvalidPath, "delete %q", path)
+	}
+
+}
+
+func (suite *S3ClientTestSuite) TestSaveRawFailsBadJson() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveRaw("/any", []byte("not json"))
+	require.ErrorIs(err, s3client.ErrInvalidJson)
+
+}
+
+func (suite *S3ClientTestSuite) TestSaveRawFailsNoBucket() {
+
+	require := suite.Require()
+
+	c := s3client.NewForClient(suite.Client.Client, "nonesuch")
+	err := c.SaveRaw("/any", []byte(`{}`))
+	require.ErrorContains(err, "NoSuchBucket")
+
+}
+
+func (suite *S3ClientTestSuite) TestLoadRawFailsNotFound() {
+
+	require := suite.Require()
+
+	data, err := suite.Client.LoadRaw("/not/here")
+	require.ErrorIs(err, s3client.ErrNotFound)
+	require.ErrorIs(err, backend.ErrNotFound)
+	require.NotErrorIs(err, backend.ErrExpired)
+	require.Nil(data)
+
+}
+
+func (suite *S3ClientTestSuite) TestLoadRawFailsExpired() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/expired/%d", &past)
+
+	data, err := suite.Client.LoadRaw("/expired/0")
+	require.ErrorIs(err, s3client.ErrNotFound)
+	require.ErrorIs(err, backend.ErrExpired)
+	require.Nil(data)
+
+	detail, err := suite.Client.LoadDetail("/expired/0")
+	require.ErrorIs(err, backend.ErrExpired)
+	require.Nil(detail)
+
+}
+
+func (suite *S3ClientTestSuite) TestSaveRawLoadRawOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	err := suite.Client.SaveRaw("/any/thing.json", data)
+	require.NoError(err)
+	require.Equal([]string{"any/thing.json"}, suite.Fake.Keys(), "keys")
+
+	fetched, err := suite.Client.LoadRaw("/any/thing.json")
+	require.NoError(err)
+	require.EqualValues(data, fetched)
+
+}
+
+func (suite *S3ClientTestSuite) TestSaveRawExpiryLoadRawOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	err := suite.Client.SaveRawExpiry("/any/thing.json", data,
+		time.Now().Add(time.Hour))
+	require.NoError(err)
+
+	fetched, err := suite.Client.LoadRaw("/any/thing.json")
+	require.NoError(err)
+	require.EqualValues(data, fetched)
+
+}
+
+func (suite *S3ClientTestSuite) TestLoadDetailFailsNotFound() {
+
+	require := suite.Require()
+	detail, err := suite.Client.LoadDetail("/nopers.json")
+	require.ErrorIs(err, s3client.ErrNotFound, "not found")
+	require.Nil(detail, "detail returned")
+}
+
+func (suite *S3ClientTestSuite) TestLoadDetailFailsExpired() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/expired/%d", &past)
+
+	detail, err := suite.Client.LoadDetail("/expired/0")
+	require.ErrorIs(err, s3client.ErrNotFound, "not found")
+	require.Nil(detail, "detail returned")
+}
+
+func (suite *S3ClientTestSuite) TestLoadDetailWithExpiryOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+
+	suite.SaveSet(1, "/detail/%d", &future)
+
+	detail, err := suite.Client.LoadDetail("/detail/0")
+	require.NoError(err, "load detail")
+
+	require.Equal("/detail/0", detail.Path(), "path")
+	require.Equal(7, detail.Size(), "size")
+	require.True(detail.Expires(), "expires")
+	require.WithinDuration(future, detail.Expiry(), time.Millisecond, "expiry")
+
+	// S3 only gives us whole seconds.
+	require.WithinDuration(time.Now(), detail.Modified(), 2*time.Second,
+		"modified")
+
+}
+
+func (suite *S3ClientTestSuite) TestLoadDetailWithoutExpiryOK() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/detail/%d", nil)
+
+	detail, err := suite.Client.LoadDetail("/detail/0")
+	require.NoError(err, "load detail")
+
+	require.Equal("/detail/0", detail.Path(), "path")
+	require.Equal(7, detail.Size(), "size")
+	require.False(detail.Expires(), "expires")
+	require.True(detail.Expiry().IsZero(), "expiry is zero time")
+
+}
+
+func (suite *S3ClientTestSuite) TestCountingOK() {
+
+	require := suite.Require()
+
+	precount, err := suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(0, precount, "nothing there yet")
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(15, "/foo/%d", nil)
+	suite.SaveSet(5, "/foo/expired/%d", &past)
+
+	count, err := suite.Client.Count("/foo")
+	require.NoError(err)
+	require.Equal(15, count, "prefix count")
+
+	count, err = suite.Client.Count("foo")
+	require.NoError(err)
+	require.Equal(0, count, "prefix without slash count")
+
+	count, err = suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(15, count, "full count")
+
+}
+
+func (suite *S3ClientTestSuite) TestListPagesOK() {
+
+	require := suite.Require()
+
+	saved := suite.SaveSet(15, "/any/thing/%02d.json", nil)
+
+	paths, err := suite.Client.List("/any/thing")
+	require.NoError(err)
+	require.EqualValues(saved.Paths, paths, "paths")
+	require.Equal(4, suite.Fake.Lists, "pages fetched")
+
+}
+
+func (suite *S3ClientTestSuite) TestListEmptyOK() {
+
+	require := suite.Require()
+	paths, err := suite.Client.List("/no/such/stuff")
+	require.NoError(err)
+	require.EqualValues([]string{}, paths, "empty path list")
+
+}
+
+func (suite *S3ClientTestSuite) TestListFailsNoBucket() {
+
+	require := suite.Require()
+
+	c := s3client.NewForClient(suite.Client.Client, "nonesuch")
+	_, err := c.List("/")
+	require.ErrorContains(err, "NoSuchBucket")
+
+}
+
+func (suite *S3ClientTestSuite) TestListDetailEmptyOK() {
+
+	require := suite.Require()
+	detailers, err := suite.Client.ListDetail("/")
+	require.NoError(err)
+	require.EqualValues([]backend.Detailer{}, detailers, "empty list")
+}
+
+func (suite *S3ClientTestSuite) TestListDetailsOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(3, "/detail/exp/%d", &future)
+	suite.SaveSet(3, "/detail/expired/%d", &past)
+	suite.SaveSet(3, "/detail/noexp/%d", nil)
+
+	detailers, err := suite.Client.ListDetail("/detail/")
+	require.NoError(err)
+	require.Equal(6, len(detailers), "detailers returned")
+
+	// They will have come back in path order.
+	for i, d := range detailers {
+		if i < 3 {
+			require.Equal(fmt.Sprintf("/detail/exp/%d", i), d.Path(), "path %d", i)
+			require.True(d.Expires(), "expires %d", i)
+			require.WithinDuration(future, d.Expiry(), time.Millisecond, "expiry %d", i)
+
+		} else {
+			require.Equal(fmt.Sprintf("/detail/noexp/%d", i-3), d.Path(), "path %d", i)
+			require.False(d.Expires(), "expires %d", i)
+			require.True(d.Expiry().IsZero(), "expiry is zero time %d", i)
+
+		}
+	}
+
+}
+
+func (suite *S3ClientTestSuite) TestDeleteFailsNotFound() {
+
+	require := suite.Require()
+
+	err := suite.Client.Delete("/not/there")
+	require.ErrorIs(err, s3client.ErrNotFound)
+}
+
+func (suite *S3ClientTestSuite) TestDeleteOK() {
+
+	require := suite.Require()
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/any/thing/%d.json", nil)
+	suite.SaveSet(1, "/any/expired/%d.json", &past)
+
+	require.NoError(suite.Client.Delete("/any/thing/0.json"))
+	require.NoError(suite.Client.Delete("/any/expired/0.json"), "expired")
+	require.Empty(suite.Fake.Keys())
+
+}
+
+func (suite *S3ClientTestSuite) TestPurgeOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-1 * time.Hour)
+	keep_a := suite.SaveSet(5, "/keep/a/%02d.json", &future)
+	suite.SaveSet(5, "/purge/%02d.json", &past)
+	keep_b := suite.SaveSet(5, "/keep/b/%02d.json", nil)
+
+	purged, err := suite.Client.Purge()
+	require.NoError(err, "purge")
+	require.Equal(5, purged, "purge count")
+	require.Equal(10, len(suite.Fake.Keys()), "keys left")
+
+	keep_paths := make([]string, 0, len(keep_a.Paths)+len(keep_b.Paths))
+	keep_paths = append(keep_paths, keep_a.Paths...)
+	keep_paths = append(keep_paths, keep_b.Paths...)
+
+	paths, err := suite.Client.List("/keep/")
+	require.NoError(err)
+	require.EqualValues(keep_paths, paths, "path list")
+
+}
+
+func (suite *S3ClientTestSuite) TestShutdownOK() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(5, "/purge/%02d.json", &past)
+
+	suite.Client.PurgeOnShutdown = false
+	defer func() { suite.Client.PurgeOnShutdown = true }()
+	require.NoError(suite.Client.Shutdown(), "shutdown without purge")
+	require.Equal(5, len(suite.Fake.Keys()), "keys left")
+
+	suite.Client.PurgeOnShutdown = true
+	require.NoError(suite.Client.Shutdown(), "shutdown with purge")
+	require.Empty(suite.Fake.Keys(), "keys left")
+
+}
+
+func (suite *S3ClientTestSuite) TestImplementsContextBackendClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.ContextBackendClient)(nil), suite.Client)
+}
+
+func (suite *S3ClientTestSuite) TestContextCanceledFails() {
+
+	require := suite.Require()
+
+	suite.SaveSet(3, "/ctx/%d", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(suite.Client.SaveRawCtx(ctx, "/ctx/x", []byte("{}")),
+		context.Canceled, "SaveRawCtx")
+	require.ErrorIs(suite.Client.SaveRawExpiryCtx(ctx, "/ctx/x", []byte("{}"),
+		time.Now()), context.Canceled, "SaveRawExpiryCtx")
+	_, err := suite.Client.LoadRawCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadRawCtx")
+	_, err = suite.Client.LoadDetailCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadDetailCtx")
+	require.ErrorIs(suite.Client.DeleteCtx(ctx, "/ctx/0"), context.Canceled,
+		"DeleteCtx")
+	_, err = suite.Client.ListCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListCtx")
+	_, err = suite.Client.ListDetailCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListDetailCtx")
+	_, err = suite.Client.CountCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "CountCtx")
+	_, err = suite.Client.CountAllCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "CountAllCtx")
+	_, err = suite.Client.PurgeCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "PurgeCtx")
+	require.ErrorIs(suite.Client.ShutdownCtx(ctx), context.Canceled,
+		"ShutdownCtx")
+
+	// Nothing happened.
+	paths, err := suite.Client.List("/ctx/")
+	require.NoError(err)
+	require.Equal([]string{"/ctx/0", "/ctx/1", "/ctx/2"}, paths)
+
+}
+
+func (suite *S3ClientTestSuite) TestImplementsVersioner() {
+
+	require := suite.Require()
+	require.Implements((*backend.Versioner)(nil), suite.Client)
+}
+
+func (suite *S3ClientTestSuite) TestVersionsOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+
+	_, _, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.ErrorIs(err, backend.ErrNotFound, "not yet saved")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "update missing")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 0)
+	require.NoError(err, "create")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 0)
+	require.ErrorIs(err, backend.ErrConflict, "create existing")
+
+	data, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+	require.EqualValues(1, version)
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 1)
+	require.NoError(err, "update")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "stale update")
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":4}`)), "plain save")
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(3, detail.Version(), "detail version")
+
+	details, err := suite.Client.ListDetail("/ver/")
+	require.NoError(err)
+	require.Len(details, 1)
+	require.EqualValues(3, details[0].Version(), "list detail version")
+
+	data, version, err = suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":4}`, string(data))
+	require.EqualValues(3, version)
+
+}
+
+func (suite *S3ClientTestSuite) TestVersionsExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":1}`), past))
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":2}`), past))
+
+	err := suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 2)
+	require.ErrorIs(err, backend.ErrConflict, "update expired")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), &future, 0)
+	require.NoError(err, "replace expired")
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(1, detail.Version(), "starts over")
+	require.True(detail.Expires(), "expiry saved")
+	require.WithinDuration(future, detail.Expiry(), time.Second)
+
+}
+
+func (suite *S3ClientTestSuite) TestSaveIfVersionConcurrentOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/conc"
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":0}`)))
+
+	// Everybody read version 1, so only one of them may win.
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf(`{"n":%d}`, i+1))
+			errs[i] = suite.Client.SaveRawIfVersionCtx(ctx, path, data, nil, 1)
+		}(i)
+	}
+	wg.Wait()
+
+	wins := 0
+	for _, err := range errs {
+		if err == nil {
+			wins++
+		} else {
+			require.ErrorIs(err, backend.ErrConflict)
+		}
+	}
+	require.Equal(1, wins, "one winner")
+
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.EqualValues(2, version)
+
+}
+
+func (suite *S3ClientTestSuite) TestSaveIfNotExistsSendsPreconditionOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/new"
+	require.NoError(suite.Client.SaveRawIfVersionCtx(ctx, path,
+		[]byte(`{"n":1}`), nil, 0))
+	require.Equal(`"*"`, suite.Fake.LastPut.Get("If-None-Match"))
+	require.Empty(suite.Fake.LastPut.Get("If-Match"))
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	require.Empty(suite.Fake.LastPut.Get("If-None-Match"), "plain save")
+	require.Empty(suite.Fake.LastPut.Get("If-Match"), "plain save")
+
+}
+
+func (suite *S3ClientTestSuite) TestSaveIfNotExistsConcurrentOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/conc-new"
+
+	// Everybody saw no object, so only one of them may create it.
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf(`{"n":%d}`, i+1))
+			errs[i] = suite.Client.SaveRawIfVersionCtx(ctx, path, data, nil, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	wins := 0
+	for _, err := range errs {
+		if err == nil {
+			wins++
+		} else {
+			require.ErrorIs(err, backend.ErrConflict)
+		}
+	}
+	require.Equal(1, wins, "one winner")
+
+}
+
+func (suite *S3ClientTestSuite) TestImplementsPager() {
+
+	require := suite.Require()
+	require.Implements((*backend.Pager)(nil), suite.Client)
+}
+
+func (suite *S3ClientTestSuite) TestListPageOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	set := suite.SaveSet(10, "/page/%02d", nil)
+	suite.SaveSet(3, "/page/%02d-old", &past)
+	suite.SaveSet(2, "/pages/%02d", nil)
+
+	paths := []string{}
+	cursor := ""
+	pages := 0
+	for {
+		page, next, err := suite.Client.ListPageCtx(ctx, "/page/", cursor, 4)
+		require.NoError(err)
+		require.LessOrEqual(len(page), 4, "page size")
+		paths = append(paths, page...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	require.Equal(set.Paths, paths, "all paths in order")
+	require.Equal(3, pages, "pages")
+
+}
+
+func (suite *S3ClientTestSuite) TestListDetailPageOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	set := suite.SaveSet(6, "/page/%02d", nil)
+
+	detailers, next, err := suite.Client.ListDetailPageCtx(ctx, "/page/", "", 3)
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(set.Paths[0], detailers[0].Path())
+	require.NotEmpty(next)
+
+	detailers, next, err = suite.Client.ListDetailPageCtx(ctx, "/page/", next, 3)
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(set.Paths[5], detailers[2].Path())
+	require.EqualValues(1, detailers[2].Version())
+	require.Empty(next, "exactly two pages")
+
+}
+
+func (suite *S3ClientTestSuite) TestListPageFailsBadArgs() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	_, _, err := suite.Client.ListPageCtx(ctx, "/page/", "?", 3)
+	require.ErrorIs(err, backend.ErrInvalidCursor)
+	_, _, err = suite.Client.ListDetailPageCtx(ctx, "/page/", "", 0)
+	require.ErrorContains(err, "page limit must be positive")
+
+}
+
+func (suite *S3ClientTestSuite) TestImplementsMetaClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.MetaClient)(nil), suite.Client)
+}
+
+func (suite *S3ClientTestSuite) TestSaveRawMetaOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	meta := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	exp := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	meta["Owner"] = "changed" // not kept by reference
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Equal(exp, detail.Meta())
+	require.False(detail.Expires())
+
+	// Other saves keep it.
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, version)
+	require.NoError(err)
+	detailers, err := suite.Client.ListDetail("/meta/")
+	require.NoError(err)
+	require.Len(detailers, 1)
+	require.Equal(exp, detailers[0].Meta(), "kept")
+
+	// Saving with nil removes it.
+	err = suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":4}`), nil, nil)
+	require.NoError(err)
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta())
+
+}
+
+func (suite *S3ClientTestSuite) TestSaveRawMetaExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	past := time.Now().Add(-time.Hour)
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), &past,
+		map[string]string{"k": "v"})
+	require.NoError(err)
+	_, err = suite.Client.LoadDetail(path)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta(), "not kept from expired object")
+
+}
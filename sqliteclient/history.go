@@ -0,0 +1,130 @@
+// history.go - keeping prior revisions of objects
+//
+// As for pgclient, history is kept in a second table, <Table>_history, by
+// triggers on the Table.  It is off until CreateHistory is called, and
+// grows until pruned, either by PruneHistoryCtx or by Purge with HistoryKeep
+// or HistoryMaxAge set.
+
+package sqliteclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// HistorySchema returns the SQL required to create the history table and
+// triggers for this client's Table.
+func (c *SqliteClient) HistorySchema() string {
+	return c.historySchemaSql()
+}
+
+// CreateHistory executes the SQL returned from HistorySchema on the current
+// database, after which every live object replaced or deleted is kept as a
+// revision.  It may be called again safely.
+func (c *SqliteClient) CreateHistory() error {
+
+	_, err := c.DB.Exec(c.HistorySchema())
+	return err
+}
+
+// ListVersionsCtx implements backend.Historian.
+func (c *SqliteClient) ListVersionsCtx(ctx context.Context, path string) ([]backend.Detailer, error) {
+	return c.queryDetailers(ctx, c.listVersionsSql(), path, nowNanos())
+}
+
+// LoadRawAtVersionCtx implements backend.Historian.
+func (c *SqliteClient) LoadRawAtVersionCtx(ctx context.Context, path string, version int64) ([]byte, error) {
+
+	row := c.DB.QueryRowContext(ctx, c.loadAtVersionSql(),
+		path, nowNanos(), version)
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		return nil, translate(err)
+	}
+	return data, nil
+
+}
+
+// LoadRawAsOfCtx implements backend.Historian.
+func (c *SqliteClient) LoadRawAsOfCtx(ctx context.Context, path string, t time.Time) ([]byte, int64, error) {
+
+	row := c.DB.QueryRowContext(ctx, c.loadAsOfSql(), path, t.UnixNano())
+	var data []byte
+	var version int64
+	if err := row.Scan(&data, &version); err != nil {
+		return nil, 0, translate(err)
+	}
+	return data, version, nil
+
+}
+
+// PruneHistoryCtx implements backend.Historian.  Call it periodically, or set
+// HistoryKeep and HistoryMaxAge to have Purge call it, to bound the size of
+// the history.
+func (c *SqliteClient) PruneHistoryCtx(ctx context.Context, keep int, max_age time.Duration) (int, error) {
+
+	if err := backend.CheckPrune(keep, max_age); err != nil {
+		return 0, err
+	}
+	pruned := 0
+	if keep > 0 {
+		res, err := c.DB.ExecContext(ctx, c.pruneHistoryKeepSql(), keep)
+		if err != nil {
+			return pruned, err
+		}
+		affected, err := res.RowsAffected()
+		pruned += int(affected)
+		if err != nil {
+			return pruned, err
+		}
+	}
+	if max_age > 0 {
+		res, err := c.DB.ExecContext(ctx, c.pruneHistoryAgeSql(),
+			time.Now().Add(-max_age).UnixNano())
+		if err != nil {
+			return pruned, err
+		}
+		affected, err := res.RowsAffected()
+		pruned += int(affected)
+		if err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+
+}
+
+// boundsHistory returns true if Purge should prune the history.
+func (c *SqliteClient) boundsHistory() bool {
+	return c.HistoryKeep != 0 || c.HistoryMaxAge != 0
+}
+
+// pruneHistoryBatch prunes the history to HistoryKeep and HistoryMaxAge as
+// for PruneHistoryCtx, deleting at most limit revisions.
+func (c *SqliteClient) pruneHistoryBatch(ctx context.Context, limit int) (int, error) {
+
+	if err := backend.CheckPrune(c.HistoryKeep, c.HistoryMaxAge); err != nil {
+		return 0, err
+	}
+	pruned := 0
+	if c.HistoryKeep > 0 {
+		n, err := c.purge(ctx, c.pruneHistoryKeepBatchSql(), c.HistoryKeep,
+			limit)
+		pruned += n
+		if err != nil || pruned >= limit {
+			return pruned, err
+		}
+	}
+	if c.HistoryMaxAge > 0 {
+		n, err := c.purge(ctx, c.pruneHistoryAgeBatchSql(),
+			time.Now().Add(-c.HistoryMaxAge).UnixNano(), limit-pruned)
+		pruned += n
+		if err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+
+}
@@ -0,0 +1,70 @@
+// history_test.go -- tests for prior revisions of objects.
+//
+// History is created for the whole suite, so every other test also runs
+// with the triggers in place.  The tests common to all Historian backends
+// are in backendtest.
+
+package sqliteclient_test
+
+import (
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
+)
+
+func (suite *SqliteClientTestSuite) TestImplementsHistorian() {
+
+	require := suite.Require()
+	require.Implements((*backend.Historian)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestCreateHistoryAgainOK() {
+
+	require := suite.Require()
+
+	require.Contains(suite.Client.HistorySchema(), "CREATE TRIGGER")
+	require.NoError(suite.Client.CreateHistory())
+
+}
+
+func (suite *SqliteClientTestSuite) TestListVersionsOK() {
+	backendtest.ListVersionsOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestHistoryKeepsDeletedOK() {
+	backendtest.HistoryKeepsDeletedOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestHistorySkipsExpiredOK() {
+	backendtest.HistorySkipsExpiredOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestLoadRawAtVersionFailsNotFound() {
+	backendtest.LoadRawAtVersionFailsNotFound(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestPruneHistoryOK() {
+	backendtest.PruneHistoryOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestPruneHistoryFailsNegative() {
+	backendtest.PruneHistoryFailsNegative(suite.Require(), suite.Client)
+}
+
+// boundHistory sets HistoryKeep and HistoryMaxAge for the rest of the test.
+func (suite *SqliteClientTestSuite) boundHistory(keep int, max_age time.Duration) {
+
+	suite.Client.HistoryKeep = keep
+	suite.Client.HistoryMaxAge = max_age
+	suite.T().Cleanup(func() {
+		suite.Client.HistoryKeep = 0
+		suite.Client.HistoryMaxAge = 0
+	})
+
+}
+
+func (suite *SqliteClientTestSuite) TestPurgePrunesHistoryOK() {
+	backendtest.PurgePrunesHistoryOK(suite.Require(), suite.Client,
+		suite.boundHistory)
+}
@@ -0,0 +1,133 @@
+// move.go - copying and moving objects
+//
+// Each operation runs in one transaction, so a move is never left half done.
+
+package sqliteclient
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// copyObject copies the live object at src to dst within tx according to
+// overwrite, returning false if it was skipped.
+func (c *SqliteClient) copyObject(ctx context.Context, tx *sql.Tx, src string, dst string, overwrite backend.Overwrite, now int64) (bool, error) {
+
+	res, err := tx.ExecContext(ctx,
+		c.copySql(overwrite == backend.OverwriteAlways), src, dst, now)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected > 0 {
+		return true, nil
+	}
+
+	// Either the source is gone or the destination was kept.
+	var found int
+	row := tx.QueryRowContext(ctx, c.existsSql(), src, now)
+	if err := row.Scan(&found); err != nil {
+		return false, err
+	}
+	if found == 0 {
+		return false, ErrNotFound
+	}
+	if overwrite == backend.OverwriteNever {
+		return false, fmt.Errorf("%w: %s exists", backend.ErrConflict, dst)
+	}
+	return false, nil
+
+}
+
+// move copies src to dst within tx and deletes src if it was copied.
+func (c *SqliteClient) move(ctx context.Context, tx *sql.Tx, src string, dst string, overwrite backend.Overwrite, now int64) (bool, error) {
+
+	moved, err := c.copyObject(ctx, tx, src, dst, overwrite, now)
+	if err != nil || !moved {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, c.deleteSql(), src); err != nil {
+		return false, err
+	}
+	return true, nil
+
+}
+
+// CopyCtx implements backend.Mover.
+func (c *SqliteClient) CopyCtx(ctx context.Context, src string, dst string, overwrite backend.Overwrite) error {
+
+	if err := backend.CheckMove(src, dst); err != nil {
+		return err
+	}
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	if _, err := c.copyObject(ctx, tx, src, dst, overwrite, nowNanos()); err != nil {
+		return err
+	}
+	return tx.Commit()
+
+}
+
+// MoveCtx implements backend.Mover.  The source is deleted outright even
+// if SoftDelete is true.
+func (c *SqliteClient) MoveCtx(ctx context.Context, src string, dst string, overwrite backend.Overwrite) error {
+
+	if err := backend.CheckMove(src, dst); err != nil {
+		return err
+	}
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	if _, err := c.move(ctx, tx, src, dst, overwrite, nowNanos()); err != nil {
+		return err
+	}
+	return tx.Commit()
+
+}
+
+// MovePrefixCtx implements backend.Mover, moving the objects one at a time
+// in a single transaction.
+func (c *SqliteClient) MovePrefixCtx(ctx context.Context, src_prefix string, dst_prefix string, overwrite backend.Overwrite) (int, error) {
+
+	if err := backend.CheckMovePrefix(src_prefix, dst_prefix); err != nil {
+		return 0, err
+	}
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	now := nowNanos()
+	paths, err := c.queryPaths(ctx, tx, c.listSql(), src_prefix, now)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, path := range paths {
+		dst := dst_prefix + path[len(src_prefix):]
+		moved, err := c.move(ctx, tx, path, dst, overwrite, now)
+		if err != nil {
+			return 0, err
+		}
+		if moved {
+			count++
+		}
+	}
+	return count, tx.Commit()
+
+}
@@ -0,0 +1,45 @@
+// move_test.go -- tests for copying and moving objects.
+//
+// The tests common to all Mover backends are in backendtest.
+
+package sqliteclient_test
+
+import (
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
+)
+
+func (suite *SqliteClientTestSuite) TestImplementsMover() {
+
+	require := suite.Require()
+	require.Implements((*backend.Mover)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestCopyOK() {
+	backendtest.CopyOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestCopyExistingOK() {
+	backendtest.CopyExistingOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestCopyFails() {
+	backendtest.CopyFails(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestMoveOK() {
+	backendtest.MoveOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestMovePrefixOK() {
+	backendtest.MovePrefixOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestMovePrefixFailsOverlap() {
+	backendtest.MovePrefixFailsOverlap(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestMoveSkipsTrashOK() {
+	suite.softDelete()
+	backendtest.MoveSkipsTrashOK(suite.Require(), suite.Client)
+}
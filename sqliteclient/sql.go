@@ -0,0 +1,519 @@
+// sql.go -- SQL snippets go here.
+//
+// NOTE: SQLite has no starts_with, so prefix matching compares the leading
+// substring instead of using LIKE, which would need escaping of % and _ in
+// the prefix.
+//
+// NOTE: SQLite has no arrays either, so lists of paths are passed in as JSON
+// and unpacked with json_each.
+//
+// NOTE: the current time is always passed in as a parameter, in Unix
+// nanoseconds, to match how expiry and modified are stored.  The history
+// triggers are the exception, as they can not take parameters.
+
+package sqliteclient
+
+import "fmt"
+
+// NOTE: modified doubles as "now" for the expiry checks in the save
+// statements.  Replacing an expired object starts it over at version 1,
+// without metadata.  A NULL meta keeps the current metadata, and the empty
+// JSON object removes it.
+func (c *SqliteClient) saveSql() string {
+	f := `INSERT INTO %[1]s (obj_path,data,size,expiry,modified,version,meta)
+VALUES (?1,?2,?3,?4,?5,1,nullif(?6,'{}'))
+ON CONFLICT (obj_path)
+DO UPDATE SET
+	data = excluded.data,
+	size = excluded.size,
+	expiry = excluded.expiry,
+	modified = excluded.modified,
+	version = CASE WHEN %[1]s.expiry IS NULL OR %[1]s.expiry > ?5
+		THEN %[1]s.version + 1 ELSE 1 END,
+	meta = CASE WHEN ?6 IS NOT NULL THEN excluded.meta
+		WHEN %[1]s.expiry IS NULL OR %[1]s.expiry > ?5 THEN %[1]s.meta
+		ELSE NULL END;`
+	return fmt.Sprintf(f, c.Table)
+}
+
+func (c *SqliteClient) saveIfVersionSql() string {
+	f := `UPDATE %s
+SET data = ?2, size = ?3, expiry = ?4, modified = ?5, version = version + 1
+WHERE obj_path = ?1 AND version = ?6 AND (expiry IS NULL OR expiry > ?5);`
+	return fmt.Sprintf(f, c.Table)
+}
+
+func (c *SqliteClient) saveIfNotExistsSql() string {
+	f := `INSERT INTO %[1]s (obj_path,data,size,expiry,modified,version)
+VALUES (?1,?2,?3,?4,?5,1)
+ON CONFLICT (obj_path)
+DO UPDATE SET
+	data = excluded.data,
+	size = excluded.size,
+	expiry = excluded.expiry,
+	modified = excluded.modified,
+	version = 1,
+	meta = NULL
+WHERE %[1]s.expiry IS NOT NULL AND %[1]s.expiry <= ?5;`
+	return fmt.Sprintf(f, c.Table)
+}
+
+func (c *SqliteClient) deleteSql() string {
+	f := "DELETE FROM %s WHERE obj_path = ?1;"
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) deleteManySql() string {
+	f := "DELETE FROM %s WHERE obj_path IN (SELECT value FROM json_each(?1));"
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) deletePrefixSql() string {
+	f := "DELETE FROM %s WHERE substr(obj_path,1,length(?1)) = ?1;"
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) listSql() string {
+	f := `SELECT obj_path
+FROM %s
+WHERE substr(obj_path,1,length(?1)) = ?1 AND (expiry IS NULL OR expiry > ?2)
+ORDER BY obj_path;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) listDetailSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta
+FROM %s
+WHERE substr(obj_path,1,length(?1)) = ?1 AND (expiry IS NULL OR expiry > ?2)
+ORDER BY obj_path;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) listDirSql() string {
+	f := `SELECT DISTINCT
+CASE WHEN d > 0 THEN substr(obj_path,1,length(?1)+d+length(?3)-1) ELSE obj_path END
+AS entry, d > 0 AS is_prefix
+FROM (
+    SELECT obj_path, instr(substr(obj_path,length(?1)+1),?3) AS d
+    FROM %s
+    WHERE substr(obj_path,1,length(?1)) = ?1
+    AND (expiry IS NULL OR expiry > ?2)
+)
+ORDER BY entry;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) listPageSql() string {
+	f := `SELECT obj_path
+FROM %s
+WHERE substr(obj_path,1,length(?1)) = ?1 AND obj_path > ?3
+AND (expiry IS NULL OR expiry > ?2)
+ORDER BY obj_path
+LIMIT ?4;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) listDetailPageSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta
+FROM %s
+WHERE substr(obj_path,1,length(?1)) = ?1 AND obj_path > ?3
+AND (expiry IS NULL OR expiry > ?2)
+ORDER BY obj_path
+LIMIT ?4;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: an object matches if none of the wanted key/value pairs in ?3 is
+// missing from its metadata.
+func (c *SqliteClient) metaMatchSql() string {
+	return `NOT EXISTS (
+	SELECT 1 FROM json_each(?3) AS want
+	WHERE NOT EXISTS (
+		SELECT 1 FROM json_each(meta) AS have
+		WHERE have.key = want.key AND have.value = want.value
+	)
+)`
+}
+
+func (c *SqliteClient) listDetailMetaSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta
+FROM %s
+WHERE substr(obj_path,1,length(?1)) = ?1 AND (expiry IS NULL OR expiry > ?2)
+AND %s
+ORDER BY obj_path;`
+	return fmt.Sprintf(f, c.Table, c.metaMatchSql())
+
+}
+
+func (c *SqliteClient) countMetaSql() string {
+	f := `SELECT COUNT(*)
+FROM %s
+WHERE substr(obj_path,1,length(?1)) = ?1 AND (expiry IS NULL OR expiry > ?2)
+AND %s;`
+	return fmt.Sprintf(f, c.Table, c.metaMatchSql())
+
+}
+
+func (c *SqliteClient) countSql() string {
+	f := `SELECT COUNT(*)
+FROM %s
+WHERE substr(obj_path,1,length(?1)) = ?1 AND (expiry IS NULL OR expiry > ?2);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) countAllSql() string {
+	f := `SELECT COUNT(*)
+FROM %s
+WHERE expiry IS NULL OR expiry > ?1;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) loadSql() string {
+	f := `SELECT data
+FROM %s
+WHERE obj_path = ?1 AND (expiry IS NULL or expiry > ?2);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) setExpirySql() string {
+	f := `UPDATE %s
+SET expiry = ?3
+WHERE obj_path = ?1 AND (expiry IS NULL OR expiry > ?2);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: objects with no expiry are not renewed, so the plain load finds
+// those.
+func (c *SqliteClient) loadRenewSql(cols string) string {
+	f := `UPDATE %s
+SET expiry = max(expiry, ?2 + ?3)
+WHERE obj_path = ?1 AND expiry > ?2
+RETURNING %s;`
+	return fmt.Sprintf(f, c.Table, cols)
+
+}
+
+func (c *SqliteClient) renewManySql() string {
+	f := `UPDATE %s
+SET expiry = max(expiry, ?2 + ?3)
+WHERE obj_path IN (SELECT value FROM json_each(?1)) AND expiry > ?2;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) loadManySql() string {
+	f := `SELECT obj_path,data
+FROM %s
+WHERE obj_path IN (SELECT value FROM json_each(?1))
+AND (expiry IS NULL or expiry > ?2);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) loadVersionSql() string {
+	f := `SELECT data,version
+FROM %s
+WHERE obj_path = ?1 AND (expiry IS NULL or expiry > ?2);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) loadDetailSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta
+FROM %s
+WHERE obj_path = ?1 AND (expiry IS NULL or expiry > ?2);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) purgeSql() string {
+	f := "DELETE FROM %s WHERE expiry <= ?1;"
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) purgeBatchSql() string {
+	f := `DELETE FROM %s
+WHERE obj_path IN (
+	SELECT obj_path FROM %s WHERE expiry <= ?1 LIMIT ?2
+);`
+	return fmt.Sprintf(f, c.Table, c.Table)
+
+}
+
+func (c *SqliteClient) schemaSql() string {
+
+	f := `CREATE TABLE %s (
+	obj_path TEXT NOT NULL PRIMARY KEY,
+	data TEXT NOT NULL CHECK (json_valid(data)),
+	size INTEGER NOT NULL,
+	expiry INTEGER NULL,
+	modified INTEGER NOT NULL,
+	version INTEGER NOT NULL DEFAULT 1,
+	meta TEXT NULL CHECK (meta IS NULL OR json_valid(meta))
+);
+CREATE INDEX %s_expiry_idx ON %s (expiry);`
+
+	return fmt.Sprintf(f, c.Table, c.Table, c.Table)
+
+}
+
+// NOTE: history is written by triggers so that every way of replacing or
+// deleting a live object is covered, but not changes to the expiry alone.
+// Updates use the new modification time as both "now" and the replacement
+// time; deletes have no new row and use the database clock, which only has
+// millisecond precision.
+func (c *SqliteClient) historySchemaSql() string {
+
+	f := `CREATE TABLE IF NOT EXISTS %[1]s_history (
+	obj_path TEXT NOT NULL,
+	data TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	expiry INTEGER NULL,
+	modified INTEGER NOT NULL,
+	version INTEGER NOT NULL,
+	meta TEXT NULL,
+	replaced INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS %[1]s_history_path_idx ON %[1]s_history (obj_path, replaced);
+CREATE INDEX IF NOT EXISTS %[1]s_history_replaced_idx ON %[1]s_history (replaced);
+DROP TRIGGER IF EXISTS %[1]s_history_update;
+CREATE TRIGGER %[1]s_history_update
+AFTER UPDATE OF data ON %[1]s
+WHEN OLD.expiry IS NULL OR OLD.expiry > NEW.modified
+BEGIN
+	INSERT INTO %[1]s_history
+		(obj_path,data,size,expiry,modified,version,meta,replaced)
+	VALUES (OLD.obj_path,OLD.data,OLD.size,OLD.expiry,OLD.modified,
+		OLD.version,OLD.meta,NEW.modified);
+END;
+DROP TRIGGER IF EXISTS %[1]s_history_delete;
+CREATE TRIGGER %[1]s_history_delete
+AFTER DELETE ON %[1]s
+WHEN OLD.expiry IS NULL
+OR OLD.expiry > CAST(unixepoch('subsec') * 1000000000 AS INTEGER)
+BEGIN
+	INSERT INTO %[1]s_history
+		(obj_path,data,size,expiry,modified,version,meta,replaced)
+	VALUES (OLD.obj_path,OLD.data,OLD.size,OLD.expiry,OLD.modified,
+		OLD.version,OLD.meta,
+		CAST(unixepoch('subsec') * 1000000000 AS INTEGER));
+END;`
+
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) listVersionsSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta
+FROM %[1]s_history
+WHERE obj_path = ?1
+UNION ALL
+SELECT obj_path,size,expiry,modified,version,meta
+FROM %[1]s
+WHERE obj_path = ?1 AND (expiry IS NULL OR expiry > ?2)
+ORDER BY modified;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: the live object sorts after any revision with the same version.
+func (c *SqliteClient) loadAtVersionSql() string {
+	f := `SELECT data FROM (
+	SELECT data, ?2 AS at
+	FROM %[1]s
+	WHERE obj_path = ?1 AND version = ?3 AND (expiry IS NULL OR expiry > ?2)
+	UNION ALL
+	SELECT data, replaced
+	FROM %[1]s_history
+	WHERE obj_path = ?1 AND version = ?3
+)
+ORDER BY at DESC
+LIMIT 1;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) loadAsOfSql() string {
+	f := `SELECT data, version FROM (
+	SELECT data, version, modified
+	FROM %[1]s
+	WHERE obj_path = ?1 AND modified <= ?2 AND (expiry IS NULL OR expiry > ?2)
+	UNION ALL
+	SELECT data, version, modified
+	FROM %[1]s_history
+	WHERE obj_path = ?1 AND modified <= ?2 AND replaced > ?2
+	AND (expiry IS NULL OR expiry > ?2)
+)
+ORDER BY modified DESC
+LIMIT 1;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) pruneHistoryKeepSql() string {
+	f := `DELETE FROM %[1]s_history
+WHERE rowid IN (
+	SELECT rowid FROM (
+		SELECT rowid, row_number() OVER (
+			PARTITION BY obj_path ORDER BY replaced DESC) AS n
+		FROM %[1]s_history
+	)
+	WHERE n > ?1
+);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) pruneHistoryKeepBatchSql() string {
+	f := `DELETE FROM %[1]s_history
+WHERE rowid IN (
+	SELECT rowid FROM (
+		SELECT rowid, row_number() OVER (
+			PARTITION BY obj_path ORDER BY replaced DESC) AS n
+		FROM %[1]s_history
+	)
+	WHERE n > ?1 LIMIT ?2
+);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) pruneHistoryAgeSql() string {
+	f := "DELETE FROM %s_history WHERE replaced < ?1;"
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) pruneHistoryAgeBatchSql() string {
+	f := `DELETE FROM %[1]s_history
+WHERE rowid IN (
+	SELECT rowid FROM %[1]s_history WHERE replaced < ?1 LIMIT ?2
+);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) trashSchemaSql() string {
+
+	f := `CREATE TABLE IF NOT EXISTS %[1]s_trash (
+	obj_path TEXT NOT NULL PRIMARY KEY,
+	data TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	expiry INTEGER NULL,
+	modified INTEGER NOT NULL,
+	version INTEGER NOT NULL,
+	meta TEXT NULL,
+	deleted INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS %[1]s_trash_deleted_idx ON %[1]s_trash (deleted);`
+
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: this copies the rows matching cond, which the caller then deletes
+// in the same transaction.  Only the latest deletion of a path is kept.
+func (c *SqliteClient) trashSql(cond string) string {
+	f := `INSERT OR REPLACE INTO %[1]s_trash
+	(obj_path,data,size,expiry,modified,version,meta,deleted)
+SELECT obj_path,data,size,expiry,modified,version,meta,?2
+FROM %[1]s
+WHERE %[2]s;`
+	return fmt.Sprintf(f, c.Table, cond)
+
+}
+
+func (c *SqliteClient) untrashSql() string {
+	f := `DELETE FROM %s_trash
+WHERE obj_path = ?1
+RETURNING data,size,expiry,modified,version,meta;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: like saveIfNotExistsSql, an expired object may be replaced.
+func (c *SqliteClient) undeleteSql() string {
+	f := `INSERT INTO %[1]s (obj_path,data,size,expiry,modified,version,meta)
+VALUES (?1,?2,?3,?4,?5,?6,?7)
+ON CONFLICT (obj_path)
+DO UPDATE SET
+	data = excluded.data,
+	size = excluded.size,
+	expiry = excluded.expiry,
+	modified = excluded.modified,
+	version = excluded.version,
+	meta = excluded.meta
+WHERE %[1]s.expiry IS NOT NULL AND %[1]s.expiry <= ?8;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) listDeletedSql() string {
+	f := `SELECT obj_path,size,expiry,modified,version,meta,deleted
+FROM %s_trash
+WHERE substr(obj_path,1,length(?1)) = ?1
+ORDER BY obj_path;`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) purgeTrashSql() string {
+	f := "DELETE FROM %s_trash WHERE deleted <= ?1;"
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+func (c *SqliteClient) purgeTrashBatchSql() string {
+	f := `DELETE FROM %[1]s_trash
+WHERE obj_path IN (
+	SELECT obj_path FROM %[1]s_trash WHERE deleted <= ?1 LIMIT ?2
+);`
+	return fmt.Sprintf(f, c.Table)
+
+}
+
+// NOTE: the copy keeps the expiry and metadata of the source, and only
+// replaces an expired object at the destination unless replace is true.
+func (c *SqliteClient) copySql(replace bool) string {
+	f := `INSERT INTO %[1]s (obj_path,data,size,expiry,modified,version,meta)
+SELECT ?2,data,size,expiry,?3,1,meta
+FROM %[1]s
+WHERE obj_path = ?1 AND (expiry IS NULL OR expiry > ?3)
+ON CONFLICT (obj_path)
+DO UPDATE SET
+	data = excluded.data,
+	size = excluded.size,
+	expiry = excluded.expiry,
+	modified = excluded.modified,
+	version = CASE WHEN %[1]s.expiry IS NULL OR %[1]s.expiry > ?3
+		THEN %[1]s.version + 1 ELSE 1 END,
+	meta = excluded.meta%[2]s;`
+	cond := ""
+	if !replace {
+		cond = fmt.Sprintf("\nWHERE %[1]s.expiry IS NOT NULL AND %[1]s.expiry <= ?3",
+			c.Table)
+	}
+	return fmt.Sprintf(f, c.Table, cond)
+
+}
+
+func (c *SqliteClient) existsSql() string {
+	f := `SELECT count(*)
+FROM %s
+WHERE obj_path = ?1 AND (expiry IS NULL OR expiry > ?2);`
+	return fmt.Sprintf(f, c.Table)
+
+}
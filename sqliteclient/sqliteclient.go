@@ -0,0 +1,851 @@
+// sqliteclient.go - SQLite backend client
+//
+// Mirrors pgclient as closely as SQLite allows.  Times are stored as Unix
+// nanoseconds, and "now" is passed in from Go rather than computed by the
+// database, so expiry filtering behaves the same regardless of how SQLite
+// was compiled.
+
+// Package sqliteclient implements backend.BackendClient for SQLite files.
+package sqliteclient
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// Override if using multiple databases in your process.
+var DatabaseFileEnvVar = "SQLITE_DATABASE"
+
+// DriverName is the database/sql driver used by New and NewForFile.
+var DriverName = "sqlite3"
+
+var DefaultTable = "obj_store"
+
+// DefaultTrashRetention is how long objects stay in the trash when
+// SoftDelete is set, for new clients.
+var DefaultTrashRetention = 7 * 24 * time.Hour
+
+// ErrNotFound is returned wrapped together with sql.ErrNoRows, so errors.Is
+// works for either.
+var ErrNotFound = backend.ErrNotFound
+
+// ErrInvalidJson is returned wrapped together with the database error when
+// the data fails the json_valid check.
+var ErrInvalidJson = backend.ErrInvalidJson
+
+// translate wraps database errors in the corresponding backend errors, and
+// returns others as they are.
+func translate(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	var sqlErr sqlite3.Error
+	if errors.As(err, &sqlErr) && sqlErr.ExtendedCode == sqlite3.ErrConstraintCheck {
+		return fmt.Errorf("%w: %w", ErrInvalidJson, err)
+	}
+	return err
+}
+
+// SqliteDetailer implements backend.Detailer to describe an object.
+type SqliteDetailer struct {
+	path     string
+	size     int
+	expiry   *time.Time
+	modified time.Time
+	version  int64
+	meta     map[string]string
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Scan scans a database row.
+func (d *SqliteDetailer) Scan(row scanner) error {
+	return d.scan(row)
+}
+
+// scan scans a database row with the Detailer columns first, then extra.
+func (d *SqliteDetailer) scan(row scanner, extra ...any) error {
+	var expiry sql.NullInt64
+	var modified int64
+	var meta sql.NullString
+	dest := []any{&d.path, &d.size, &expiry, &modified, &d.version, &meta}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	d.expiry = fromNullNanos(expiry)
+	d.modified = time.Unix(0, modified)
+	if meta.Valid {
+		if err := json.Unmarshal([]byte(meta.String), &d.meta); err != nil {
+			return fmt.Errorf("bad meta for %s: %w", d.path, err)
+		}
+	}
+	return nil
+}
+
+// Path implements backend.Detailer.
+func (d *SqliteDetailer) Path() string {
+	return d.path
+}
+
+// Size implements backend.Detailer.
+func (d *SqliteDetailer) Size() int {
+	return d.size
+}
+
+// Expires implements backend.Detailer.
+func (d *SqliteDetailer) Expires() bool {
+	return d.expiry != nil
+}
+
+// Expiry implements backend.Detailer. If Expires returns false then Expiry must
+// be ignored.
+func (d *SqliteDetailer) Expiry() time.Time {
+	if d.expiry == nil {
+		return time.Time{} // "zero time"
+	}
+	return *d.expiry
+}
+
+// Modified implements backend.Detailer.
+func (d *SqliteDetailer) Modified() time.Time {
+	return d.modified
+}
+
+// Version implements backend.Detailer.
+func (d *SqliteDetailer) Version() int64 {
+	return d.version
+}
+
+// Meta implements backend.Detailer.
+func (d *SqliteDetailer) Meta() map[string]string {
+	return d.meta
+}
+
+func fromNullNanos(n sql.NullInt64) *time.Time {
+	if !n.Valid {
+		return nil
+	}
+	t := time.Unix(0, n.Int64)
+	return &t
+}
+
+func toNullNanos(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+func nowNanos() int64 {
+	return time.Now().UnixNano()
+}
+
+// SqliteClient is a BackendClient for SQLite databases.
+//
+// If SoftDelete is true, Delete, DeleteMany and DeletePrefix move objects to
+// the trash table made by CreateTrash, and Purge also deletes the objects
+// that have been in the trash longer than TrashRetention.
+//
+// If SlidingExpiry is positive, every load of an object that has an expiry
+// renews it to at least SlidingExpiry from now, so that objects in use do
+// not expire: LoadRaw, also within a transaction, LoadRawMany and
+// LoadRawVersion.  Listing, iterating and reading history do not, as they
+// scan objects rather than use them.  Objects with no expiry are not
+// affected.
+//
+// If HistoryKeep or HistoryMaxAge is positive, Purge also prunes the history
+// table made by CreateHistory as for PruneHistoryCtx, keeping at most
+// HistoryKeep revisions per object and none older than HistoryMaxAge.  Zero
+// disables either bound.
+type SqliteClient struct {
+	DB              *sql.DB
+	Table           string
+	PurgeOnShutdown bool
+	SoftDelete      bool
+	TrashRetention  time.Duration
+	SlidingExpiry   time.Duration
+	HistoryKeep     int
+	HistoryMaxAge   time.Duration
+}
+
+// String returns an identifying string.
+func (c *SqliteClient) String() string {
+	return fmt.Sprintf("sqliteclient (table=%s)", c.Table)
+}
+
+// New returns a new SqliteClient using DefaultTable and the database file
+// named at DatabaseFileEnvVar, with PurgeOnShutdown true.
+func New() (*SqliteClient, error) {
+	name := os.Getenv(DatabaseFileEnvVar)
+	if name == "" {
+		return nil, errors.New(DatabaseFileEnvVar + " not defined in env")
+	}
+	return NewForFile(name)
+}
+
+// NewForFile returns a new SqliteClient for the database file name, with
+// defaults as in New.
+//
+// The name is passed to the driver as-is, so go-sqlite3 DSN options such as
+// "file:jsobs.db?_busy_timeout=5000" may be used.  Note that ":memory:"
+// is probably not what you want, as every pooled connection gets its own
+// empty database.
+func NewForFile(name string) (*SqliteClient, error) {
+	db, err := sql.Open(DriverName, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return NewForDB(db), nil
+}
+
+// NewForDB returns a new SqliteClient with the provided DB, with defaults
+// as in New.
+func NewForDB(db *sql.DB) *SqliteClient {
+	return &SqliteClient{
+		DB:              db,
+		Table:           DefaultTable,
+		PurgeOnShutdown: true,
+		TrashRetention:  DefaultTrashRetention,
+	}
+}
+
+// save saves raw_obj on db, keeping the live object's metadata if meta is
+// nil and otherwise replacing it with the JSON in meta.
+func (c *SqliteClient) save(ctx context.Context, db querier, path string, raw_obj []byte, expiry *time.Time, meta any) error {
+
+	_, err := db.ExecContext(ctx, c.saveSql(),
+		path, string(raw_obj), len(raw_obj), toNullNanos(expiry), nowNanos(),
+		meta)
+	return translate(err)
+
+}
+
+// SaveRaw saves the raw_obj to the database with no expiry.
+func (c *SqliteClient) SaveRaw(path string, raw_obj []byte) error {
+	return c.SaveRawCtx(context.Background(), path, raw_obj)
+}
+
+// SaveRawCtx is SaveRaw with a context.
+func (c *SqliteClient) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	return c.save(ctx, c.DB, path, raw_obj, nil, nil)
+}
+
+// SaveRawExpiry saves the raw bytes to the database for availability until
+// expiry.
+func (c *SqliteClient) SaveRawExpiry(path string, raw_obj []byte, expiry time.Time) error {
+	return c.SaveRawExpiryCtx(context.Background(), path, raw_obj, expiry)
+}
+
+// SaveRawExpiryCtx is SaveRawExpiry with a context.
+func (c *SqliteClient) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	return c.save(ctx, c.DB, path, raw_obj, &expiry, nil)
+}
+
+// SaveRawMetaCtx implements backend.MetaClient.
+func (c *SqliteClient) SaveRawMetaCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, meta map[string]string) error {
+	b, err := metaJson(meta)
+	if err != nil {
+		return err
+	}
+	return c.save(ctx, c.DB, path, raw_obj, expiry, string(b))
+}
+
+// metaJson returns meta as JSON, always an object.
+func metaJson(meta map[string]string) ([]byte, error) {
+	if meta == nil {
+		meta = map[string]string{}
+	}
+	return json.Marshal(meta)
+}
+
+// LoadRaw retrieves the object at path and returns its raw value.
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *SqliteClient) LoadRaw(path string) ([]byte, error) {
+	return c.LoadRawCtx(context.Background(), path)
+}
+
+// LoadRawCtx is LoadRaw with a context.
+func (c *SqliteClient) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+	return c.loadRaw(ctx, c.DB, path)
+}
+
+// loadRaw loads the object at path from db, renewing its expiry if
+// SlidingExpiry is set.
+func (c *SqliteClient) loadRaw(ctx context.Context, db querier, path string) ([]byte, error) {
+
+	now := nowNanos()
+	var data []byte
+	if c.SlidingExpiry > 0 {
+		row := db.QueryRowContext(ctx, c.loadRenewSql("data"),
+			path, now, c.SlidingExpiry.Nanoseconds())
+		err := row.Scan(&data)
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, translate(err)
+		}
+	}
+	row := db.QueryRowContext(ctx, c.loadSql(), path, now)
+	if err := row.Scan(&data); err != nil {
+		return nil, translate(err)
+	}
+	return data, nil
+
+}
+
+// SetExpiryCtx implements backend.Expirer with a single statement.
+func (c *SqliteClient) SetExpiryCtx(ctx context.Context, path string, expiry *time.Time) error {
+
+	res, err := c.DB.ExecContext(ctx, c.setExpirySql(),
+		path, nowNanos(), toNullNanos(expiry))
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+
+}
+
+// LoadRawVersionCtx implements backend.Versioner.
+func (c *SqliteClient) LoadRawVersionCtx(ctx context.Context, path string) ([]byte, int64, error) {
+
+	now := nowNanos()
+	var data []byte
+	var version int64
+	if c.SlidingExpiry > 0 {
+		row := c.DB.QueryRowContext(ctx, c.loadRenewSql("data,version"),
+			path, now, c.SlidingExpiry.Nanoseconds())
+		err := row.Scan(&data, &version)
+		if err == nil {
+			return data, version, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, translate(err)
+		}
+	}
+	row := c.DB.QueryRowContext(ctx, c.loadVersionSql(), path, now)
+	if err := row.Scan(&data, &version); err != nil {
+		return nil, 0, translate(err)
+	}
+	return data, version, nil
+
+}
+
+// SaveRawIfVersionCtx implements backend.Versioner.
+func (c *SqliteClient) SaveRawIfVersionCtx(ctx context.Context, path string, raw_obj []byte, expiry *time.Time, version int64) error {
+
+	args := []any{path, string(raw_obj), len(raw_obj), toNullNanos(expiry),
+		nowNanos()}
+	query := c.saveIfNotExistsSql()
+	if version != 0 {
+		query = c.saveIfVersionSql()
+		args = append(args, version)
+	}
+	res, err := c.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return translate(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: %s not at version %d", backend.ErrConflict,
+			path, version)
+	}
+	return nil
+
+}
+
+// LoadDetail retrieves the details of the object at path and returns its
+// a jsobs.Detailer.
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *SqliteClient) LoadDetail(path string) (backend.Detailer, error) {
+	return c.LoadDetailCtx(context.Background(), path)
+}
+
+// LoadDetailCtx is LoadDetail with a context.
+func (c *SqliteClient) LoadDetailCtx(ctx context.Context, path string) (backend.Detailer, error) {
+
+	row := c.DB.QueryRowContext(ctx, c.loadDetailSql(), path, nowNanos())
+	detail := &SqliteDetailer{}
+	if err := detail.Scan(row); err != nil {
+		return nil, translate(err)
+	}
+	return detail, nil
+}
+
+// Delete deletes the object at path.
+//
+// As with pgclient, an expired object will still be deleted, and if
+// SoftDelete is true the object is moved to the trash instead.
+//
+// If the object does not exist, the error returned will be ErrNotFound.
+func (c *SqliteClient) Delete(path string) error {
+	return c.DeleteCtx(context.Background(), path)
+}
+
+// DeleteCtx is Delete with a context.
+func (c *SqliteClient) DeleteCtx(ctx context.Context, path string) error {
+
+	affected, err := c.delete(ctx, c.deleteSql(), "obj_path = ?1", path)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// delete executes the delete query, first copying the rows matching cond to
+// the trash in the same transaction if SoftDelete is true.  It returns the
+// number of rows deleted.
+func (c *SqliteClient) delete(ctx context.Context, query string, cond string, arg any) (int64, error) {
+
+	if !c.SoftDelete {
+		return c.deleteOn(ctx, c.DB, query, cond, arg)
+	}
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	affected, err := c.deleteOn(ctx, tx, query, cond, arg)
+	if err != nil {
+		return 0, err
+	}
+	return affected, tx.Commit()
+
+}
+
+// deleteOn is delete on db, which must be a transaction if SoftDelete is
+// true.
+func (c *SqliteClient) deleteOn(ctx context.Context, db querier, query string, cond string, arg any) (int64, error) {
+
+	if c.SoftDelete {
+		if _, err := db.ExecContext(ctx, c.trashSql(cond), arg, nowNanos()); err != nil {
+			return 0, err
+		}
+	}
+	res, err := db.ExecContext(ctx, query, arg)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+
+}
+
+// SaveRawManyCtx implements backend.BatchClient.  All the saves are made in
+// one transaction: if any fails, none are saved.
+func (c *SqliteClient) SaveRawManyCtx(ctx context.Context, raw_objs map[string][]byte) error {
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	stmt, err := tx.PrepareContext(ctx, c.saveSql())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := nowNanos()
+	for path, raw_obj := range raw_objs {
+		_, err := stmt.ExecContext(ctx,
+			path, string(raw_obj), len(raw_obj), nil, now, nil)
+		if err != nil {
+			return translate(err)
+		}
+	}
+	return tx.Commit()
+
+}
+
+// LoadRawManyCtx implements backend.BatchClient with a single query.
+func (c *SqliteClient) LoadRawManyCtx(ctx context.Context, paths []string) (map[string][]byte, error) {
+
+	b, err := json.Marshal(paths)
+	if err != nil {
+		return nil, err
+	}
+	now := nowNanos()
+	if c.SlidingExpiry > 0 {
+		_, err := c.DB.ExecContext(ctx, c.renewManySql(), string(b), now,
+			c.SlidingExpiry.Nanoseconds())
+		if err != nil {
+			return nil, err
+		}
+	}
+	rows, err := c.DB.QueryContext(ctx, c.loadManySql(), string(b), now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	loaded := make(map[string][]byte, len(paths))
+	for rows.Next() {
+		var path string
+		var data []byte
+		if err := rows.Scan(&path, &data); err != nil {
+			return nil, err
+		}
+		loaded[path] = data
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+
+}
+
+// DeleteManyCtx implements backend.BatchClient with a single statement.
+// If SoftDelete is true the objects are moved to the trash instead.
+func (c *SqliteClient) DeleteManyCtx(ctx context.Context, paths []string) (int, error) {
+
+	b, err := json.Marshal(paths)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := c.delete(ctx, c.deleteManySql(),
+		"obj_path IN (SELECT value FROM json_each(?1))", string(b))
+	return int(affected), err
+
+}
+
+// DeletePrefixCtx implements backend.PrefixDeleter with a single statement.
+// If SoftDelete is true the objects are moved to the trash instead.
+func (c *SqliteClient) DeletePrefixCtx(ctx context.Context, prefix string) (int, error) {
+
+	if err := backend.CheckDeletePrefix(prefix); err != nil {
+		return 0, err
+	}
+	affected, err := c.delete(ctx, c.deletePrefixSql(),
+		"substr(obj_path,1,length(?1)) = ?1", prefix)
+	return int(affected), err
+
+}
+
+// List returns an array of all objects beginning with prefix.  An empty array
+// is not considered an error.
+func (c *SqliteClient) List(prefix string) ([]string, error) {
+	return c.ListCtx(context.Background(), prefix)
+}
+
+// ListCtx is List with a context.
+func (c *SqliteClient) ListCtx(ctx context.Context, prefix string) ([]string, error) {
+	return c.queryPaths(ctx, c.DB, c.listSql(), prefix, nowNanos())
+}
+
+// queryPaths returns the paths in the single column selected by query on
+// db.
+func (c *SqliteClient) queryPaths(ctx context.Context, db querier, query string, args ...any) ([]string, error) {
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+
+}
+
+// ListDetail returns an array of all Detailers describing all objects
+// beginning with prefix.  An empty array is not considered an error.
+func (c *SqliteClient) ListDetail(prefix string) ([]backend.Detailer, error) {
+	return c.ListDetailCtx(context.Background(), prefix)
+}
+
+// ListDetailCtx is ListDetail with a context.
+func (c *SqliteClient) ListDetailCtx(ctx context.Context, prefix string) ([]backend.Detailer, error) {
+	return c.queryDetailers(ctx, c.listDetailSql(), prefix, nowNanos())
+}
+
+// queryDetailers returns the Detailers for the rows selected by query.
+func (c *SqliteClient) queryDetailers(ctx context.Context, query string, args ...any) ([]backend.Detailer, error) {
+
+	rows, err := c.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	detailers := []backend.Detailer{}
+	for rows.Next() {
+		d := &SqliteDetailer{}
+		if err := d.Scan(rows); err != nil {
+			return nil, err
+		}
+		detailers = append(detailers, d)
+	}
+	return detailers, rows.Err()
+
+}
+
+// ListPageCtx implements backend.Pager, using the primary key so that each
+// page is a single index range scan however deep into the listing it is.
+func (c *SqliteClient) ListPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]string, string, error) {
+
+	after, err := backend.CheckPage(cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// One extra row tells us whether there is another page.
+	paths, err := c.queryPaths(ctx, c.DB, c.listPageSql(),
+		prefix, nowNanos(), after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(paths) > limit {
+		paths = paths[:limit]
+		next = backend.EncodeCursor(paths[limit-1])
+	}
+	return paths, next, nil
+
+}
+
+// ListDetailPageCtx implements backend.Pager as for ListPageCtx.
+func (c *SqliteClient) ListDetailPageCtx(ctx context.Context, prefix string, cursor string, limit int) ([]backend.Detailer, string, error) {
+
+	after, err := backend.CheckPage(cursor, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	detailers, err := c.queryDetailers(ctx, c.listDetailPageSql(),
+		prefix, nowNanos(), after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(detailers) > limit {
+		detailers = detailers[:limit]
+		next = backend.EncodeCursor(detailers[limit-1].Path())
+	}
+	return detailers, next, nil
+
+}
+
+// ListDirCtx implements backend.DirLister, rolling up the common prefixes
+// in the database so that only the directory entries are returned.
+func (c *SqliteClient) ListDirCtx(ctx context.Context, prefix string, delimiter string) ([]string, []string, error) {
+
+	if err := backend.CheckDelimiter(delimiter); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := c.DB.QueryContext(ctx, c.listDirSql(),
+		prefix, nowNanos(), delimiter)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	prefixes := []string{}
+	for rows.Next() {
+		var entry string
+		var is_prefix bool
+		if err := rows.Scan(&entry, &is_prefix); err != nil {
+			return nil, nil, err
+		}
+		if is_prefix {
+			prefixes = append(prefixes, entry)
+		} else {
+			paths = append(paths, entry)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return paths, prefixes, nil
+
+}
+
+// ListDetailMetaCtx implements backend.MetaLister.
+func (c *SqliteClient) ListDetailMetaCtx(ctx context.Context, prefix string, meta map[string]string) ([]backend.Detailer, error) {
+	b, err := metaJson(meta)
+	if err != nil {
+		return nil, err
+	}
+	return c.queryDetailers(ctx, c.listDetailMetaSql(),
+		prefix, nowNanos(), string(b))
+}
+
+// CountMetaCtx implements backend.MetaLister.
+func (c *SqliteClient) CountMetaCtx(ctx context.Context, prefix string, meta map[string]string) (int, error) {
+
+	b, err := metaJson(meta)
+	if err != nil {
+		return 0, err
+	}
+	count := -1
+	row := c.DB.QueryRowContext(ctx, c.countMetaSql(),
+		prefix, nowNanos(), string(b))
+	err = row.Scan(&count)
+	return count, err
+
+}
+
+// Count returns the number of non-expired objects beginning with prefix.
+// If none are found, zero is returned.
+func (c *SqliteClient) Count(prefix string) (int, error) {
+	return c.CountCtx(context.Background(), prefix)
+}
+
+// CountCtx is Count with a context.
+func (c *SqliteClient) CountCtx(ctx context.Context, prefix string) (int, error) {
+
+	count := -1
+	row := c.DB.QueryRowContext(ctx, c.countSql(), prefix, nowNanos())
+	err := row.Scan(&count)
+	return count, err
+
+}
+
+// CountAll returns the total number of non-expired objects in the database.
+func (c *SqliteClient) CountAll() (int, error) {
+	return c.CountAllCtx(context.Background())
+}
+
+// CountAllCtx is CountAll with a context.
+func (c *SqliteClient) CountAllCtx(ctx context.Context) (int, error) {
+
+	count := -1
+	row := c.DB.QueryRowContext(ctx, c.countAllSql(), nowNanos())
+	err := row.Scan(&count)
+	return count, err
+}
+
+// Purge deletes expired items from the database, if SoftDelete is true the
+// items in the trash past TrashRetention, and the revisions outside
+// HistoryKeep and HistoryMaxAge.  Returns the number of rows deleted.
+func (c *SqliteClient) Purge() (int, error) {
+	return c.PurgeCtx(context.Background())
+}
+
+// PurgeCtx is Purge with a context.
+func (c *SqliteClient) PurgeCtx(ctx context.Context) (int, error) {
+
+	purged, err := c.purge(ctx, c.purgeSql(), nowNanos())
+	if err == nil && c.SoftDelete {
+		var trashed int
+		trashed, err = c.purge(ctx, c.purgeTrashSql(), c.trashCutoff())
+		purged += trashed
+	}
+	if err != nil || !c.boundsHistory() {
+		return purged, err
+	}
+	pruned, err := c.PruneHistoryCtx(ctx, c.HistoryKeep, c.HistoryMaxAge)
+	return purged + pruned, err
+
+}
+
+// PurgeBatch deletes at most limit items from the database as for Purge,
+// expired items first.  Returns the number of rows deleted.
+func (c *SqliteClient) PurgeBatch(limit int) (int, error) {
+	return c.PurgeBatchCtx(context.Background(), limit)
+}
+
+// PurgeBatchCtx is PurgeBatch with a context.
+func (c *SqliteClient) PurgeBatchCtx(ctx context.Context, limit int) (int, error) {
+
+	purged, err := c.purge(ctx, c.purgeBatchSql(), nowNanos(), limit)
+	if err == nil && c.SoftDelete && purged < limit {
+		var trashed int
+		trashed, err = c.purge(ctx, c.purgeTrashBatchSql(),
+			c.trashCutoff(), limit-purged)
+		purged += trashed
+	}
+	if err != nil || !c.boundsHistory() || purged >= limit {
+		return purged, err
+	}
+	pruned, err := c.pruneHistoryBatch(ctx, limit-purged)
+	return purged + pruned, err
+
+}
+
+// purge executes a purge query and returns the number of rows deleted.
+func (c *SqliteClient) purge(ctx context.Context, query string, args ...any) (int, error) {
+
+	res, err := c.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	return int(affected), err
+
+}
+
+// Schema returns the SQL required to create this client's Table.
+func (c *SqliteClient) Schema() string {
+	return c.schemaSql()
+}
+
+// CreateTable executes the SQL returned from Schema on the current database.
+// If the table exists an error is returned.  For obvious reasons there is no
+// corresponding DropTable function.
+func (c *SqliteClient) CreateTable() error {
+
+	_, err := c.DB.Exec(c.Schema())
+	return err
+}
+
+// Shutdown calls Purge if PurgeOnShutdown is true, and then closes the DB.
+// The client can not be used after Shutdown; if the DB is shared, call Purge
+// instead.
+func (c *SqliteClient) Shutdown() error {
+	return c.ShutdownCtx(context.Background())
+}
+
+// ShutdownCtx is Shutdown with a context, which is useful for limiting the
+// time spent purging.  The DB is closed even if the purge fails.
+func (c *SqliteClient) ShutdownCtx(ctx context.Context) error {
+	var err error
+	if c.PurgeOnShutdown {
+		_, err = c.PurgeCtx(ctx)
+	}
+	if cerr := c.DB.Close(); err == nil {
+		err = cerr
+	}
+	return err
+
+}
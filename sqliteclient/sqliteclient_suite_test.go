@@ -0,0 +1,122 @@
+// sqliteclient_suite_test.go -- test suite rigging
+
+package sqliteclient_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/biztos/jsobs/sqliteclient"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SqliteClientTestSuite struct {
+	suite.Suite
+	Client *sqliteclient.SqliteClient
+	Dir    string
+	File   string
+}
+
+// Use one client per suite, in a fresh temp file.
+func (suite *SqliteClientTestSuite) SetupSuite() {
+
+	require := suite.Require()
+
+	dir, err := os.MkdirTemp("", "jsobs_sqlite_test_")
+	require.NoError(err, "temp dir")
+	suite.Dir = dir
+	suite.File = filepath.Join(dir, "test.db")
+
+	client, err := sqliteclient.NewForFile(suite.File)
+	require.NoError(err, "client setup err")
+	suite.Client = client
+
+	require.NoError(suite.Client.CreateTable(), "create table")
+	require.NoError(suite.Client.CreateHistory(), "create history")
+	require.NoError(suite.Client.CreateTrash(), "create trash")
+
+}
+
+// Remove the test database unless KEEP_DB is set.
+func (suite *SqliteClientTestSuite) TearDownSuite() {
+
+	require := suite.Require()
+
+	require.NoError(suite.Client.DB.Close(), "close db")
+
+	val := os.Getenv("KEEP_DB")
+	if val != "" && val != "0" && val != "false" {
+		return
+	}
+
+	require.NoError(os.RemoveAll(suite.Dir), "remove temp dir")
+
+}
+
+// Zero out the tables per test, so there are no fragments.
+func (suite *SqliteClientTestSuite) SetupTest() {
+
+	require := suite.Require()
+
+	sql := fmt.Sprintf(`DELETE FROM %[1]s; DELETE FROM %[1]s_history;
+DELETE FROM %[1]s_trash;`, suite.Client.Table)
+	_, err := suite.Client.DB.Exec(sql)
+	require.NoError(err, "exec delete")
+
+}
+
+func (suite *SqliteClientTestSuite) FullCount() int {
+
+	require := suite.Require()
+
+	sql := fmt.Sprintf("SELECT COUNT(*) FROM %s;", suite.Client.Table)
+	var count int
+	err := suite.Client.DB.QueryRow(sql).Scan(&count)
+	require.NoError(err, "query")
+	return count
+
+}
+
+// see below...
+type SaveSetResult struct {
+	Paths    []string
+	PathData map[string][]byte
+}
+
+// We need sets of data a lot, with expiry in future or not at all.
+func (suite *SqliteClientTestSuite) SaveSet(count int, pfmt string, exp *time.Time) *SaveSetResult {
+
+	require := suite.Require() // bail out on err
+
+	paths := make([]string, count)
+	path_data := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		path := fmt.Sprintf(pfmt, i)
+		data := []byte(fmt.Sprintf(`{"n":%d}`, i))
+		paths[i] = path
+		path_data[path] = data
+		var err error
+		if exp == nil {
+			err = suite.Client.SaveRaw(path, data)
+		} else {
+			err = suite.Client.SaveRawExpiry(path, data, *exp)
+		}
+
+		require.NoError(err, "save error")
+
+	}
+	return &SaveSetResult{
+		Paths:    paths,
+		PathData: path_data,
+	}
+
+}
+
+// The actual runner func:
+func TestSqliteClientTestSuite(t *testing.T) {
+	suite.Run(t, new(SqliteClientTestSuite))
+}
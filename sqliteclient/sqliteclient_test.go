@@ -0,0 +1,937 @@
+// sqliteclient_test.go
+//
+// suite rigging is in sqliteclient_suite_test.go, actual tests are here.
+
+package sqliteclient_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
+	"github.com/biztos/jsobs/sqliteclient"
+)
+
+func (suite *SqliteClientTestSuite) TestNewFailsNoFile() {
+
+	require := suite.Require()
+
+	sqliteclient.DatabaseFileEnvVar = "OTHER_SQLITE_DATABASE"
+	os.Setenv("OTHER_SQLITE_DATABASE", "")
+	defer func() { sqliteclient.DatabaseFileEnvVar = "SQLITE_DATABASE" }()
+
+	_, err := sqliteclient.New()
+	require.ErrorContains(err, "OTHER_SQLITE_DATABASE not defined in env")
+
+}
+
+func (suite *SqliteClientTestSuite) TestNewFailsBadFile() {
+
+	require := suite.Require()
+
+	orig := os.Getenv("SQLITE_DATABASE")
+	defer os.Setenv("SQLITE_DATABASE", orig)
+
+	os.Setenv("SQLITE_DATABASE", filepath.Join(suite.Dir, "nope", "x.db"))
+	_, err := sqliteclient.New()
+	require.ErrorContains(err, "unable to open database file")
+
+}
+
+func (suite *SqliteClientTestSuite) TestNewFailsBadDriver() {
+
+	require := suite.Require()
+
+	sqliteclient.DriverName = "nonesuch"
+	defer func() { sqliteclient.DriverName = "sqlite3" }()
+
+	_, err := sqliteclient.NewForFile(suite.File)
+	require.ErrorContains(err, "unknown driver")
+
+}
+
+func (suite *SqliteClientTestSuite) TestNewUsesEnvOK() {
+
+	require := suite.Require()
+
+	orig := os.Getenv("SQLITE_DATABASE")
+	defer os.Setenv("SQLITE_DATABASE", orig)
+	os.Setenv("SQLITE_DATABASE", suite.File)
+
+	c, err := sqliteclient.New()
+	require.NoError(err, "connect error")
+	defer c.DB.Close()
+	require.Equal(sqliteclient.DefaultTable, c.Table)
+	require.True(c.PurgeOnShutdown)
+
+	c.Table = suite.Client.Table
+	_, err = c.CountAll()
+	require.NoError(err, "query error")
+
+}
+
+func (suite *SqliteClientTestSuite) TestStringOK() {
+
+	require := suite.Require()
+
+	exp := fmt.Sprintf("sqliteclient (table=%s)", suite.Client.Table)
+	require.Equal(exp, suite.Client.String(), "String")
+}
+
+func (suite *SqliteClientTestSuite) TestSchemaOK() {
+
+	require := suite.Require()
+
+	c := sqliteclient.NewForDB(suite.Client.DB)
+	c.Table = "schema_test"
+	require.Contains(c.Schema(), "CREATE TABLE schema_test (")
+	require.NoError(c.CreateTable(), "create")
+	require.ErrorContains(c.CreateTable(), "already exists")
+
+}
+
+func (suite *SqliteClientTestSuite) TestSaveRawFailsBadJson() {
+
+	require := suite.Require()
+
+	data := []byte("not json")
+	err := suite.Client.SaveRaw("/any", data)
+	require.ErrorContains(err, "CHECK constraint failed")
+	require.ErrorIs(err, backend.ErrInvalidJson)
+
+}
+
+func (suite *SqliteClientTestSuite) TestSaveRawExpiryFailsBadJson() {
+
+	require := suite.Require()
+
+	data := []byte("not json")
+	err := suite.Client.SaveRawExpiry("/any", data, time.Now())
+	require.ErrorContains(err, "CHECK constraint failed")
+	require.ErrorIs(err, backend.ErrInvalidJson)
+
+}
+
+func (suite *SqliteClientTestSuite) TestLoadRawFailsNotFound() {
+
+	require := suite.Require()
+
+	data, err := suite.Client.LoadRaw("/not/here")
+	require.ErrorIs(err, sqliteclient.ErrNotFound)
+	require.ErrorIs(err, backend.ErrNotFound)
+	require.ErrorIs(err, sql.ErrNoRows, "driver error still there")
+	require.Nil(data)
+
+}
+
+func (suite *SqliteClientTestSuite) TestLoadRawFailsExpired() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/expired/%d", &past)
+
+	data, err := suite.Client.LoadRaw("/expired/0")
+	require.ErrorIs(err, sqliteclient.ErrNotFound)
+	require.Nil(data)
+
+}
+
+func (suite *SqliteClientTestSuite) TestSaveRawLoadRawOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	err := suite.Client.SaveRaw("/any/thing.json", data)
+	require.NoError(err)
+
+	fetched, err := suite.Client.LoadRaw("/any/thing.json")
+	require.NoError(err)
+	require.EqualValues(data, fetched)
+
+}
+
+func (suite *SqliteClientTestSuite) TestSaveRawExpiryLoadRawOK() {
+
+	require := suite.Require()
+
+	data := []byte(`{"json": true}`)
+	err := suite.Client.SaveRawExpiry("/any/thing.json", data,
+		time.Now().Add(time.Hour))
+	require.NoError(err)
+
+	fetched, err := suite.Client.LoadRaw("/any/thing.json")
+	require.NoError(err)
+	require.EqualValues(data, fetched)
+
+}
+
+func (suite *SqliteClientTestSuite) TestLoadDetailFailsNotFound() {
+
+	require := suite.Require()
+	detail, err := suite.Client.LoadDetail("/nopers.json")
+	require.ErrorIs(err, sqliteclient.ErrNotFound, "not found")
+	require.ErrorIs(err, sql.ErrNoRows, "driver error")
+	require.Nil(detail, "detail returned")
+}
+
+func (suite *SqliteClientTestSuite) TestLoadDetailWithExpiryOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+
+	suite.SaveSet(1, "/detail/%d", &future)
+
+	detail, err := suite.Client.LoadDetail("/detail/0")
+	require.NoError(err, "load detail")
+
+	require.Equal("/detail/0", detail.Path(), "path")
+	require.Equal(7, detail.Size(), "size")
+	require.True(detail.Expires(), "expires")
+	require.WithinDuration(future, detail.Expiry(), time.Second, "expiry")
+
+	require.Greater(time.Since(detail.Modified()), time.Duration(0),
+		"modified not in future")
+	require.Less(time.Since(detail.Modified()), time.Second,
+		"modified not too old")
+
+}
+
+func (suite *SqliteClientTestSuite) TestLoadDetailWithoutExpiryOK() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/detail/%d", nil)
+
+	detail, err := suite.Client.LoadDetail("/detail/0")
+	require.NoError(err, "load detail")
+
+	require.Equal("/detail/0", detail.Path(), "path")
+	require.Equal(7, detail.Size(), "size")
+	require.False(detail.Expires(), "expires")
+	require.True(detail.Expiry().IsZero(), "expiry is zero time")
+
+}
+
+func (suite *SqliteClientTestSuite) TestCountingOK() {
+
+	require := suite.Require()
+
+	precount, err := suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(0, precount, "nothing there yet")
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(15, "/foo/%d", nil)
+	suite.SaveSet(5, "/foo/expired/%d", &past)
+
+	postcount, err := suite.Client.Count("/foo")
+	require.NoError(err)
+	require.Equal(15, postcount, "saved number")
+
+	allcount, err := suite.Client.CountAll()
+	require.NoError(err)
+	require.Equal(15, allcount, "all number")
+
+}
+
+func (suite *SqliteClientTestSuite) TestListOK() {
+
+	require := suite.Require()
+
+	saved := suite.SaveSet(15, "/any/thing/%02d.json", nil)
+
+	// Make sure LIKE-ish characters are not treated specially.
+	suite.SaveSet(1, "/any/th%%ng/%02d.json", nil)
+
+	paths, err := suite.Client.List("/any/thing")
+	require.NoError(err)
+	require.EqualValues(saved.Paths, paths, "paths")
+
+}
+
+func (suite *SqliteClientTestSuite) TestListEmptyOK() {
+
+	require := suite.Require()
+	paths, err := suite.Client.List("/no/such/stuff")
+	require.NoError(err)
+	require.EqualValues([]string{}, paths, "empty path list")
+
+}
+
+func (suite *SqliteClientTestSuite) TestListDetailEmptyOK() {
+
+	require := suite.Require()
+	detailers, err := suite.Client.ListDetail("/")
+	require.NoError(err)
+	require.EqualValues([]backend.Detailer{}, detailers, "empty list")
+}
+
+func (suite *SqliteClientTestSuite) TestListDetailsOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+	suite.SaveSet(3, "/detail/exp/%d", &future)
+	suite.SaveSet(3, "/detail/noexp/%d", nil)
+
+	detailers, err := suite.Client.ListDetail("/detail/")
+	require.NoError(err)
+	require.Equal(6, len(detailers), "detailers returned")
+
+	// They will have come back in path order.
+	for i, d := range detailers {
+		if i < 3 {
+			require.Equal(fmt.Sprintf("/detail/exp/%d", i), d.Path(), "path %d", i)
+			require.True(d.Expires(), "expires %d", i)
+			require.WithinDuration(future, d.Expiry(), time.Second, "expiry %d", i)
+
+		} else {
+			require.Equal(fmt.Sprintf("/detail/noexp/%d", i-3), d.Path(), "path %d", i)
+			require.False(d.Expires(), "expires %d", i)
+			require.True(d.Expiry().IsZero(), "expiry is zero time %d", i)
+
+		}
+
+		require.Less(time.Since(d.Modified()), time.Second,
+			"modified not too old %d", i)
+
+	}
+
+}
+
+func (suite *SqliteClientTestSuite) TestDeleteFailsNotFound() {
+
+	require := suite.Require()
+
+	err := suite.Client.Delete("/not/there")
+	require.ErrorIs(err, sqliteclient.ErrNotFound)
+}
+
+func (suite *SqliteClientTestSuite) TestDeleteOK() {
+
+	require := suite.Require()
+	suite.SaveSet(1, "/any/thing/%d.json", nil)
+
+	err := suite.Client.Delete("/any/thing/0.json")
+	require.NoError(err)
+	require.Equal(0, suite.FullCount(), "full count")
+
+}
+
+func (suite *SqliteClientTestSuite) TestPurgeOK() {
+
+	require := suite.Require()
+
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-1 * time.Hour)
+	keep_a := suite.SaveSet(5, "/keep/a/%02d.json", &future)
+	suite.SaveSet(5, "/purge/%02d.json", &past)
+	keep_b := suite.SaveSet(5, "/keep/b/%02d.json", nil)
+
+	require.Equal(15, suite.FullCount(), "full count")
+
+	purged, err := suite.Client.Purge()
+	require.NoError(err, "purge")
+	require.Equal(5, purged, "purge count")
+
+	require.Equal(10, suite.FullCount(), "full count")
+
+	keep_paths := make([]string, 0, len(keep_a.Paths)+len(keep_b.Paths))
+	keep_paths = append(keep_paths, keep_a.Paths...)
+	keep_paths = append(keep_paths, keep_b.Paths...)
+
+	paths, err := suite.Client.List("/keep/")
+	require.NoError(err)
+
+	require.EqualValues(keep_paths, paths, "path list")
+
+}
+
+func (suite *SqliteClientTestSuite) TestPurgeBatchOK() {
+
+	require := suite.Require()
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(7, "/purge/%02d.json", &past)
+	suite.SaveSet(3, "/keep/%02d.json", nil)
+
+	purged, err := suite.Client.PurgeBatch(5)
+	require.NoError(err, "first batch")
+	require.Equal(5, purged, "first batch count")
+	require.Equal(5, suite.FullCount(), "full count after first")
+
+	purged, err = suite.Client.PurgeBatch(5)
+	require.NoError(err, "second batch")
+	require.Equal(2, purged, "second batch count")
+
+	purged, err = suite.Client.PurgeBatch(5)
+	require.NoError(err, "third batch")
+	require.Equal(0, purged, "third batch count")
+	require.Equal(3, suite.FullCount(), "full count after all")
+
+}
+
+func (suite *SqliteClientTestSuite) TestShutdownOK() {
+
+	require := suite.Require()
+
+	// Shutdown closes the DB, so we need our own clients here.
+	newClient := func() *sqliteclient.SqliteClient {
+		c, err := sqliteclient.NewForFile(suite.File)
+		require.NoError(err, "open error")
+		c.Table = suite.Client.Table
+		return c
+	}
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(50, "/purge/%02d.json", &past)
+	require.Equal(50, suite.FullCount(), "full count pre shutdown")
+
+	c := newClient()
+	c.PurgeOnShutdown = false
+	require.NoError(c.Shutdown(), "shutdown without purge")
+	require.Equal(50, suite.FullCount(), "full count after no-purge shutdown")
+	_, err := c.CountAll()
+	require.Error(err, "db closed")
+
+	c = newClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(c.ShutdownCtx(ctx), context.Canceled, "canceled shutdown")
+	require.Equal(50, suite.FullCount(), "full count after canceled shutdown")
+	_, err = c.CountAll()
+	require.Error(err, "db closed after canceled shutdown")
+
+	c = newClient()
+	require.NoError(c.Shutdown(), "shutdown with purge")
+	require.Equal(0, suite.FullCount(), "full count after purging shutdown")
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsContextBackendClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.ContextBackendClient)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestContextCanceledFails() {
+
+	require := suite.Require()
+
+	suite.SaveSet(3, "/ctx/%d", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(suite.Client.SaveRawCtx(ctx, "/ctx/x", []byte("{}")),
+		context.Canceled, "SaveRawCtx")
+	require.ErrorIs(suite.Client.SaveRawExpiryCtx(ctx, "/ctx/x", []byte("{}"),
+		time.Now()), context.Canceled, "SaveRawExpiryCtx")
+	_, err := suite.Client.LoadRawCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadRawCtx")
+	_, err = suite.Client.LoadDetailCtx(ctx, "/ctx/0")
+	require.ErrorIs(err, context.Canceled, "LoadDetailCtx")
+	require.ErrorIs(suite.Client.DeleteCtx(ctx, "/ctx/0"), context.Canceled,
+		"DeleteCtx")
+	_, err = suite.Client.ListCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListCtx")
+	_, err = suite.Client.ListDetailCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "ListDetailCtx")
+	_, err = suite.Client.CountCtx(ctx, "/ctx/")
+	require.ErrorIs(err, context.Canceled, "CountCtx")
+	_, err = suite.Client.CountAllCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "CountAllCtx")
+	_, err = suite.Client.PurgeCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "PurgeCtx")
+
+	// Nothing happened.
+	paths, err := suite.Client.List("/ctx/")
+	require.NoError(err)
+	require.Equal([]string{"/ctx/0", "/ctx/1", "/ctx/2"}, paths)
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsVersioner() {
+
+	require := suite.Require()
+	require.Implements((*backend.Versioner)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestVersionsOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+
+	_, _, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.ErrorIs(err, backend.ErrNotFound, "not yet saved")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "update missing")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":1}`), nil, 0)
+	require.NoError(err, "create")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 0)
+	require.ErrorIs(err, backend.ErrConflict, "create existing")
+
+	data, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+	require.EqualValues(1, version)
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":2}`), nil, 1)
+	require.NoError(err, "update")
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 1)
+	require.ErrorIs(err, backend.ErrConflict, "stale update")
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":4}`)), "plain save")
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(3, detail.Version(), "detail version")
+
+	details, err := suite.Client.ListDetail("/ver/")
+	require.NoError(err)
+	require.Len(details, 1)
+	require.EqualValues(3, details[0].Version(), "list detail version")
+
+	data, version, err = suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	require.JSONEq(`{"n":4}`, string(data))
+	require.EqualValues(3, version)
+
+}
+
+func (suite *SqliteClientTestSuite) TestVersionsExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/ver/x"
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":1}`), past))
+	require.NoError(suite.Client.SaveRawExpiry(path, []byte(`{"n":2}`), past))
+
+	err := suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, 2)
+	require.ErrorIs(err, backend.ErrConflict, "update expired")
+
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), &future, 0)
+	require.NoError(err, "replace expired")
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.EqualValues(1, detail.Version(), "starts over")
+	require.True(detail.Expires(), "expiry saved")
+	require.WithinDuration(future, detail.Expiry(), time.Second)
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsBatchClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.BatchClient)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestManyOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	raw_objs := map[string][]byte{}
+	for i := 0; i < 50; i++ {
+		raw_objs[fmt.Sprintf("/many/%02d", i)] = []byte(fmt.Sprintf(`{"n":%d}`, i))
+	}
+	require.NoError(suite.Client.SaveRawManyCtx(ctx, raw_objs), "save")
+	require.Equal(50, suite.FullCount(), "full count")
+
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(1, "/many/old/%d", &past)
+
+	loaded, err := suite.Client.LoadRawManyCtx(ctx,
+		[]string{"/many/00", "/many/49", "/many/none", "/many/old/0"})
+	require.NoError(err, "load")
+	require.Len(loaded, 2, "only live objects")
+	require.JSONEq(`{"n":0}`, string(loaded["/many/00"]))
+	require.JSONEq(`{"n":49}`, string(loaded["/many/49"]))
+
+	deleted, err := suite.Client.DeleteManyCtx(ctx,
+		[]string{"/many/00", "/many/01", "/many/none", "/many/old/0"})
+	require.NoError(err, "delete")
+	require.Equal(3, deleted, "expired deleted too")
+	require.Equal(48, suite.FullCount(), "full count after delete")
+
+}
+
+func (suite *SqliteClientTestSuite) TestManyEmptyOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	require.NoError(suite.Client.SaveRawManyCtx(ctx, nil), "save")
+	loaded, err := suite.Client.LoadRawManyCtx(ctx, nil)
+	require.NoError(err, "load")
+	require.Empty(loaded)
+	deleted, err := suite.Client.DeleteManyCtx(ctx, nil)
+	require.NoError(err, "delete")
+	require.Zero(deleted)
+
+}
+
+func (suite *SqliteClientTestSuite) TestSaveRawManyAtomicFails() {
+
+	require := suite.Require()
+
+	err := suite.Client.SaveRawManyCtx(context.Background(), map[string][]byte{
+		"/many/a": []byte(`{"n":1}`),
+		"/many/b": []byte(`not json`),
+		"/many/c": []byte(`{"n":3}`),
+	})
+	require.ErrorIs(err, backend.ErrInvalidJson)
+	require.Equal(0, suite.FullCount(), "nothing saved")
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsPager() {
+
+	require := suite.Require()
+	require.Implements((*backend.Pager)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestListPageOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	set := suite.SaveSet(10, "/page/%02d", nil)
+	suite.SaveSet(3, "/page/%02d-old", &past)
+	suite.SaveSet(2, "/pages/%02d", nil)
+
+	paths := []string{}
+	cursor := ""
+	pages := 0
+	for {
+		page, next, err := suite.Client.ListPageCtx(ctx, "/page/", cursor, 4)
+		require.NoError(err)
+		require.LessOrEqual(len(page), 4, "page size")
+		paths = append(paths, page...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	require.Equal(set.Paths, paths, "all paths in order")
+	require.Equal(3, pages, "pages")
+
+}
+
+func (suite *SqliteClientTestSuite) TestListDetailPageOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	set := suite.SaveSet(6, "/page/%02d", nil)
+
+	detailers, next, err := suite.Client.ListDetailPageCtx(ctx, "/page/", "", 3)
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(set.Paths[0], detailers[0].Path())
+	require.NotEmpty(next)
+
+	detailers, next, err = suite.Client.ListDetailPageCtx(ctx, "/page/", next, 3)
+	require.NoError(err)
+	require.Len(detailers, 3)
+	require.Equal(set.Paths[5], detailers[2].Path())
+	require.EqualValues(1, detailers[2].Version())
+	require.Empty(next, "exactly two pages")
+
+}
+
+func (suite *SqliteClientTestSuite) TestListPageFailsBadArgs() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	_, _, err := suite.Client.ListPageCtx(ctx, "/page/", "?", 3)
+	require.ErrorIs(err, backend.ErrInvalidCursor)
+	_, _, err = suite.Client.ListDetailPageCtx(ctx, "/page/", "", 0)
+	require.ErrorContains(err, "page limit must be positive")
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsDirLister() {
+
+	require := suite.Require()
+	require.Implements((*backend.DirLister)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestListDirOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(2, "/dir/%d", nil)
+	suite.SaveSet(3, "/dir/sub/%d", nil)
+	suite.SaveSet(1, "/dir/sub/deeper/%d", nil)
+	suite.SaveSet(2, "/dir/old/%d", &past)
+	suite.SaveSet(1, "/dir/trailing/%d/", nil)
+	suite.SaveSet(1, "/dirt/%d", nil)
+
+	paths, prefixes, err := suite.Client.ListDirCtx(ctx, "/dir/", "/")
+	require.NoError(err)
+	require.Equal([]string{"/dir/0", "/dir/1"}, paths, "paths")
+	require.Equal([]string{"/dir/sub/", "/dir/trailing/"}, prefixes,
+		"prefixes, not expired")
+
+	paths, prefixes, err = suite.Client.ListDirCtx(ctx, "/dir/sub/", "/")
+	require.NoError(err)
+	require.Equal([]string{"/dir/sub/0", "/dir/sub/1", "/dir/sub/2"}, paths)
+	require.Equal([]string{"/dir/sub/deeper/"}, prefixes)
+
+	paths, prefixes, err = suite.Client.ListDirCtx(ctx, "/di", "r/s")
+	require.NoError(err)
+	require.Equal([]string{"/dir/0", "/dir/1", "/dir/trailing/0/", "/dirt/0"},
+		paths, "odd delimiter paths")
+	require.Equal([]string{"/dir/s"}, prefixes, "odd delimiter prefixes")
+
+	paths, prefixes, err = suite.Client.ListDirCtx(ctx, "/none/", "/")
+	require.NoError(err)
+	require.Empty(paths)
+	require.Empty(prefixes)
+
+}
+
+func (suite *SqliteClientTestSuite) TestListDirFailsEmptyDelimiter() {
+
+	require := suite.Require()
+
+	_, _, err := suite.Client.ListDirCtx(context.Background(), "/dir/", "")
+	require.EqualError(err, "delimiter must not be empty")
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsMetaClient() {
+
+	require := suite.Require()
+	require.Implements((*backend.MetaClient)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestSaveRawMetaOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	meta := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	exp := map[string]string{"Owner": "bob", "trace-id": "a/b c"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	meta["Owner"] = "changed" // not kept by reference
+
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Equal(exp, detail.Meta())
+	require.False(detail.Expires())
+
+	// Other saves keep it.
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	_, version, err := suite.Client.LoadRawVersionCtx(ctx, path)
+	require.NoError(err)
+	err = suite.Client.SaveRawIfVersionCtx(ctx, path, []byte(`{"n":3}`), nil, version)
+	require.NoError(err)
+	detailers, err := suite.Client.ListDetail("/meta/")
+	require.NoError(err)
+	require.Len(detailers, 1)
+	require.Equal(exp, detailers[0].Meta(), "kept")
+
+	// Saving with nil removes it.
+	err = suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":4}`), nil, nil)
+	require.NoError(err)
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta())
+
+}
+
+func (suite *SqliteClientTestSuite) TestSaveRawMetaExpiredOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/meta/x"
+	past := time.Now().Add(-time.Hour)
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), &past,
+		map[string]string{"k": "v"})
+	require.NoError(err)
+	_, err = suite.Client.LoadDetail(path)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	require.NoError(suite.Client.SaveRaw(path, []byte(`{"n":2}`)))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.Empty(detail.Meta(), "not kept from expired object")
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsMetaLister() {
+
+	require := suite.Require()
+	require.Implements((*backend.MetaLister)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestListDetailMetaOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	data := []byte(`{}`)
+	saves := []struct {
+		path   string
+		expiry *time.Time
+		meta   map[string]string
+	}{
+		{"/m/a", nil, map[string]string{"kind": "x", "owner": "bob"}},
+		{"/m/b", nil, map[string]string{"kind": "x"}},
+		{"/m/c", nil, nil},
+		{"/m/d", &past, map[string]string{"kind": "x"}},
+		{"/n/e", nil, map[string]string{"kind": "x"}},
+	}
+	for _, s := range saves {
+		require.NoError(suite.Client.SaveRawMetaCtx(ctx, s.path, data,
+			s.expiry, s.meta))
+	}
+
+	for _, tc := range []struct {
+		meta  map[string]string
+		paths []string
+	}{
+		{map[string]string{"kind": "x"}, []string{"/m/a", "/m/b"}},
+		{map[string]string{"kind": "x", "owner": "bob"}, []string{"/m/a"}},
+		{map[string]string{"kind": "y"}, []string{}},
+		{map[string]string{"owner": "x"}, []string{}},
+		{map[string]string{}, []string{"/m/a", "/m/b", "/m/c"}},
+	} {
+		detailers, err := suite.Client.ListDetailMetaCtx(ctx, "/m/", tc.meta)
+		require.NoError(err)
+		paths := []string{}
+		for _, d := range detailers {
+			paths = append(paths, d.Path())
+		}
+		require.Equal(tc.paths, paths, "list %v", tc.meta)
+		count, err := suite.Client.CountMetaCtx(ctx, "/m/", tc.meta)
+		require.NoError(err)
+		require.Equal(len(tc.paths), count, "count %v", tc.meta)
+	}
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsExpirer() {
+
+	require := suite.Require()
+	require.Implements((*backend.Expirer)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestSetExpiryOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	path := "/expiry/x"
+	meta := map[string]string{"owner": "bob"}
+	err := suite.Client.SaveRawMetaCtx(ctx, path, []byte(`{"n":1}`), nil, meta)
+	require.NoError(err)
+	before, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+
+	expiry := time.Now().Add(time.Hour).Round(time.Millisecond)
+	require.NoError(suite.Client.SetExpiryCtx(ctx, path, &expiry))
+	detail, err := suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.True(expiry.Equal(detail.Expiry()), "expiry set")
+	require.Equal(before.Version(), detail.Version(), "version unchanged")
+	require.True(before.Modified().Equal(detail.Modified()), "modified unchanged")
+	require.Equal(meta, detail.Meta(), "meta unchanged")
+	data, err := suite.Client.LoadRaw(path)
+	require.NoError(err)
+	require.JSONEq(`{"n":1}`, string(data))
+
+	require.NoError(suite.Client.SetExpiryCtx(ctx, path, nil))
+	detail, err = suite.Client.LoadDetail(path)
+	require.NoError(err)
+	require.False(detail.Expires(), "expiry cleared")
+
+}
+
+func (suite *SqliteClientTestSuite) TestSetExpiryFailsNotFound() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	expiry := time.Now().Add(time.Hour)
+	err := suite.Client.SetExpiryCtx(ctx, "/expiry/none", &expiry)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+	past := time.Now().Add(-time.Hour)
+	require.NoError(suite.Client.SaveRawExpiry("/expiry/old", []byte(`1`), past))
+	err = suite.Client.SetExpiryCtx(ctx, "/expiry/old", &expiry)
+	require.ErrorIs(err, backend.ErrNotFound)
+
+}
+
+func (suite *SqliteClientTestSuite) TestSlidingExpiryOK() {
+
+	defer func() { suite.Client.SlidingExpiry = 0 }()
+	suite.Client.SlidingExpiry = time.Hour
+	backendtest.SlidingExpiryOK(suite.Require(), suite.Client)
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsPrefixDeleter() {
+
+	require := suite.Require()
+	require.Implements((*backend.PrefixDeleter)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestDeletePrefixOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	past := time.Now().Add(-1 * time.Hour)
+	suite.SaveSet(3, "/del_/thing/%d.json", nil)
+	suite.SaveSet(2, "/del_/expired/%d.json", &past)
+	suite.SaveSet(2, "/delx/%d.json", nil)
+
+	deleted, err := suite.Client.DeletePrefixCtx(ctx, "/del_/")
+	require.NoError(err)
+	require.Equal(5, deleted, "expired too")
+	require.Equal(2, suite.FullCount(), "full count")
+
+	deleted, err = suite.Client.DeletePrefixCtx(ctx, "/del_/")
+	require.NoError(err)
+	require.Equal(0, deleted)
+
+}
+
+func (suite *SqliteClientTestSuite) TestDeletePrefixFailsEmpty() {
+
+	require := suite.Require()
+
+	suite.SaveSet(1, "/any/%d.json", nil)
+	_, err := suite.Client.DeletePrefixCtx(context.Background(), "")
+	require.ErrorIs(err, backend.ErrInvalidPath)
+	require.Equal(1, suite.FullCount(), "full count")
+
+}
@@ -0,0 +1,114 @@
+// trash.go - soft deletion
+//
+// As for pgclient, deleted objects are moved to a second table,
+// <Table>_trash, if SoftDelete is set.
+
+package sqliteclient
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// SqliteDeletedDetailer implements backend.DeletedDetailer to describe an
+// object in the trash.
+type SqliteDeletedDetailer struct {
+	SqliteDetailer
+	deleted time.Time
+}
+
+// Scan scans a database row.
+func (d *SqliteDeletedDetailer) Scan(row scanner) error {
+	var deleted int64
+	if err := d.scan(row, &deleted); err != nil {
+		return err
+	}
+	d.deleted = time.Unix(0, deleted)
+	return nil
+}
+
+// Deleted implements backend.DeletedDetailer.
+func (d *SqliteDeletedDetailer) Deleted() time.Time {
+	return d.deleted
+}
+
+// TrashSchema returns the SQL required to create the trash table for this
+// client's Table.
+func (c *SqliteClient) TrashSchema() string {
+	return c.trashSchemaSql()
+}
+
+// CreateTrash executes the SQL returned from TrashSchema on the current
+// database.  It must be called before setting SoftDelete, and may be called
+// again safely.
+func (c *SqliteClient) CreateTrash() error {
+
+	_, err := c.DB.Exec(c.TrashSchema())
+	return err
+}
+
+// trashCutoff returns the time, in Unix nanoseconds, at or before which
+// trashed objects are purged.
+func (c *SqliteClient) trashCutoff() int64 {
+	return time.Now().Add(-c.TrashRetention).UnixNano()
+}
+
+// UndeleteCtx implements backend.Trasher.  The object is removed from the
+// trash and restored in one transaction.
+func (c *SqliteClient) UndeleteCtx(ctx context.Context, path string) error {
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	var data []byte
+	var size int
+	var expiry sql.NullInt64
+	var modified, version int64
+	var meta sql.NullString
+	row := tx.QueryRowContext(ctx, c.untrashSql(), path)
+	if err := row.Scan(&data, &size, &expiry, &modified, &version, &meta); err != nil {
+		return translate(err)
+	}
+	res, err := tx.ExecContext(ctx, c.undeleteSql(),
+		path, string(data), size, expiry, modified, version, meta, nowNanos())
+	if err != nil {
+		return translate(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: %s exists", backend.ErrConflict, path)
+	}
+	return tx.Commit()
+
+}
+
+// ListDeletedCtx implements backend.Trasher.
+func (c *SqliteClient) ListDeletedCtx(ctx context.Context, prefix string) ([]backend.DeletedDetailer, error) {
+
+	rows, err := c.DB.QueryContext(ctx, c.listDeletedSql(), prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	detailers := []backend.DeletedDetailer{}
+	for rows.Next() {
+		d := &SqliteDeletedDetailer{}
+		if err := d.Scan(rows); err != nil {
+			return nil, err
+		}
+		detailers = append(detailers, d)
+	}
+	return detailers, rows.Err()
+
+}
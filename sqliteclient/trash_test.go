@@ -0,0 +1,93 @@
+// trash_test.go -- tests for soft deletion.
+//
+// The tests common to all Trasher backends are in backendtest.
+
+package sqliteclient_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+	"github.com/biztos/jsobs/backend/backendtest"
+)
+
+// softDelete turns on SoftDelete for the rest of the test.
+func (suite *SqliteClientTestSuite) softDelete() {
+
+	suite.Client.SoftDelete = true
+	suite.T().Cleanup(func() { suite.Client.SoftDelete = false })
+
+}
+
+func (suite *SqliteClientTestSuite) TestImplementsTrasher() {
+
+	require := suite.Require()
+	require.Implements((*backend.Trasher)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestCreateTrashAgainOK() {
+
+	require := suite.Require()
+
+	require.Contains(suite.Client.TrashSchema(), "_trash")
+	require.NoError(suite.Client.CreateTrash())
+
+}
+
+func (suite *SqliteClientTestSuite) TestSoftDeleteOK() {
+	suite.softDelete()
+	backendtest.SoftDeleteOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestSoftDeleteManyOK() {
+	suite.softDelete()
+	backendtest.SoftDeleteManyOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestSoftDeletePrefixOK() {
+	suite.softDelete()
+	backendtest.SoftDeletePrefixOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestHardDeleteSkipsTrashOK() {
+	backendtest.HardDeleteSkipsTrashOK(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestUndeleteFailsNotFound() {
+	backendtest.UndeleteFailsNotFound(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestUndeleteFailsConflict() {
+	suite.softDelete()
+	backendtest.UndeleteFailsConflict(suite.Require(), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestPurgeTrashOK() {
+
+	require := suite.Require()
+
+	suite.softDelete()
+	defer func(d time.Duration) { suite.Client.TrashRetention = d }(suite.Client.TrashRetention)
+	require.Equal(7*24*time.Hour, suite.Client.TrashRetention, "default")
+	backendtest.PurgeTrashOK(require, suite.Client, func() {
+		suite.Client.TrashRetention = 0
+	})
+
+}
+
+func (suite *SqliteClientTestSuite) TestSoftDeleteUsesClientClockOK() {
+
+	require := suite.Require()
+
+	suite.softDelete()
+	require.NoError(suite.Client.SaveRaw("/t/a", []byte(`1`)))
+	before := time.Now()
+	require.NoError(suite.Client.Delete("/t/a"))
+
+	deleted, err := suite.Client.ListDeletedCtx(context.Background(), "/t/")
+	require.NoError(err)
+	require.Len(deleted, 1)
+	require.False(deleted[0].Deleted().Before(before))
+
+}
@@ -0,0 +1,77 @@
+// tx.go - transactions spanning several operations
+
+package sqliteclient
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// querier runs statements on the DB, or within a transaction for SqliteTx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SqliteTx implements backend.TxClient within a sql.Tx.  The client's
+// settings, such as SoftDelete, apply as they do outside the transaction.
+type SqliteTx struct {
+	client *SqliteClient
+	tx     *sql.Tx
+}
+
+// TxCtx implements backend.Transactor over a sql.Tx, which is committed if
+// fn returns nil and otherwise rolled back.
+func (c *SqliteClient) TxCtx(ctx context.Context, fn func(backend.TxClient) error) error {
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	if err := fn(&SqliteTx{client: c, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+
+}
+
+// SaveRawCtx implements backend.TxClient.
+func (t *SqliteTx) SaveRawCtx(ctx context.Context, path string, raw_obj []byte) error {
+	return t.client.save(ctx, t.tx, path, raw_obj, nil, nil)
+}
+
+// SaveRawExpiryCtx implements backend.TxClient.
+func (t *SqliteTx) SaveRawExpiryCtx(ctx context.Context, path string, raw_obj []byte, expiry time.Time) error {
+	return t.client.save(ctx, t.tx, path, raw_obj, &expiry, nil)
+}
+
+// LoadRawCtx implements backend.TxClient.
+func (t *SqliteTx) LoadRawCtx(ctx context.Context, path string) ([]byte, error) {
+	return t.client.loadRaw(ctx, t.tx, path)
+}
+
+// DeleteCtx implements backend.TxClient.
+func (t *SqliteTx) DeleteCtx(ctx context.Context, path string) error {
+
+	affected, err := t.client.deleteOn(ctx, t.tx, t.client.deleteSql(),
+		"obj_path = ?1", path)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+
+}
+
+// ListCtx implements backend.TxClient.
+func (t *SqliteTx) ListCtx(ctx context.Context, prefix string) ([]string, error) {
+	return t.client.queryPaths(ctx, t.tx, t.client.listSql(), prefix, nowNanos())
+}
@@ -0,0 +1,85 @@
+// tx_test.go -- tests for transactions.
+
+package sqliteclient_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+func (suite *SqliteClientTestSuite) TestImplementsTransactor() {
+
+	require := suite.Require()
+	require.Implements((*backend.Transactor)(nil), suite.Client)
+}
+
+func (suite *SqliteClientTestSuite) TestTxOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	c := suite.Client
+	require.NoError(c.SaveRaw("/tx/old", []byte(`1`)))
+	err := c.TxCtx(ctx, func(tx backend.TxClient) error {
+		require.NoError(tx.SaveRawCtx(ctx, "/tx/a", []byte(`"a"`)))
+		data, err := tx.LoadRawCtx(ctx, "/tx/a")
+		require.NoError(err)
+		require.Equal(`"a"`, string(data))
+		require.NoError(tx.DeleteCtx(ctx, "/tx/old"))
+		require.ErrorIs(tx.DeleteCtx(ctx, "/tx/old"), backend.ErrNotFound)
+		_, err = tx.LoadRawCtx(ctx, "/tx/old")
+		require.ErrorIs(err, backend.ErrNotFound)
+		paths, err := tx.ListCtx(ctx, "/tx/")
+		require.NoError(err)
+		require.Equal([]string{"/tx/a"}, paths)
+		return nil
+	})
+	require.NoError(err)
+
+	paths, err := c.List("/tx/")
+	require.NoError(err)
+	require.Equal([]string{"/tx/a"}, paths)
+
+}
+
+func (suite *SqliteClientTestSuite) TestTxRollbackOK() {
+
+	require := suite.Require()
+
+	ctx := context.Background()
+	c := suite.Client
+	require.NoError(c.SaveRaw("/tx/old", []byte(`1`)))
+	oops := errors.New("oops")
+	err := c.TxCtx(ctx, func(tx backend.TxClient) error {
+		require.NoError(tx.SaveRawCtx(ctx, "/tx/a", []byte(`1`)))
+		require.NoError(tx.DeleteCtx(ctx, "/tx/old"))
+		return oops
+	})
+	require.ErrorIs(err, oops)
+
+	paths, err := c.List("/tx/")
+	require.NoError(err)
+	require.Equal([]string{"/tx/old"}, paths)
+
+}
+
+func (suite *SqliteClientTestSuite) TestTxSoftDeleteOK() {
+
+	require := suite.Require()
+
+	suite.softDelete()
+	ctx := context.Background()
+	c := suite.Client
+	require.NoError(c.SaveRaw("/tx/old", []byte(`1`)))
+	err := c.TxCtx(ctx, func(tx backend.TxClient) error {
+		return tx.DeleteCtx(ctx, "/tx/old")
+	})
+	require.NoError(err)
+
+	deleted, err := c.ListDeletedCtx(ctx, "/tx/")
+	require.NoError(err)
+	require.Len(deleted, 1)
+
+}
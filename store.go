@@ -0,0 +1,128 @@
+// store.go -- typed access to objects under a prefix.
+
+package jsobs
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Store provides typed access to the objects of type T kept under Prefix,
+// addressed by keys relative to it.  The key "bob" in a Store with Prefix
+// "/users/" is the object at "/users/bob".
+//
+// Nothing stops other code from saving something else under Prefix, in
+// which case Get and All will return the unmarshaling error.
+type Store[T any] struct {
+	Client *Client
+	Prefix string
+}
+
+// NewStore returns a Store for objects of type T under prefix, which is
+// given a trailing slash if it lacks one.
+func NewStore[T any](client *Client, prefix string) *Store[T] {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &Store[T]{Client: client, Prefix: prefix}
+}
+
+// Path returns the object path for key.
+func (s *Store[T]) Path(key string) string {
+	return s.Prefix + key
+}
+
+// Get loads the object at key.
+func (s *Store[T]) Get(key string) (T, error) {
+	return s.GetCtx(context.Background(), key)
+}
+
+// GetCtx is Get with a context.
+func (s *Store[T]) GetCtx(ctx context.Context, key string) (T, error) {
+	var obj T
+	if err := s.Client.LoadCtx(ctx, s.Path(key), &obj); err != nil {
+		var zero T
+		return zero, err
+	}
+	return obj, nil
+}
+
+// Put saves obj at key with no expiry.
+func (s *Store[T]) Put(key string, obj T) error {
+	return s.PutCtx(context.Background(), key, obj)
+}
+
+// PutCtx is Put with a context.
+func (s *Store[T]) PutCtx(ctx context.Context, key string, obj T) error {
+	return s.Client.SaveCtx(ctx, s.Path(key), obj)
+}
+
+// PutExpiry saves obj at key with expiry set.
+func (s *Store[T]) PutExpiry(key string, obj T, expiry time.Time) error {
+	return s.PutExpiryCtx(context.Background(), key, obj, expiry)
+}
+
+// PutExpiryCtx is PutExpiry with a context.
+func (s *Store[T]) PutExpiryCtx(ctx context.Context, key string, obj T, expiry time.Time) error {
+	return s.Client.SaveExpiryCtx(ctx, s.Path(key), obj, expiry)
+}
+
+// Delete deletes the object at key.
+func (s *Store[T]) Delete(key string) error {
+	return s.DeleteCtx(context.Background(), key)
+}
+
+// DeleteCtx is Delete with a context.
+func (s *Store[T]) DeleteCtx(ctx context.Context, key string) error {
+	return s.Client.DeleteCtx(ctx, s.Path(key))
+}
+
+// List returns the keys of all objects in the Store.  An empty array is not
+// considered an error.
+func (s *Store[T]) List() ([]string, error) {
+	return s.ListCtx(context.Background())
+}
+
+// ListCtx is List with a context.
+func (s *Store[T]) ListCtx(ctx context.Context) ([]string, error) {
+	paths, err := s.Client.ListCtx(ctx, s.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(paths))
+	for i, path := range paths {
+		keys[i] = strings.TrimPrefix(path, s.Prefix)
+	}
+	return keys, nil
+}
+
+// All loads every object in the Store, by key.  Objects deleted or expired
+// between listing and loading are left out.
+//
+// The objects are loaded with LoadMany, so this is one call per object
+// unless the Backend implements backend.BatchClient.
+func (s *Store[T]) All() (map[string]T, error) {
+	return s.AllCtx(context.Background())
+}
+
+// AllCtx is All with a context.
+func (s *Store[T]) AllCtx(ctx context.Context) (map[string]T, error) {
+	paths, err := s.Client.ListCtx(ctx, s.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := s.Client.LoadManyCtx(ctx, paths)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]T, len(loaded))
+	for path, b := range loaded {
+		var obj T
+		if err := s.Client.unmarshal(b, &obj); err != nil {
+			return nil, err
+		}
+		all[strings.TrimPrefix(path, s.Prefix)] = obj
+	}
+	return all, nil
+}
@@ -0,0 +1,190 @@
+// store_test.go -- tests for the typed Store.
+
+package jsobs_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/biztos/jsobs"
+)
+
+type storeThing struct {
+	Name  string
+	Count int
+}
+
+func (suite *JsobsTestSuite) TestNewStoreAddsSlashOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.Equal("/things/", jsobs.NewStore[storeThing](client, "/things").Prefix)
+	require.Equal("/things/", jsobs.NewStore[storeThing](client, "/things/").Prefix)
+	require.Equal("/things/x", jsobs.NewStore[storeThing](client, "/things").Path("x"))
+
+}
+
+func (suite *JsobsTestSuite) TestStorePutGetOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	store := jsobs.NewStore[storeThing](client, "/things")
+
+	exp := storeThing{Name: "foo", Count: 3}
+	require.NoError(store.Put("foo", exp))
+
+	got, err := store.Get("foo")
+	require.NoError(err)
+	require.Equal(exp, got)
+
+	// It's really just an object at the path.
+	var raw storeThing
+	require.NoError(client.Load("/things/foo", &raw))
+	require.Equal(exp, raw)
+
+}
+
+func (suite *JsobsTestSuite) TestStoreGetNotFound() {
+
+	require := suite.Require()
+
+	store := jsobs.NewStore[storeThing](suite.memClient(), "/things")
+
+	got, err := store.Get("none")
+	require.ErrorIs(err, jsobs.ErrNotFound)
+	require.Equal(storeThing{}, got, "zero value")
+
+}
+
+func (suite *JsobsTestSuite) TestStoreGetWrongTypeError() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/things/bad", "not a thing"))
+	store := jsobs.NewStore[storeThing](client, "/things")
+
+	got, err := store.Get("bad")
+	require.ErrorContains(err, "Failed to marshal JSON")
+	require.Equal(storeThing{}, got, "zero value")
+
+	_, err = store.All()
+	require.ErrorContains(err, "Failed to marshal JSON")
+
+}
+
+func (suite *JsobsTestSuite) TestStorePutExpiryOK() {
+
+	require := suite.Require()
+
+	store := jsobs.NewStore[int](suite.memClient(), "/n")
+
+	require.NoError(store.PutExpiry("old", 1, time.Now().Add(-time.Second)))
+	require.NoError(store.PutExpiry("new", 2, time.Now().Add(time.Hour)))
+
+	_, err := store.Get("old")
+	require.ErrorIs(err, jsobs.ErrExpired)
+	n, err := store.Get("new")
+	require.NoError(err)
+	require.Equal(2, n)
+
+}
+
+func (suite *JsobsTestSuite) TestStoreListAllOK() {
+
+	require := suite.Require()
+
+	client := suite.memClient()
+	require.NoError(client.Save("/other/x", 99))
+	require.NoError(client.Save("/n-but-not-in-store", 99))
+	store := jsobs.NewStore[int](client, "/n")
+	require.NoError(store.Put("b", 2))
+	require.NoError(store.Put("a", 1))
+	require.NoError(store.Put("sub/c", 3))
+
+	keys, err := store.List()
+	require.NoError(err)
+	require.Equal([]string{"a", "b", "sub/c"}, keys)
+
+	all, err := store.All()
+	require.NoError(err)
+	require.Equal(map[string]int{"a": 1, "b": 2, "sub/c": 3}, all)
+
+	require.NoError(store.Delete("a"))
+	keys, err = store.List()
+	require.NoError(err)
+	require.Equal([]string{"b", "sub/c"}, keys)
+
+}
+
+func (suite *JsobsTestSuite) TestStoreAllUsesBatchBackendOK() {
+
+	require := suite.Require()
+
+	b := &TestBatchBackend{TestBackend: suite.Backend}
+	store := jsobs.NewStore[int](&jsobs.Client{Backend: b}, "/n")
+
+	b.nextPaths = []string{"/n/a", "/n/b", "/n/gone"}
+	b.nextMany = map[string][]byte{"/n/a": []byte("1"), "/n/b": []byte("2")}
+	all, err := store.All()
+	require.NoError(err)
+	require.Equal(map[string]int{"a": 1, "b": 2}, all)
+	require.Equal([]string{"/n/a", "/n/b", "/n/gone"}, b.lastPaths)
+	require.Equal([]string{"List", "LoadRawManyCtx"}, b.allCalls, "calls")
+
+}
+
+func (suite *JsobsTestSuite) TestStoreListEmptyOK() {
+
+	require := suite.Require()
+
+	store := jsobs.NewStore[int](suite.memClient(), "/n")
+
+	keys, err := store.List()
+	require.NoError(err)
+	require.Empty(keys)
+	all, err := store.All()
+	require.NoError(err)
+	require.Empty(all)
+
+}
+
+func (suite *JsobsTestSuite) TestStoreListError() {
+
+	require := suite.Require()
+
+	suite.Backend.nextError = errors.New("oops")
+	store := jsobs.NewStore[int](suite.Client, "/n")
+
+	_, err := store.List()
+	require.EqualError(err, "oops")
+	_, err = store.All()
+	require.EqualError(err, "oops")
+	require.Equal("/n/", suite.Backend.lastPrefix, "prefix")
+
+}
+
+func (suite *JsobsTestSuite) TestStoreCtxMethodsFailCanceled() {
+
+	require := suite.Require()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	store := jsobs.NewStore[int](suite.Client, "/n")
+	_, err := store.GetCtx(ctx, "x")
+	require.ErrorIs(err, context.Canceled, "GetCtx")
+	require.ErrorIs(store.PutCtx(ctx, "x", 1), context.Canceled, "PutCtx")
+	require.ErrorIs(store.PutExpiryCtx(ctx, "x", 1, time.Now()),
+		context.Canceled, "PutExpiryCtx")
+	require.ErrorIs(store.DeleteCtx(ctx, "x"), context.Canceled, "DeleteCtx")
+	_, err = store.ListCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "ListCtx")
+	_, err = store.AllCtx(ctx)
+	require.ErrorIs(err, context.Canceled, "AllCtx")
+	require.Nil(suite.Backend.allCalls, "calls")
+
+}
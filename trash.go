@@ -0,0 +1,54 @@
+// trash.go -- undeleting soft-deleted objects.
+
+package jsobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// trasher returns the Backend as a backend.Trasher, or ErrUnsupported.
+func (c *Client) trasher() (backend.Trasher, error) {
+	t, ok := c.Backend.(backend.Trasher)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no trash", ErrUnsupported, c.Backend)
+	}
+	return t, nil
+}
+
+// Undelete restores the object at path from the trash, as it was when it was
+// deleted.  If it is not in the trash the error is ErrNotFound, and if a
+// live object has since been saved at path it is ErrConflict.
+//
+// Soft deletion must be enabled in the backend, e.g. with pgclient's
+// CreateTrash and SoftDelete.  Backends not implementing backend.Trasher
+// return ErrUnsupported.
+func (c *Client) Undelete(path string) error {
+	return c.UndeleteCtx(context.Background(), path)
+}
+
+// UndeleteCtx is Undelete with a context.
+func (c *Client) UndeleteCtx(ctx context.Context, path string) error {
+	t, err := c.trasher()
+	if err != nil {
+		return err
+	}
+	return t.UndeleteCtx(ctx, path)
+}
+
+// ListDeleted returns DeletedDetailers for the objects in the trash beginning
+// with prefix, in path order.  An empty array is not considered an error.
+func (c *Client) ListDeleted(prefix string) ([]backend.DeletedDetailer, error) {
+	return c.ListDeletedCtx(context.Background(), prefix)
+}
+
+// ListDeletedCtx is ListDeleted with a context.
+func (c *Client) ListDeletedCtx(ctx context.Context, prefix string) ([]backend.DeletedDetailer, error) {
+	t, err := c.trasher()
+	if err != nil {
+		return nil, err
+	}
+	return t.ListDeletedCtx(ctx, prefix)
+}
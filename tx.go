@@ -0,0 +1,98 @@
+// tx.go -- transactions spanning several operations.
+
+package jsobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/biztos/jsobs/backend"
+)
+
+// Tx runs operations within a transaction started by Client.Tx.  It uses the
+// context given to Tx, and must not be used after the transaction ends.
+type Tx struct {
+	ctx    context.Context
+	client *Client
+	tx     backend.TxClient
+}
+
+// Tx calls fn with a new transaction, which is committed if fn returns nil
+// and rolled back if it returns an error or panics.  The error from fn is
+// returned as is, so that callers can check for their own errors:
+//
+//	err := client.Tx(ctx, func(tx *jsobs.Tx) error {
+//		if err := tx.Save("/orders/1", order); err != nil {
+//			return err
+//		}
+//		return tx.Save("/index/customer/7/orders/1", true)
+//	})
+//
+// Backends not implementing backend.Transactor return ErrUnsupported, and
+// fn is not called.
+func (c *Client) Tx(ctx context.Context, fn func(tx *Tx) error) error {
+
+	t, ok := c.Backend.(backend.Transactor)
+	if !ok {
+		return fmt.Errorf("%w: %s has no transactions", ErrUnsupported, c.Backend)
+	}
+	return t.TxCtx(ctx, func(tc backend.TxClient) error {
+		return fn(&Tx{ctx: ctx, client: c, tx: tc})
+	})
+
+}
+
+// Save is Client.Save within the transaction.
+func (t *Tx) Save(path string, obj any) error {
+
+	b, err := t.client.marshal(obj)
+	if err != nil {
+		return err
+	}
+	return t.tx.SaveRawCtx(t.ctx, path, b)
+
+}
+
+// SaveExpiry is Client.SaveExpiry within the transaction.
+func (t *Tx) SaveExpiry(path string, obj any, expiry time.Time) error {
+
+	b, err := t.client.marshal(obj)
+	if err != nil {
+		return err
+	}
+	return t.tx.SaveRawExpiryCtx(t.ctx, path, b, expiry)
+
+}
+
+// SaveRaw is Client.SaveRaw within the transaction.
+func (t *Tx) SaveRaw(path string, raw_obj []byte) error {
+	return t.tx.SaveRawCtx(t.ctx, path, raw_obj)
+}
+
+// Load is Client.Load within the transaction, seeing any changes already
+// made in it.
+func (t *Tx) Load(path string, obj any) error {
+
+	b, err := t.tx.LoadRawCtx(t.ctx, path)
+	if err != nil {
+		return err
+	}
+	return t.client.unmarshal(b, obj)
+
+}
+
+// LoadRaw is Client.LoadRaw within the transaction.
+func (t *Tx) LoadRaw(path string) ([]byte, error) {
+	return t.tx.LoadRawCtx(t.ctx, path)
+}
+
+// Delete is Client.Delete within the transaction.
+func (t *Tx) Delete(path string) error {
+	return t.tx.DeleteCtx(t.ctx, path)
+}
+
+// List is Client.List within the transaction.
+func (t *Tx) List(prefix string) ([]string, error) {
+	return t.tx.ListCtx(t.ctx, prefix)
+}